@@ -0,0 +1,365 @@
+// Package backfill enriches workflow runs that completed before the
+// webhook listener was running. It pages through an organization's runs
+// with the same Enricher the listener uses, pauses when the GitHub rate
+// limit runs low, and records its progress in a checkpoint file so an
+// interrupted backfill resumes where it stopped.
+package backfill
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	gh "github.com/BuildGuard-Test-Lab/pbom/internal/github"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/webhook"
+)
+
+const (
+	perPage = 100
+	// maxResults is the most runs GitHub returns for one filtered query;
+	// busier windows are split in two.
+	maxResults = 1000
+	// minWindow is the shortest window that is still split.
+	minWindow = time.Hour
+)
+
+// Config selects the runs to backfill.
+type Config struct {
+	Org   string
+	Repo  string // only this repository of Org; empty for all
+	Since time.Time
+	// Checkpoint is the progress file; empty disables resuming.
+	Checkpoint string
+	// MinRemaining pauses the backfill until the rate limit resets when
+	// fewer API requests remain. Zero means 100.
+	MinRemaining int
+}
+
+// Stats counts the runs a backfill visited.
+type Stats struct {
+	Enriched int `json:"enriched"`
+	Skipped  int `json:"skipped"` // already stored, or never enriched
+	Failed   int `json:"failed"`
+}
+
+// Backfiller enriches historical workflow runs.
+type Backfiller struct {
+	client   *gh.Client
+	enricher *webhook.Enricher
+	store    storage.Storage
+	cfg      Config
+	logger   *slog.Logger
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+	cp    *checkpoint
+	stats Stats
+}
+
+// New creates a Backfiller. The enricher should share client, so that its
+// API calls count toward the rate limit the Backfiller watches.
+func New(client *gh.Client, enricher *webhook.Enricher, store storage.Storage, cfg Config, logger *slog.Logger) *Backfiller {
+	if cfg.MinRemaining <= 0 {
+		cfg.MinRemaining = 100
+	}
+	return &Backfiller{
+		client:   client,
+		enricher: enricher,
+		store:    store,
+		cfg:      cfg,
+		logger:   logger,
+		now:      time.Now,
+		sleep:    sleep,
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// checkpoint is the persisted progress of a backfill.
+type checkpoint struct {
+	Org     string        `json:"org"`
+	Repo    string        `json:"repo,omitempty"`
+	Since   time.Time     `json:"since"`
+	Done    []string      `json:"done_repos"`
+	Current *repoProgress `json:"current,omitempty"`
+	Failed  []failedRun   `json:"failed,omitempty"`
+	Updated time.Time     `json:"updated_at"`
+}
+
+// repoProgress tracks the repository being backfilled.
+type repoProgress struct {
+	Repo    gh.Repo  `json:"repo"`
+	Windows []window `json:"windows"` // pending; the first is in progress
+	Page    int      `json:"page"`    // next page of Windows[0]
+}
+
+// window is a range of run creation times, both ends inclusive.
+type window struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+func (w window) query() string {
+	return w.From.UTC().Format(time.RFC3339) + ".." + w.To.UTC().Format(time.RFC3339)
+}
+
+// failedRun is a run whose enrichment failed; it is retried on the next
+// backfill.
+type failedRun struct {
+	Repo  gh.Repo `json:"repo"`
+	RunID int64   `json:"run_id"`
+	Error string  `json:"error"`
+}
+
+// Run backfills every selected repository and returns what it did. It
+// stops at the first error other than a failed enrichment; the
+// checkpoint then lets a new Run continue.
+func (b *Backfiller) Run(ctx context.Context) (Stats, error) {
+	if err := b.loadCheckpoint(); err != nil {
+		return b.stats, err
+	}
+
+	if err := b.retryFailed(ctx); err != nil {
+		return b.stats, err
+	}
+
+	if b.cp.Current != nil {
+		if err := b.backfillRepo(ctx); err != nil {
+			return b.stats, err
+		}
+	}
+
+	if b.cfg.Repo != "" {
+		repo := gh.Repo{Name: b.cfg.Repo, FullName: b.cfg.Org + "/" + b.cfg.Repo, Owner: gh.Owner{Login: b.cfg.Org}}
+		return b.stats, b.startRepo(ctx, repo)
+	}
+
+	if err := b.waitForRateLimit(ctx); err != nil {
+		return b.stats, err
+	}
+	repos, err := b.client.ListOrgRepos(ctx, b.cfg.Org)
+	if err != nil {
+		return b.stats, fmt.Errorf("listing repositories of %s: %w", b.cfg.Org, err)
+	}
+	for _, repo := range repos {
+		if err := b.startRepo(ctx, repo); err != nil {
+			return b.stats, err
+		}
+	}
+	return b.stats, nil
+}
+
+// startRepo backfills a repository unless the checkpoint marks it done.
+func (b *Backfiller) startRepo(ctx context.Context, repo gh.Repo) error {
+	if slices.Contains(b.cp.Done, repo.FullName) {
+		return nil
+	}
+	b.cp.Current = &repoProgress{
+		Repo:    repo,
+		Windows: []window{{From: b.cfg.Since, To: b.now().UTC().Truncate(time.Second)}},
+		Page:    1,
+	}
+	return b.backfillRepo(ctx)
+}
+
+// backfillRepo works through the pending windows of the current
+// repository, saving the checkpoint after every page.
+func (b *Backfiller) backfillRepo(ctx context.Context) error {
+	cur := b.cp.Current
+	log := b.logger.With("repo", cur.Repo.FullName)
+	log.Info("backfilling repository")
+
+	for len(cur.Windows) > 0 {
+		w := cur.Windows[0]
+		if err := b.waitForRateLimit(ctx); err != nil {
+			return err
+		}
+		runs, total, err := b.client.ListRuns(ctx, cur.Repo.Owner.Login, cur.Repo.Name, gh.ListRunsOptions{
+			Status:  "completed",
+			Created: w.query(),
+			Page:    cur.Page,
+			PerPage: perPage,
+		})
+		if err != nil {
+			return fmt.Errorf("listing runs of %s: %w", cur.Repo.FullName, err)
+		}
+
+		if cur.Page == 1 && total > maxResults {
+			if w.To.Sub(w.From) > minWindow {
+				mid := w.From.Add(w.To.Sub(w.From) / 2).Truncate(time.Second)
+				cur.Windows = append([]window{{w.From, mid}, {mid.Add(time.Second), w.To}}, cur.Windows[1:]...)
+				continue
+			}
+			log.Warn("more runs than GitHub lists for one query; some will be missed",
+				"window", w.query(), "runs", total)
+		}
+
+		for _, run := range runs {
+			if err := b.backfillRun(ctx, cur.Repo, run); err != nil {
+				return err
+			}
+		}
+
+		if len(runs) < perPage || cur.Page*perPage >= maxResults {
+			cur.Windows, cur.Page = cur.Windows[1:], 1
+		} else {
+			cur.Page++
+		}
+		if err := b.saveCheckpoint(); err != nil {
+			return err
+		}
+	}
+
+	b.cp.Done = append(b.cp.Done, cur.Repo.FullName)
+	b.cp.Current = nil
+	return b.saveCheckpoint()
+}
+
+// backfillRun enriches one run unless it is already stored. Enrichment
+// failures are recorded for retry rather than returned.
+func (b *Backfiller) backfillRun(ctx context.Context, repo gh.Repo, run gh.WorkflowRun) error {
+	event := webhook.EventFromRun(run, repo)
+	if webhook.IgnoreReason(event) != "" {
+		b.stats.Skipped++
+		return nil
+	}
+	stored, err := webhook.Stored(ctx, b.store, repo.Owner.Login, repo.Name, run.ID, run.RunAttempt)
+	if err != nil {
+		return fmt.Errorf("checking storage: %w", err)
+	}
+	if stored {
+		b.stats.Skipped++
+		return nil
+	}
+
+	if err := b.waitForRateLimit(ctx); err != nil {
+		return err
+	}
+	if err := b.enricher.Enrich(ctx, event); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		b.logger.Warn("enrichment failed", "repo", repo.FullName, "run_id", run.ID, "error", err)
+		b.stats.Failed++
+		b.cp.Failed = append(b.cp.Failed, failedRun{Repo: repo, RunID: run.ID, Error: err.Error()})
+		return nil
+	}
+	b.stats.Enriched++
+	return nil
+}
+
+// retryFailed re-enriches the runs that failed in earlier backfills.
+func (b *Backfiller) retryFailed(ctx context.Context) error {
+	failed := b.cp.Failed
+	b.cp.Failed = nil
+	for i, f := range failed {
+		if err := b.waitForRateLimit(ctx); err != nil {
+			b.cp.Failed = append(b.cp.Failed, failed[i:]...)
+			return err
+		}
+		run, err := b.client.GetWorkflowRun(ctx, f.Repo.Owner.Login, f.Repo.Name, f.RunID)
+		if err != nil {
+			b.logger.Warn("could not fetch failed run", "repo", f.Repo.FullName, "run_id", f.RunID, "error", err)
+			b.stats.Failed++
+			f.Error = err.Error()
+			b.cp.Failed = append(b.cp.Failed, f)
+			continue
+		}
+		if err := b.backfillRun(ctx, f.Repo, *run); err != nil {
+			b.cp.Failed = append(b.cp.Failed, failed[i:]...)
+			return err
+		}
+	}
+	return b.saveCheckpoint()
+}
+
+// waitForRateLimit sleeps until the rate limit resets if fewer than
+// MinRemaining requests are left.
+func (b *Backfiller) waitForRateLimit(ctx context.Context) error {
+	rl, ok := b.client.RateLimit()
+	if !ok || rl.Remaining >= b.cfg.MinRemaining {
+		return nil
+	}
+	wait := rl.Reset.Sub(b.now()) + time.Second
+	if wait <= 0 {
+		return nil
+	}
+	b.logger.Info("rate limit low, pausing", "remaining", rl.Remaining, "resume_at", rl.Reset.Format(time.RFC3339))
+	if err := b.saveCheckpoint(); err != nil {
+		return err
+	}
+	return b.sleep(ctx, wait)
+}
+
+// loadCheckpoint reads the checkpoint file, or starts a new one. A
+// checkpoint written for a different backfill is an error rather than
+// silently discarded.
+func (b *Backfiller) loadCheckpoint() error {
+	fresh := &checkpoint{Org: b.cfg.Org, Repo: b.cfg.Repo, Since: b.cfg.Since.UTC()}
+	b.cp = fresh
+	if b.cfg.Checkpoint == "" {
+		return nil
+	}
+	data, err := os.ReadFile(b.cfg.Checkpoint)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading checkpoint: %w", err)
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return fmt.Errorf("parsing checkpoint %s: %w", b.cfg.Checkpoint, err)
+	}
+	if cp.Org != fresh.Org || cp.Repo != fresh.Repo || !cp.Since.Equal(fresh.Since) {
+		return fmt.Errorf("checkpoint %s belongs to another backfill (org %q, repo %q, since %s); remove it or choose another file",
+			b.cfg.Checkpoint, cp.Org, cp.Repo, cp.Since.Format(time.RFC3339))
+	}
+	b.cp = &cp
+	b.logger.Info("resuming from checkpoint", "done_repos", len(cp.Done), "failed_runs", len(cp.Failed))
+	return nil
+}
+
+// saveCheckpoint writes the checkpoint atomically.
+func (b *Backfiller) saveCheckpoint() error {
+	if b.cfg.Checkpoint == "" {
+		return nil
+	}
+	b.cp.Updated = b.now().UTC()
+	data, err := json.MarshalIndent(b.cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(b.cfg.Checkpoint), ".checkpoint-*")
+	if err != nil {
+		return fmt.Errorf("writing checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), b.cfg.Checkpoint); err != nil {
+		return fmt.Errorf("writing checkpoint: %w", err)
+	}
+	return nil
+}
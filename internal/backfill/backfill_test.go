@@ -0,0 +1,230 @@
+package backfill
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gh "github.com/BuildGuard-Test-Lab/pbom/internal/github"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/webhook"
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+)
+
+// fakeGitHub serves organization acme with repositories a and b. Run 10
+// of b fails enrichment while failJobs is set.
+type fakeGitHub struct {
+	failJobs  atomic.Bool
+	remaining atomic.Int64
+
+	mu       sync.Mutex
+	runLists []string // repo and created filter of each run listing
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-RateLimit-Limit", "5000")
+	w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(f.remaining.Load(), 10))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10))
+
+	q := r.URL.Query()
+	switch path := r.URL.Path; {
+	case path == "/orgs/acme/repos":
+		fmt.Fprint(w, `[{"name":"a","full_name":"acme/a","owner":{"login":"acme"}},{"name":"b","full_name":"acme/b","owner":{"login":"acme"}}]`)
+	case strings.HasSuffix(path, "/actions/runs") && q.Get("head_sha") != "":
+		fmt.Fprint(w, `{"total_count":0,"workflow_runs":[]}`)
+	case path == "/repos/acme/a/actions/runs":
+		f.recordList("a", q)
+		fmt.Fprint(w, `{"total_count":3,"workflow_runs":[
+			{"id":3,"run_attempt":2,"name":"CI","conclusion":"success","head_sha":"cccccccc"},
+			{"id":2,"run_attempt":1,"name":"PBOM Collector","conclusion":"success","head_sha":"bbbbbbbb"},
+			{"id":1,"run_attempt":1,"name":"CI","conclusion":"failure","head_sha":"aaaaaaaa"}]}`)
+	case path == "/repos/acme/b/actions/runs":
+		f.recordList("b", q)
+		fmt.Fprint(w, `{"total_count":1,"workflow_runs":[{"id":10,"run_attempt":1,"name":"Deploy","conclusion":"success","head_sha":"dddddddd"}]}`)
+	case path == "/repos/acme/b/actions/runs/10":
+		fmt.Fprint(w, `{"id":10,"run_attempt":1,"name":"Deploy","conclusion":"success","head_sha":"dddddddd"}`)
+	case path == "/repos/acme/b/actions/runs/10/jobs" && f.failJobs.Load():
+		http.Error(w, "boom", http.StatusBadGateway)
+	case strings.HasSuffix(path, "/jobs"):
+		fmt.Fprint(w, `{"total_count":0,"jobs":[]}`)
+	case strings.HasSuffix(path, "/artifacts"):
+		fmt.Fprint(w, `{"total_count":0,"artifacts":[]}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeGitHub) recordList(repo string, q map[string][]string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.runLists = append(f.runLists, repo+" "+strings.Join(q["created"], ""))
+}
+
+func (f *fakeGitHub) lists() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.runLists...)
+}
+
+func newTestBackfiller(t *testing.T, fake *fakeGitHub, st storage.Storage, cfg Config) *Backfiller {
+	t.Helper()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client := gh.NewClientWithBase("", srv.URL)
+	enricher := webhook.NewEnricher(client, st, nil, logger)
+	enricher.WaitForCollector(false)
+	return New(client, enricher, st, cfg, logger)
+}
+
+func TestBackfillAndResume(t *testing.T) {
+	ctx := context.Background()
+	st, err := storage.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Attempt 2 of run 3 is already stored.
+	stored := &schema.PBOM{PBOMVersion: schema.Version, ID: "existing", Source: schema.Source{Repository: "acme/a"}}
+	if _, err := webhook.Store(ctx, st, stored, nil, "acme", "a", 3, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeGitHub{}
+	fake.remaining.Store(4000)
+	fake.failJobs.Store(true)
+	cfg := Config{
+		Org:        "acme",
+		Since:      time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Checkpoint: filepath.Join(t.TempDir(), "checkpoint.json"),
+	}
+
+	stats, err := newTestBackfiller(t, fake, st, cfg).Run(ctx)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if want := (Stats{Enriched: 1, Skipped: 2, Failed: 1}); stats != want {
+		t.Errorf("first run stats = %+v, want %+v", stats, want)
+	}
+	if ok, _ := webhook.Stored(ctx, st, "acme", "a", 1, 1); !ok {
+		t.Error("run 1 was not stored")
+	}
+	if lists := fake.lists(); len(lists) != 2 || !strings.HasPrefix(lists[0], "a 2026-01-01T00:00:00Z..") {
+		t.Errorf("run listings = %q", lists)
+	}
+
+	var cp checkpoint
+	data, err := os.ReadFile(cfg.Checkpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		t.Fatal(err)
+	}
+	if len(cp.Done) != 2 || cp.Current != nil || len(cp.Failed) != 1 || cp.Failed[0].RunID != 10 {
+		t.Errorf("checkpoint = %s", data)
+	}
+
+	// The next run retries the failure and skips the finished repositories.
+	fake.failJobs.Store(false)
+	stats, err = newTestBackfiller(t, fake, st, cfg).Run(ctx)
+	if err != nil {
+		t.Fatalf("resumed Run: %v", err)
+	}
+	if want := (Stats{Enriched: 1}); stats != want {
+		t.Errorf("resumed stats = %+v, want %+v", stats, want)
+	}
+	if lists := fake.lists(); len(lists) != 2 {
+		t.Errorf("resumed backfill listed runs again: %q", lists)
+	}
+
+	// A checkpoint is only reused for the same backfill.
+	other := cfg
+	other.Repo = "a"
+	if _, err := newTestBackfiller(t, fake, st, other).Run(ctx); err == nil || !strings.Contains(err.Error(), "another backfill") {
+		t.Errorf("mismatched checkpoint: err = %v", err)
+	}
+}
+
+func TestBackfillPausesForRateLimit(t *testing.T) {
+	st, err := storage.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeGitHub{}
+	fake.remaining.Store(3)
+	b := newTestBackfiller(t, fake, st, Config{Org: "acme", Repo: "b", Since: time.Now().Add(-24 * time.Hour)})
+
+	var pauses []time.Duration
+	b.sleep = func(ctx context.Context, d time.Duration) error {
+		pauses = append(pauses, d)
+		return nil
+	}
+	stats, err := b.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if stats.Enriched != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if len(pauses) == 0 {
+		t.Fatal("backfill did not pause with 3 requests left")
+	}
+	for _, d := range pauses {
+		if d <= 0 || d > 2*time.Minute {
+			t.Errorf("pause = %v, want until the reset a minute away", d)
+		}
+	}
+}
+
+func TestWindowSplitting(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		created := r.URL.Query().Get("created")
+		mu.Lock()
+		queries = append(queries, created)
+		mu.Unlock()
+		from, to, _ := strings.Cut(created, "..")
+		start, _ := time.Parse(time.RFC3339, from)
+		end, _ := time.Parse(time.RFC3339, to)
+		// Pretend there are 1,500 runs a day.
+		total := int(end.Sub(start).Hours() / 24 * 1500)
+		fmt.Fprintf(w, `{"total_count":%d,"workflow_runs":[]}`, total)
+	}))
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client := gh.NewClientWithBase("", srv.URL)
+	now := time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC)
+	b := New(client, nil, nil, Config{Org: "acme", Repo: "busy", Since: now.Add(-48 * time.Hour)}, logger)
+	b.now = func() time.Time { return now }
+
+	if _, err := b.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// 2 days -> 1 day -> 12h windows, each under 1,000 runs.
+	var leaves int
+	for _, q := range queries {
+		from, to, _ := strings.Cut(q, "..")
+		start, _ := time.Parse(time.RFC3339, from)
+		end, _ := time.Parse(time.RFC3339, to)
+		if end.Sub(start) <= 12*time.Hour {
+			leaves++
+		}
+	}
+	if leaves != 4 || len(queries) != 7 {
+		t.Errorf("queries = %q, want 3 split and 4 leaf windows", queries)
+	}
+}
@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/backfill"
+	gh "github.com/BuildGuard-Test-Lab/pbom/internal/github"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/webhook"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backfillOrg        string
+	backfillRepo       string
+	backfillSince      string
+	backfillToken      string
+	backfillStorage    string
+	backfillSigningKey string
+	backfillCheckpoint string
+	backfillJSON       bool
+	backfillAppID      int64
+	backfillAppKey     string
+	backfillGitHubURL  string
+	backfillCABundle   string
+	backfillMaxArtMB   int
+)
+
+var backfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "Generate PBOMs for workflow runs completed before the listener ran",
+	Long: `Pages through the completed workflow runs of an organization (or one
+repository) created since a date, and enriches each one exactly as
+'pbom webhook' would: runner, timestamps, secrets and Docker artifacts,
+on top of the collector's skeleton PBOM while its artifact is still
+retained. Runs already in storage are skipped.
+
+  pbom backfill --org acme-corp --since 2026-01-01
+  pbom backfill --org acme-corp --repo my-app --since 2026-01-01
+
+Progress is saved to --checkpoint after every page of runs, so an
+interrupted backfill resumes where it stopped when run again with the
+same arguments; runs whose enrichment failed are retried then. When the
+GitHub rate limit runs low the backfill pauses until it resets.
+
+With --app-id and --app-key the backfill authenticates as the GitHub
+App's installation on the organization instead of with a token.`,
+	Args: cobra.NoArgs,
+	RunE: runBackfill,
+}
+
+func init() {
+	backfillCmd.Flags().StringVar(&backfillOrg, "org", "", "Organization to backfill (required)")
+	backfillCmd.Flags().StringVar(&backfillRepo, "repo", "", "Only backfill this repository of the organization")
+	backfillCmd.Flags().StringVar(&backfillSince, "since", "", "Backfill runs created at or after this date, YYYY-MM-DD or RFC 3339 (required)")
+	backfillCmd.Flags().StringVar(&backfillToken, "token", "", "GitHub token (or GITHUB_TOKEN env)")
+	backfillCmd.Flags().StringVar(&backfillGitHubURL, "github-url", "", "GitHub Enterprise Server API URL (or GITHUB_API_URL env; default https://api.github.com)")
+	backfillCmd.Flags().StringVar(&backfillCABundle, "github-ca-bundle", "", "PEM CA bundle for the GitHub API (or PBOM_GITHUB_CA_BUNDLE env)")
+	backfillCmd.Flags().IntVar(&backfillMaxArtMB, "max-artifact-mb", 64, "Largest artifact to download, in MiB")
+	backfillCmd.Flags().Int64Var(&backfillAppID, "app-id", 0, "GitHub App ID to authenticate as instead of a token (or GITHUB_APP_ID env)")
+	backfillCmd.Flags().StringVar(&backfillAppKey, "app-key", "", "GitHub App PEM private key file (or GITHUB_APP_PRIVATE_KEY_FILE env)")
+	backfillCmd.Flags().StringVar(&backfillStorage, "storage", "./pbom-data", "Storage URL or directory for enriched PBOMs (or PBOM_STORAGE env)")
+	backfillCmd.Flags().StringVar(&backfillSigningKey, "signing-key", "", "PEM private key to sign stored PBOMs with (or PBOM_SIGNING_KEY env)")
+	backfillCmd.Flags().StringVar(&backfillCheckpoint, "checkpoint", "./pbom-backfill.json", "Progress file for resuming an interrupted backfill")
+	backfillCmd.Flags().BoolVar(&backfillJSON, "json", false, "Print the summary as JSON")
+	backfillCmd.MarkFlagRequired("org")
+	backfillCmd.MarkFlagRequired("since")
+}
+
+func runBackfill(cmd *cobra.Command, args []string) error {
+	since, err := time.Parse(time.RFC3339, backfillSince)
+	if err != nil {
+		if since, err = time.Parse(time.DateOnly, backfillSince); err != nil {
+			return fmt.Errorf("--since %q is not an RFC 3339 time or YYYY-MM-DD date", backfillSince)
+		}
+	}
+	if backfillToken == "" {
+		backfillToken = os.Getenv("GITHUB_TOKEN")
+	}
+	githubURL, transport, err := loadGitHubEndpoint(backfillGitHubURL, backfillCABundle)
+	if err != nil {
+		return err
+	}
+	if backfillMaxArtMB <= 0 {
+		return fmt.Errorf("--max-artifact-mb must be positive")
+	}
+	app, err := loadGitHubApp(backfillAppID, backfillAppKey, githubURL, transport)
+	if err != nil {
+		return err
+	}
+	if backfillToken == "" && app == nil {
+		return fmt.Errorf("GitHub token (--token or GITHUB_TOKEN) or App (--app-id and --app-key) required")
+	}
+	if !cmd.Flags().Changed("storage") {
+		if s := os.Getenv("PBOM_STORAGE"); s != "" {
+			backfillStorage = s
+		}
+	}
+	if backfillSigningKey == "" {
+		backfillSigningKey = os.Getenv("PBOM_SIGNING_KEY")
+	}
+
+	signer, err := loadSigningKey(backfillSigningKey)
+	if err != nil {
+		return err
+	}
+
+	store, err := storage.Open(backfillStorage)
+	if err != nil {
+		return fmt.Errorf("opening storage: %w", err)
+	}
+	if c, ok := store.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	client := gh.NewClientWithBase(backfillToken, githubURL)
+	if transport != nil {
+		client.SetTransport(transport)
+	}
+	client.SetMaxDownloadSize(int64(backfillMaxArtMB) << 20)
+	if app != nil {
+		app.SetMaxDownloadSize(int64(backfillMaxArtMB) << 20)
+		id, err := app.OrgInstallation(ctx, backfillOrg)
+		if err != nil {
+			return err
+		}
+		client = app.Client(id)
+	}
+
+	logger := slog.New(slog.NewTextHandler(cmd.ErrOrStderr(), nil))
+	enricher := webhook.NewEnricher(client, store, signer, logger)
+	enricher.WaitForCollector(false)
+
+	b := backfill.New(client, enricher, store, backfill.Config{
+		Org:        backfillOrg,
+		Repo:       backfillRepo,
+		Since:      since,
+		Checkpoint: backfillCheckpoint,
+	}, logger)
+
+	stats, err := b.Run(ctx)
+	if backfillJSON {
+		pretty, _ := json.MarshalIndent(stats, "", "  ")
+		fmt.Fprintln(cmd.OutOrStdout(), string(pretty))
+	} else {
+		fmt.Fprintf(cmd.OutOrStdout(), "enriched %d, skipped %d, failed %d\n", stats.Enriched, stats.Skipped, stats.Failed)
+	}
+	if err != nil {
+		return fmt.Errorf("backfill stopped (rerun to resume): %w", err)
+	}
+	return nil
+}
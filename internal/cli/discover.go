@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/oci"
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var (
+	discoverRegistryConfig string
+	discoverPlainHTTP      bool
+	discoverJSON           bool
+)
+
+var discoverCmd = &cobra.Command{
+	Use:   "discover <artifact-ref>",
+	Short: "List PBOMs attached to an image in an OCI registry",
+	Long: `Lists every PBOM referrer attached to an image, oldest first, with the
+referrer manifest digest, creation time and PBOM id.
+
+Uses the Referrers API (OCI 1.1) with fallback to the tag-schema
+referrers index. Pass a referrer digest to 'pbom pull --referrer' to
+download a specific PBOM.
+
+Example:
+  pbom discover ghcr.io/acme-corp/my-app@sha256:abc123...`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDiscover,
+}
+
+func init() {
+	discoverCmd.Flags().StringVar(&discoverRegistryConfig, "registry-config", "", "Path to Docker config file with registry credentials")
+	discoverCmd.Flags().BoolVar(&discoverPlainHTTP, "plain-http", false, "Use plain HTTP instead of HTTPS for the registry")
+	discoverCmd.Flags().BoolVar(&discoverJSON, "json", false, "Output referrer descriptors as JSON")
+}
+
+func runDiscover(cmd *cobra.Command, args []string) error {
+	ref, err := oci.ParseReference(args[0])
+	if err != nil {
+		return fmt.Errorf("parsing artifact reference: %w", err)
+	}
+
+	client, err := newRegistryClient(discoverRegistryConfig, discoverPlainHTTP)
+	if err != nil {
+		return err
+	}
+
+	referrers, err := discoverPBOMs(context.Background(), client, ref)
+	if err != nil {
+		return err
+	}
+
+	if discoverJSON {
+		if referrers == nil {
+			referrers = []oci.Descriptor{}
+		}
+		pretty, _ := json.MarshalIndent(referrers, "", "  ")
+		fmt.Fprintln(cmd.OutOrStdout(), string(pretty))
+		return nil
+	}
+
+	if len(referrers) == 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "no PBOMs attached to %s\n", ref)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "DIGEST\tCREATED\tPBOM ID")
+	for _, d := range referrers {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", d.Digest, d.Annotations[oci.AnnotationCreated], d.Annotations[annotationPBOMID])
+	}
+	return w.Flush()
+}
+
+// discoverPBOMs lists PBOM referrers of ref, sorted by creation time.
+func discoverPBOMs(ctx context.Context, client *oci.Client, ref oci.Reference) ([]oci.Descriptor, error) {
+	referrers, err := client.Referrers(ctx, ref, schema.MediaType)
+	if err != nil {
+		return nil, fmt.Errorf("listing referrers of %s: %w", ref, err)
+	}
+
+	// RFC 3339 UTC timestamps sort lexically.
+	sort.SliceStable(referrers, func(i, j int) bool {
+		return referrers[i].Annotations[oci.AnnotationCreated] < referrers[j].Annotations[oci.AnnotationCreated]
+	})
+	return referrers, nil
+}
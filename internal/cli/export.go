@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/export"
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+// exporters maps each --format value to its converter.
+var exporters = map[string]func(*schema.PBOM) (any, error){
+	"slsa-provenance": func(p *schema.PBOM) (any, error) { return export.ToSLSAProvenance(p) },
+	"cyclonedx":       func(p *schema.PBOM) (any, error) { return export.ToCycloneDX(p) },
+	"spdx":            func(p *schema.PBOM) (any, error) { return export.ToSPDX(p) },
+}
+
+var (
+	exportFormat string
+	exportOutput string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export <file>",
+	Short: "Convert a PBOM into another supply chain format",
+	Long: `Translates a PBOM document (bare or DSSE-signed) into a format that
+tools without PBOM support understand.
+
+Formats:
+  slsa-provenance  in-toto Statement v1 with a SLSA v1.0 provenance
+                   predicate, one subject per artifact digest
+  cyclonedx        CycloneDX 1.5 BOM; the build is recorded as a
+                   formulation workflow, artifacts as components
+  spdx             SPDX 3.0.1 JSON-LD using the build profile; artifacts
+                   are packages linked to the build by hasOutput
+
+The PBOM is validated first and invalid documents are refused.
+
+Example:
+  pbom export --format slsa-provenance -o provenance.json pbom.json
+  pbom export --format cyclonedx pbom.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runExport,
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportFormat, "format", "", "Output format: "+strings.Join(exportFormats(), ", "))
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Output file path (default: stdout)")
+	exportCmd.MarkFlagRequired("format")
+}
+
+func exportFormats() []string {
+	formats := make([]string, 0, len(exporters))
+	for f := range exporters {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	convert, ok := exporters[exportFormat]
+	if !ok {
+		return fmt.Errorf("unsupported format %q (expected %s)", exportFormat, strings.Join(exportFormats(), ", "))
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("reading file: %w", err)
+	}
+
+	pbom, findings, err := checkPBOM(data)
+	if err != nil {
+		return err
+	}
+	if errs := errorMessages(findings); len(errs) > 0 {
+		return fmt.Errorf("refusing to export invalid PBOM:\n  %s", strings.Join(errs, "\n  "))
+	}
+
+	doc, err := convert(pbom)
+	if err != nil {
+		return fmt.Errorf("exporting %s: %w", exportFormat, err)
+	}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling %s: %w", exportFormat, err)
+	}
+
+	if exportOutput == "" {
+		fmt.Fprintln(cmd.OutOrStdout(), string(out))
+		return nil
+	}
+	if err := os.WriteFile(exportOutput, append(out, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "%s written to %s\n", exportFormat, exportOutput)
+	return nil
+}
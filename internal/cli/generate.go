@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,7 +30,7 @@ zero configuration.
 
 Environment variables read:
   GITHUB_SHA, GITHUB_REPOSITORY, GITHUB_REF, GITHUB_REF_NAME,
-  GITHUB_ACTOR, GITHUB_RUN_ID, GITHUB_WORKFLOW, GITHUB_EVENT_NAME,
+  GITHUB_ACTOR, GITHUB_RUN_ID, GITHUB_RUN_ATTEMPT, GITHUB_WORKFLOW, GITHUB_EVENT_NAME,
   GITHUB_WORKFLOW_REF, RUNNER_OS, RUNNER_ARCH, RUNNER_NAME,
   RUNNER_ENVIRONMENT`,
 	RunE: runGenerate,
@@ -48,6 +49,9 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	// Detect installed build tools
 	toolVersions := detect.ToolVersions()
 
+	// Unset outside GitHub Actions, in which case run_attempt is omitted.
+	runAttempt, _ := strconv.Atoi(envOrEmpty("GITHUB_RUN_ATTEMPT"))
+
 	pbom := schema.PBOM{
 		PBOMVersion: schema.Version,
 		ID:          uuid.New().String(),
@@ -61,9 +65,10 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		},
 		Build: schema.Build{
 			WorkflowRunID: envOrEmpty("GITHUB_RUN_ID"),
+			RunAttempt:    runAttempt,
 			WorkflowName:  envOrEmpty("GITHUB_WORKFLOW"),
 			WorkflowFile:  envOrEmpty("GITHUB_WORKFLOW_REF"),
-			Trigger:       mapTrigger(envOrEmpty("GITHUB_EVENT_NAME")),
+			Trigger:       schema.TriggerForEvent(envOrEmpty("GITHUB_EVENT_NAME")),
 			Actor:         envOrEmpty("GITHUB_ACTOR"),
 			Runner:        runner,
 			ToolVersions:  toolVersions,
@@ -120,14 +125,3 @@ func buildRunner() *schema.Runner {
 		SelfHosted: selfHosted,
 	}
 }
-
-func mapTrigger(event string) string {
-	switch event {
-	case "push", "pull_request", "workflow_dispatch", "schedule", "release":
-		return event
-	case "":
-		return ""
-	default:
-		return "other"
-	}
-}
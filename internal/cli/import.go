@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	gh "github.com/BuildGuard-Test-Lab/pbom/internal/github"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/importer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importOutput         string
+	importAttestationURI string
+	importGitHubURL      string
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Create a PBOM from SLSA provenance or a GitHub artifact attestation",
+	Long: `Reads SLSA provenance and produces a PBOM document, so repositories
+that attest their builds (for example with actions/attest-build-provenance
+or the slsa-github-generator) get PBOM coverage without the collector.
+
+Accepted input:
+  - an in-toto Statement with a SLSA v1.0 or v0.2 provenance predicate
+  - the same Statement wrapped in a DSSE envelope
+  - a Sigstore bundle (e.g. from 'gh attestation download')
+
+Source, build and one artifact per subject are filled from the provenance.
+Each artifact's provenance records the builder ID and the SLSA build level
+inferred from it. GitHub-hosted runners and the slsa-github-generator are
+recognized on github.com and, with --github-url, on a GitHub Enterprise
+Server. Signatures are not verified; use 'gh attestation verify' or
+cosign for that.
+
+Example:
+  gh attestation download oci://ghcr.io/acme-corp/my-app:v1.2.3 -R acme-corp/my-app
+  pbom import -o pbom.json sha256:abc123....jsonl`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImport,
+}
+
+func init() {
+	importCmd.Flags().StringVarP(&importOutput, "output", "o", "", "Write PBOM to file (default: stdout)")
+	importCmd.Flags().StringVar(&importAttestationURI, "attestation-uri", "", "Where the attestation is published, recorded in each artifact's provenance")
+	importCmd.Flags().StringVar(&importGitHubURL, "github-url", "", "GitHub Enterprise Server API URL whose builders are trusted (or GITHUB_API_URL env; default https://api.github.com)")
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("reading file: %w", err)
+	}
+
+	rawURL := importGitHubURL
+	if rawURL == "" {
+		rawURL = os.Getenv("GITHUB_API_URL")
+	}
+	baseURL := gh.DefaultBaseURL
+	if rawURL != "" {
+		if baseURL, err = gh.ParseBaseURL(rawURL); err != nil {
+			return err
+		}
+	}
+
+	pbom, err := importer.FromProvenance(data, gh.WebURL(baseURL))
+	if err != nil {
+		return fmt.Errorf("importing %s: %w", args[0], err)
+	}
+	if importAttestationURI != "" {
+		for i := range pbom.Artifacts {
+			pbom.Artifacts[i].Provenance.AttestationURI = importAttestationURI
+		}
+	}
+
+	out, err := json.MarshalIndent(pbom, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling PBOM: %w", err)
+	}
+
+	_, findings, err := checkPBOM(out)
+	if err != nil {
+		return err
+	}
+	if errs := errorMessages(findings); len(errs) > 0 {
+		return fmt.Errorf("provenance lacks data required by a PBOM:\n  %s", strings.Join(errs, "\n  "))
+	}
+	for _, f := range findings {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s\n", f)
+	}
+
+	if importOutput != "" {
+		if err := os.WriteFile(importOutput, append(out, '\n'), 0o644); err != nil {
+			return fmt.Errorf("writing file %s: %w", importOutput, err)
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "PBOM written to %s\n", importOutput)
+		return nil
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), string(out))
+	return nil
+}
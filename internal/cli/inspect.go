@@ -1,26 +1,45 @@
 package cli
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 	"text/tabwriter"
+	"time"
 
+	"github.com/BuildGuard-Test-Lab/pbom/internal/dsse"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/webhook"
 	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
 	"github.com/spf13/cobra"
 )
 
 var (
-	inspectJSON bool
+	inspectJSON    bool
+	inspectStorage string
+	inspectAttempt int
 )
 
 var inspectCmd = &cobra.Command{
-	Use:   "inspect <file>",
+	Use:   "inspect <file | owner/repo/run-id>",
 	Short: "Display the lineage of an artifact from a PBOM document",
-	Long: `Reads a PBOM file and prints a human-readable summary of the artifact's
+	Long: `Reads a PBOM file (bare or DSSE-signed) and prints a human-readable summary of the artifact's
 pipeline lineage: source commit, build details, artifact digests, and
 promotion history.
 
+With --storage, the argument names a workflow run in the storage of
+'pbom webhook' instead, and every stored attempt of the run is listed.
+A re-run of a workflow produces a new attempt; the latest is shown
+unless --attempt selects another:
+
+  pbom inspect --storage ./pbom-data acme-corp/my-app/7890123456
+  pbom inspect --storage ./pbom-data acme-corp/my-app/7890123456 --attempt 1
+
 Use --json to output the raw PBOM instead.`,
 	Args: cobra.ExactArgs(1),
 	RunE: runInspect,
@@ -28,26 +47,161 @@ Use --json to output the raw PBOM instead.`,
 
 func init() {
 	inspectCmd.Flags().BoolVar(&inspectJSON, "json", false, "Output raw JSON instead of formatted summary")
+	inspectCmd.Flags().StringVar(&inspectStorage, "storage", "", "Read a workflow run's attempts from this storage URL or directory")
+	inspectCmd.Flags().IntVar(&inspectAttempt, "attempt", 0, "Run attempt to show with --storage (default latest)")
 }
 
 func runInspect(cmd *cobra.Command, args []string) error {
+	if inspectStorage != "" {
+		return runInspectRun(cmd, args[0])
+	}
+	if cmd.Flags().Changed("attempt") {
+		return fmt.Errorf("--attempt requires --storage")
+	}
+
 	data, err := os.ReadFile(args[0])
 	if err != nil {
 		return fmt.Errorf("reading file: %w", err)
 	}
+	return showPBOM(cmd.OutOrStdout(), data)
+}
+
+// runInspectRun shows one attempt of a stored workflow run followed by
+// the run's attempt history.
+func runInspectRun(cmd *cobra.Command, ref string) error {
+	owner, repo, runID, err := parseRunRef(ref)
+	if err != nil {
+		return err
+	}
+
+	store, err := storage.Open(inspectStorage)
+	if err != nil {
+		return fmt.Errorf("opening storage: %w", err)
+	}
+	if c, ok := store.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	ctx := context.Background()
+	history, err := webhook.RunHistory(ctx, store, owner, repo, runID)
+	if err != nil {
+		return fmt.Errorf("reading run %s: %w", ref, err)
+	}
+	if len(history) == 0 {
+		return fmt.Errorf("no PBOMs stored for run %s", ref)
+	}
+
+	selected := history[len(history)-1]
+	if inspectAttempt != 0 {
+		found := false
+		for _, h := range history {
+			if h.Attempt == inspectAttempt {
+				selected, found = h, true
+			}
+		}
+		if !found {
+			return fmt.Errorf("run %s has no stored attempt %d", ref, inspectAttempt)
+		}
+	}
+
+	data, err := store.Get(ctx, selected.Key)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", selected.Key, err)
+	}
+	if err := showPBOM(cmd.OutOrStdout(), data); err != nil {
+		return err
+	}
+	if !inspectJSON {
+		printAttempts(cmd.OutOrStdout(), history, selected.Key)
+	}
+	return nil
+}
+
+// parseRunRef splits an owner/repo/run-id reference.
+func parseRunRef(ref string) (owner, repo string, runID int64, err error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) == 3 && parts[0] != "" && parts[1] != "" {
+		if runID, err = strconv.ParseInt(parts[2], 10, 64); err == nil {
+			return parts[0], parts[1], runID, nil
+		}
+	}
+	return "", "", 0, fmt.Errorf("invalid run %q: want owner/repo/run-id", ref)
+}
+
+// showPBOM prints a bare or DSSE-enveloped PBOM document.
+func showPBOM(out io.Writer, data []byte) error {
+	data, env, err := dsse.Unwrap(data, schema.MediaType)
+	if err != nil {
+		return err
+	}
 
-	var pbom schema.PBOM
-	if err := json.Unmarshal(data, &pbom); err != nil {
+	// Older documents are shown in their migrated form; unknown versions
+	// are still displayed on a best-effort basis.
+	pbom, err := schema.Decode(data)
+	if errors.Is(err, schema.ErrUnsupportedVersion) {
+		pbom = &schema.PBOM{}
+		err = json.Unmarshal(data, pbom)
+	}
+	if err != nil {
 		return fmt.Errorf("invalid JSON: %w", err)
 	}
 
 	if inspectJSON {
 		pretty, _ := json.MarshalIndent(pbom, "", "  ")
-		fmt.Fprintln(cmd.OutOrStdout(), string(pretty))
+		fmt.Fprintln(out, string(pretty))
 		return nil
 	}
 
-	out := cmd.OutOrStdout()
+	printInspect(out, pbom)
+	if env != nil {
+		printSignatures(out, env)
+	}
+	return nil
+}
+
+// printAttempts lists every stored attempt of a run, marking the one
+// shown above.
+func printAttempts(out io.Writer, history []webhook.RunAttempt, shown string) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(out, "ATTEMPTS")
+	for _, h := range history {
+		attempt := "legacy" // stored before attempts were tracked
+		if h.Attempt > 0 {
+			attempt = "#" + strconv.Itoa(h.Attempt)
+		}
+		marker := " "
+		if h.Key == shown {
+			marker = "*"
+		}
+		var digests []string
+		for _, a := range h.PBOM.Artifacts {
+			digests = append(digests, a.Digest)
+		}
+		fmt.Fprintf(w, "  %s %s\t%s\t%s\t%s\t%s\n", marker, attempt, h.PBOM.Build.Status,
+			h.PBOM.Timestamp.Format(time.RFC3339), h.Key, strings.Join(digests, ","))
+	}
+	w.Flush()
+	fmt.Fprintln(out)
+}
+
+// printSignatures lists the signatures of a DSSE-enveloped PBOM. They are
+// not verified here; that needs a trusted key ('pbom verify').
+func printSignatures(out io.Writer, env *dsse.Envelope) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(out, "SIGNATURES (unverified, see 'pbom verify')")
+	for _, sig := range env.Signatures {
+		keyID := sig.KeyID
+		if keyID == "" {
+			keyID = "(no key id)"
+		}
+		fmt.Fprintf(w, "  Key\t%s\n", keyID)
+	}
+	w.Flush()
+	fmt.Fprintln(out)
+}
+
+// printInspect writes the human-readable lineage summary of a PBOM.
+func printInspect(out io.Writer, pbom *schema.PBOM) {
 	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
 
 	fmt.Fprintf(out, "PBOM %s\n\n", pbom.ID)
@@ -65,7 +219,11 @@ func runInspect(cmd *cobra.Command, args []string) error {
 
 	fmt.Fprintln(out)
 	fmt.Fprintln(out, "BUILD")
-	fmt.Fprintf(w, "  Workflow\t%s (run %s)\n", pbom.Build.WorkflowName, pbom.Build.WorkflowRunID)
+	if pbom.Build.RunAttempt > 0 {
+		fmt.Fprintf(w, "  Workflow\t%s (run %s, attempt %d)\n", pbom.Build.WorkflowName, pbom.Build.WorkflowRunID, pbom.Build.RunAttempt)
+	} else {
+		fmt.Fprintf(w, "  Workflow\t%s (run %s)\n", pbom.Build.WorkflowName, pbom.Build.WorkflowRunID)
+	}
 	fmt.Fprintf(w, "  Trigger\t%s\n", pbom.Build.Trigger)
 	fmt.Fprintf(w, "  Actor\t%s\n", pbom.Build.Actor)
 	fmt.Fprintf(w, "  Status\t%s\n", pbom.Build.Status)
@@ -82,6 +240,13 @@ func runInspect(cmd *cobra.Command, args []string) error {
 			fmt.Fprintf(w, "  Secret\t%s\n", s)
 		}
 	}
+	if sk := pbom.Build.Skeleton; sk != nil {
+		check := "digest verified"
+		if !sk.Verified {
+			check = "no digest from GitHub to verify"
+		}
+		fmt.Fprintf(w, "  Skeleton\tartifact %d of run %s, %s (%s)\n", sk.ArtifactID, sk.RunID, sk.Digest, check)
+	}
 	w.Flush()
 
 	for i, a := range pbom.Artifacts {
@@ -134,5 +299,4 @@ func runInspect(cmd *cobra.Command, args []string) error {
 	w.Flush()
 
 	fmt.Fprintln(out)
-	return nil
 }
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/webhook"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lookupStorage string
+	lookupJSON    bool
+	lookupReindex bool
+)
+
+var lookupCmd = &cobra.Command{
+	Use:   "lookup <digest|sha|image-ref>",
+	Short: "Find the builds that produced an artifact or commit",
+	Long: `Finds stored PBOMs through the storage index, newest first. The query
+may be an artifact digest, a commit SHA (abbreviated or full), or an image
+reference by tag or digest:
+
+  pbom lookup sha256:4f2a...
+  pbom lookup 3e1b9c0
+  pbom lookup ghcr.io/acme-corp/my-app:v1.4.2
+  pbom lookup ghcr.io/acme-corp/my-app@sha256:4f2a...
+
+The storage URL is the one given to 'pbom webhook' (see its help for the
+supported backends). PBOMs stored before the index existed are found
+after running once with --reindex.`,
+	Args: cobra.RangeArgs(0, 1),
+	RunE: runLookup,
+}
+
+func init() {
+	lookupCmd.Flags().StringVar(&lookupStorage, "storage", "./pbom-data", "Storage URL or directory (or PBOM_STORAGE env)")
+	lookupCmd.Flags().BoolVar(&lookupJSON, "json", false, "Output matching PBOMs as JSON")
+	lookupCmd.Flags().BoolVar(&lookupReindex, "reindex", false, "Rebuild the index of every stored PBOM first")
+}
+
+func runLookup(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 && !lookupReindex {
+		return fmt.Errorf("requires a digest, commit SHA or image reference")
+	}
+	if !cmd.Flags().Changed("storage") {
+		if s := os.Getenv("PBOM_STORAGE"); s != "" {
+			lookupStorage = s
+		}
+	}
+
+	store, err := storage.Open(lookupStorage)
+	if err != nil {
+		return fmt.Errorf("opening storage: %w", err)
+	}
+	if c, ok := store.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	ctx := context.Background()
+	if lookupReindex {
+		n, err := webhook.Reindex(ctx, store)
+		if err != nil {
+			return fmt.Errorf("reindexing: %w", err)
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "reindexed %d PBOMs\n", n)
+		if len(args) == 0 {
+			return nil
+		}
+	}
+
+	results, err := webhook.Lookup(ctx, store, args[0])
+	if err != nil {
+		return fmt.Errorf("looking up %s: %w", args[0], err)
+	}
+
+	if lookupJSON {
+		pboms := make([]any, 0, len(results))
+		for _, r := range results {
+			pboms = append(pboms, r.PBOM)
+		}
+		pretty, _ := json.MarshalIndent(pboms, "", "  ")
+		fmt.Fprintln(cmd.OutOrStdout(), string(pretty))
+		return nil
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "no PBOMs found for %s\n", args[0])
+		return nil
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PBOM ID\tREPOSITORY\tCOMMIT\tWORKFLOW RUN\tCONCLUSION\tTIMESTAMP\tMATCHED")
+	for _, r := range results {
+		p := r.PBOM
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s #%s\t%s\t%s\t%s\n",
+			p.ID, p.Source.Repository, shortSHA(p.Source.CommitSHA),
+			p.Build.WorkflowName, p.Build.WorkflowRunID, p.Build.Status,
+			p.Timestamp.UTC().Format(time.RFC3339), r.Match)
+	}
+	return w.Flush()
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
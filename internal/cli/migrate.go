@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateOutputDir string
+	migrateDryRun    bool
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate <file|dir|glob>...",
+	Short: "Upgrade stored PBOM documents to the current schema version",
+	Long: `Upgrades PBOM documents written with an older pbom_version to the
+current schema version, one version step at a time.
+
+By default files are rewritten in place (atomically). With --output-dir,
+every input file is written to that directory instead and the originals
+are left untouched; files already at the current version are copied
+as-is so the output directory is complete.
+
+Arguments are resolved like 'pbom validate': files, directories
+(searched recursively for *.json) and glob patterns.
+
+Example:
+  pbom migrate ./pbom-data
+  pbom migrate --output-dir ./pbom-data-v2 ./pbom-data`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runMigrate,
+}
+
+func init() {
+	migrateCmd.Flags().StringVarP(&migrateOutputDir, "output-dir", "o", "", "Write migrated documents to this directory instead of in place")
+	migrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "Report what would change without writing anything")
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	paths, err := expandPaths(args)
+	if err != nil {
+		return err
+	}
+
+	if migrateOutputDir != "" && !migrateDryRun {
+		if err := os.MkdirAll(migrateOutputDir, 0o755); err != nil {
+			return fmt.Errorf("creating output dir: %w", err)
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	written := map[string]string{}
+	var migrated, failed int
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			failed++
+			fmt.Fprintf(out, "FAIL      %s: %v\n", path, err)
+			continue
+		}
+
+		upgraded, from, err := schema.Migrate(data)
+		if err != nil {
+			failed++
+			fmt.Fprintf(out, "FAIL      %s: %v\n", path, err)
+			continue
+		}
+
+		dest := path
+		if migrateOutputDir != "" {
+			dest = filepath.Join(migrateOutputDir, filepath.Base(path))
+			if prev, dup := written[dest]; dup {
+				failed++
+				fmt.Fprintf(out, "FAIL      %s: output %s already written from %s\n", path, dest, prev)
+				continue
+			}
+			written[dest] = path
+		}
+
+		if from == schema.Version {
+			if dest != path && !migrateDryRun {
+				if err := writeFileAtomic(dest, data); err != nil {
+					failed++
+					fmt.Fprintf(out, "FAIL      %s: %v\n", path, err)
+					continue
+				}
+			}
+			fmt.Fprintf(out, "current   %s\n", path)
+			continue
+		}
+
+		if !migrateDryRun {
+			if err := writeFileAtomic(dest, upgraded); err != nil {
+				failed++
+				fmt.Fprintf(out, "FAIL      %s: %v\n", path, err)
+				continue
+			}
+		}
+		migrated++
+		fmt.Fprintf(out, "migrated  %s (%s → %s)\n", dest, from, schema.Version)
+	}
+
+	fmt.Fprintf(out, "\n%d files: %d migrated, %d already current, %d failed\n",
+		len(paths), migrated, len(paths)-migrated-failed, failed)
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files could not be migrated", failed, len(paths))
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temp file next to path and renames it
+// into place, so readers never observe a partially written document.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".pbom-*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("setting permissions on %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing %s: %w", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("renaming into %s: %w", path, err)
+	}
+	return nil
+}
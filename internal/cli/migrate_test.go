@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestMigrateCommand(t *testing.T) {
+	src := t.TempDir()
+	current := filepath.Join(src, "current.json")
+	unknown := filepath.Join(src, "unknown.json")
+	if err := os.WriteFile(current, []byte(`{"pbom_version":"1.3.0","id":"a"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(unknown, []byte(`{"pbom_version":"0.0.1","id":"b"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := t.TempDir()
+	migrateOutputDir, migrateDryRun = out, false
+	defer func() { migrateOutputDir = "" }()
+
+	var buf bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&buf)
+	err := runMigrate(cmd, []string{src})
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 files") {
+		t.Fatalf("runMigrate error = %v, want 1 of 2 failures", err)
+	}
+	if !strings.Contains(buf.String(), "unsupported pbom_version") {
+		t.Errorf("output missing unsupported version failure:\n%s", buf.String())
+	}
+
+	// Current documents are copied unchanged so the output dir is complete.
+	data, err := os.ReadFile(filepath.Join(out, "current.json"))
+	if err != nil {
+		t.Fatalf("current document not copied: %v", err)
+	}
+	if string(data) != `{"pbom_version":"1.3.0","id":"a"}` {
+		t.Errorf("current document modified: %s", data)
+	}
+	if _, err := os.Stat(filepath.Join(out, "unknown.json")); !os.IsNotExist(err) {
+		t.Errorf("unsupported document should not be written, stat err = %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/oci"
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+// maxPBOMSize caps the size of a PBOM payload downloaded from a registry.
+const maxPBOMSize = 10 << 20
+
+var (
+	pullRegistryConfig string
+	pullPlainHTTP      bool
+	pullReferrer       string
+	pullAll            bool
+	pullOutputDir      string
+	pullValidate       bool
+	pullJSON           bool
+)
+
+var pullCmd = &cobra.Command{
+	Use:   "pull <artifact-ref>",
+	Short: "Download PBOMs attached to an image in an OCI registry",
+	Long: `Downloads PBOM referrers attached to an image and prints their lineage
+summary (same output as 'pbom inspect').
+
+By default the most recently attached PBOM is pulled. Use --referrer to
+pick one by its referrer manifest digest (see 'pbom discover'); it must
+be attached to the given image. Use --all to pull every PBOM attached to
+the image.
+
+With --output-dir, PBOMs are written to <dir>/<id>.pbom.json as pulled
+instead of being printed. With --json, they are printed as JSON after
+migration to the current schema version. With --validate, each PBOM is
+also checked with the same rules as 'pbom validate' and the command
+fails if any is invalid.
+
+Example:
+  pbom pull ghcr.io/acme-corp/my-app@sha256:abc123...
+  pbom pull --all --validate -o ./pboms ghcr.io/acme-corp/my-app:v1.2.3`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPull,
+}
+
+func init() {
+	pullCmd.Flags().StringVar(&pullRegistryConfig, "registry-config", "", "Path to Docker config file with registry credentials")
+	pullCmd.Flags().BoolVar(&pullPlainHTTP, "plain-http", false, "Use plain HTTP instead of HTTPS for the registry")
+	pullCmd.Flags().StringVar(&pullReferrer, "referrer", "", "Digest of the PBOM referrer manifest to pull")
+	pullCmd.Flags().BoolVar(&pullAll, "all", false, "Pull every PBOM attached to the image")
+	pullCmd.Flags().StringVarP(&pullOutputDir, "output-dir", "o", "", "Write PBOMs to this directory instead of printing them")
+	pullCmd.Flags().BoolVar(&pullValidate, "validate", false, "Validate each pulled PBOM")
+	pullCmd.Flags().BoolVar(&pullJSON, "json", false, "Print the migrated PBOM as JSON instead of formatted summary")
+	pullCmd.MarkFlagsMutuallyExclusive("referrer", "all")
+}
+
+func runPull(cmd *cobra.Command, args []string) error {
+	ref, err := oci.ParseReference(args[0])
+	if err != nil {
+		return fmt.Errorf("parsing artifact reference: %w", err)
+	}
+
+	client, err := newRegistryClient(pullRegistryConfig, pullPlainHTTP)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	var digests []string
+	if pullReferrer != "" {
+		digests = []string{pullReferrer}
+	} else {
+		referrers, err := discoverPBOMs(ctx, client, ref)
+		if err != nil {
+			return err
+		}
+		if len(referrers) == 0 {
+			return fmt.Errorf("no PBOMs attached to %s", ref)
+		}
+		if !pullAll {
+			referrers = referrers[len(referrers)-1:]
+		}
+		for _, d := range referrers {
+			digests = append(digests, d.Digest)
+		}
+	}
+
+	if pullOutputDir != "" {
+		if err := os.MkdirAll(pullOutputDir, 0o755); err != nil {
+			return fmt.Errorf("creating output dir: %w", err)
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	invalid := 0
+	for _, digest := range digests {
+		// Discovered referrers are listed by their subject; a digest
+		// given with --referrer must be checked against it.
+		fetch := client.FetchPayload
+		if pullReferrer != "" {
+			fetch = client.FetchReferrer
+		}
+		data, _, err := fetch(ctx, ref, digest, schema.MediaType, maxPBOMSize)
+		if err != nil {
+			return fmt.Errorf("pulling %s: %w", digest, err)
+		}
+
+		pbom, findings, err := checkPBOM(data)
+		if err != nil {
+			return fmt.Errorf("referrer %s: %w", digest, err)
+		}
+
+		if errs := errorMessages(findings); pullValidate && len(errs) > 0 {
+			invalid++
+			fmt.Fprintf(cmd.ErrOrStderr(), "referrer %s: validation failed:\n  %s\n", digest, strings.Join(errs, "\n  "))
+		}
+		if pbom == nil {
+			// The schema errors keep the document from decoding.
+			if pullValidate {
+				continue
+			}
+			return fmt.Errorf("referrer %s is not a valid PBOM:\n  %s", digest, strings.Join(errorMessages(findings), "\n  "))
+		}
+
+		switch {
+		case pullOutputDir != "":
+			name := pbom.ID
+			if name == "" {
+				name = strings.Replace(digest, ":", "-", 1)
+			}
+			path := filepath.Join(pullOutputDir, filepath.Base(name)+".pbom.json")
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				return fmt.Errorf("writing %s: %w", path, err)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "PBOM %s written to %s\n", pbom.ID, path)
+		case pullJSON:
+			pretty, _ := json.MarshalIndent(pbom, "", "  ")
+			fmt.Fprintln(out, string(pretty))
+		default:
+			fmt.Fprintf(out, "REFERRER %s\n", digest)
+			printInspect(out, pbom)
+		}
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d PBOMs failed validation", invalid, len(digests))
+	}
+	return nil
+}
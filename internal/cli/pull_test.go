@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/oci"
+	"github.com/spf13/cobra"
+)
+
+// memRegistry is an in-process registry without the Referrers API, so
+// referrers go through the tag-schema index.
+type memRegistry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte // digest → content
+	types     map[string]string // digest → media type
+	tags      map[string]string // repo:tag → digest
+}
+
+func (r *memRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	switch {
+	case strings.Contains(path, "/blobs/uploads/") && req.Method == http.MethodPost:
+		w.Header().Set("Location", req.URL.Path+"1")
+		w.WriteHeader(http.StatusAccepted)
+	case strings.Contains(path, "/blobs/uploads/") && req.Method == http.MethodPut:
+		data, _ := io.ReadAll(req.Body)
+		r.blobs[req.URL.Query().Get("digest")] = data
+		w.WriteHeader(http.StatusCreated)
+	case strings.Contains(path, "/blobs/"):
+		data, ok := r.blobs[path[strings.LastIndex(path, "/")+1:]]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		if req.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case strings.Contains(path, "/manifests/"):
+		i := strings.Index(path, "/manifests/")
+		repo, ident := path[:i], path[i+len("/manifests/"):]
+		if req.Method == http.MethodPut {
+			data, _ := io.ReadAll(req.Body)
+			d := oci.DigestOf(data)
+			r.manifests[d] = data
+			r.types[d] = req.Header.Get("Content-Type")
+			if !strings.HasPrefix(ident, "sha256:") {
+				r.tags[repo+":"+ident] = d
+			}
+			w.Header().Set("Docker-Content-Digest", d)
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		d := ident
+		if !strings.HasPrefix(ident, "sha256:") {
+			d = r.tags[repo+":"+ident]
+		}
+		data, ok := r.manifests[d]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", r.types[d])
+		w.Header().Set("Docker-Content-Digest", d)
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		if req.Method == http.MethodGet {
+			w.Write(data)
+		}
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// pushTestImage stores a minimal image manifest as ref's tag.
+func pushTestImage(t *testing.T, client *oci.Client, ref string) {
+	t.Helper()
+	r, err := oci.ParseReference(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, _ := json.Marshal(oci.Manifest{
+		SchemaVersion: 2,
+		MediaType:     oci.MediaTypeImageManifest,
+		Config:        oci.Descriptor{MediaType: "application/vnd.oci.image.config.v1+json", Digest: oci.DigestOf([]byte(ref)), Size: int64(len(ref))},
+		Layers:        []oci.Descriptor{},
+	})
+	if _, _, err := client.PushManifest(context.Background(), r, r.Tag, oci.MediaTypeImageManifest, m); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPushAndPullReferrer(t *testing.T) {
+	srv := httptest.NewServer(&memRegistry{
+		blobs:     map[string][]byte{},
+		manifests: map[string][]byte{},
+		types:     map[string]string{},
+		tags:      map[string]string{},
+	})
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	app, other := u.Host+"/acme/app:v1", u.Host+"/acme/app:v2"
+
+	noCreds := filepath.Join(t.TempDir(), "config.json")
+	pushRegistryConfig, pushPlainHTTP = noCreds, true
+	pullRegistryConfig, pullPlainHTTP = noCreds, true
+	defer func() {
+		pushRegistryConfig, pushPlainHTTP = "", false
+		pullRegistryConfig, pullPlainHTTP, pullReferrer, pullJSON = "", false, "", false
+	}()
+
+	client := oci.NewClient(&oci.DockerConfig{}, true)
+	pushTestImage(t, client, app)
+	pushTestImage(t, client, other)
+
+	var pushed bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&pushed)
+	cmd.SetErr(io.Discard)
+	if err := runPush(cmd, []string{"../../schema/example.pbom.json", app}); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	referrer := strings.TrimSpace(pushed.String())
+
+	pullReferrer, pullJSON = referrer, true
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	if err := runPull(cmd, []string{app}); err != nil {
+		t.Fatalf("pull --referrer: %v", err)
+	}
+	if !strings.Contains(out.String(), `"id": "f47ac10b-58cc-4372-a567-0e02b2c3d479"`) {
+		t.Errorf("pulled PBOM missing its ID:\n%s", out.String())
+	}
+
+	// The referrer exists in the repository but belongs to another image.
+	err := runPull(cmd, []string{other})
+	if err == nil || !strings.Contains(err.Error(), "refers to") {
+		t.Errorf("pull --referrer of another image: error = %v, want subject mismatch", err)
+	}
+}
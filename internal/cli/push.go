@@ -1,20 +1,41 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/BuildGuard-Test-Lab/pbom/internal/oci"
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
 	"github.com/spf13/cobra"
 )
 
+// annotationPBOMID carries the PBOM document id on referrer manifests so
+// discover can list it without downloading the payload.
+const annotationPBOMID = "dev.pbom.id"
+
+var (
+	pushRegistryConfig string
+	pushPlainHTTP      bool
+)
+
 var pushCmd = &cobra.Command{
 	Use:   "push <file> <artifact-ref>",
 	Short: "Push a PBOM to an OCI registry as a referrer artifact",
 	Long: `Pushes a PBOM JSON document to an OCI-compliant registry using the
 Referrers API (OCI 1.1). The PBOM is stored as a separate artifact
-linked to the target artifact via its digest.
+(artifact type application/vnd.pbom.v1+json) whose subject is the
+target artifact's manifest digest.
+
+Registries without the Referrers API get the PBOM recorded in the
+tag-schema referrers index (sha256-<digest>) instead.
 
-Requires ORAS libraries. This is a placeholder until the ORAS
-integration is implemented.
+Credentials are read from the Docker config file ($DOCKER_CONFIG/config.json
+or ~/.docker/config.json unless --registry-config is set).
+
+Prints the digest of the referrer manifest on success.
 
 Example:
   pbom push pbom.json ghcr.io/acme-corp/my-app@sha256:abc123...`,
@@ -22,16 +43,57 @@ Example:
 	RunE: runPush,
 }
 
+func init() {
+	pushCmd.Flags().StringVar(&pushRegistryConfig, "registry-config", "", "Path to Docker config file with registry credentials")
+	pushCmd.Flags().BoolVar(&pushPlainHTTP, "plain-http", false, "Use plain HTTP instead of HTTPS for the registry")
+}
+
 func runPush(cmd *cobra.Command, args []string) error {
 	pbomFile := args[0]
-	artifactRef := args[1]
 
-	// TODO: Implement ORAS-based push logic
-	// 1. Read and validate the PBOM file
-	// 2. Resolve the artifact reference to a digest
-	// 3. Push the PBOM as a referrer with artifact-type: application/vnd.pbom.v1+json
-	// 4. Print the resulting referrer digest
+	data, err := os.ReadFile(pbomFile)
+	if err != nil {
+		return fmt.Errorf("reading file: %w", err)
+	}
+
+	pbom, findings, err := checkPBOM(data)
+	if err != nil {
+		return err
+	}
+	if errs := errorMessages(findings); len(errs) > 0 {
+		return fmt.Errorf("refusing to push invalid PBOM:\n  %s", strings.Join(errs, "\n  "))
+	}
+
+	ref, err := oci.ParseReference(args[1])
+	if err != nil {
+		return fmt.Errorf("parsing artifact reference: %w", err)
+	}
+
+	client, err := newRegistryClient(pushRegistryConfig, pushPlainHTTP)
+	if err != nil {
+		return err
+	}
+
+	desc, err := client.Attach(context.Background(), ref, schema.MediaType, filepath.Base(pbomFile), data, map[string]string{
+		annotationPBOMID: pbom.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("pushing PBOM: %w", err)
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "PBOM %s attached to %s\n", pbom.ID, ref)
+	fmt.Fprintln(cmd.OutOrStdout(), desc.Digest)
+	return nil
+}
 
-	fmt.Fprintf(cmd.ErrOrStderr(), "push not yet implemented (file=%s, ref=%s)\n", pbomFile, artifactRef)
-	return fmt.Errorf("not implemented")
+// newRegistryClient loads registry credentials and builds an OCI client.
+func newRegistryClient(configPath string, plainHTTP bool) (*oci.Client, error) {
+	if configPath == "" {
+		configPath = oci.DefaultDockerConfigPath()
+	}
+	creds, err := oci.LoadDockerConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	return oci.NewClient(creds, plainHTTP), nil
 }
@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/queue"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/webhook"
+	"github.com/spf13/cobra"
+)
+
+const defaultQueue = "./pbom-queue"
+
+var (
+	queueURL       string
+	queueListDead  bool
+	queueListJSON  bool
+	queueReplayAll bool
+)
+
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Inspect and replay the webhook job queue",
+	Long: `Inspects the durable job queue of 'pbom webhook'. Events that fail
+enrichment are retried with exponential backoff and, after the configured
+number of attempts, moved to a dead-letter list. Replaying a dead job
+returns it to the queue with a fresh set of attempts; a running listener
+picks it up within seconds.`,
+}
+
+var queueListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List pending or dead-lettered jobs",
+	Args:  cobra.NoArgs,
+	RunE:  runQueueList,
+}
+
+var queueReplayCmd = &cobra.Command{
+	Use:   "replay [job-id...]",
+	Short: "Return dead-lettered jobs to the queue",
+	Long: `Returns dead-lettered jobs to the queue. Pass job IDs from
+'pbom queue list --dead', or --all to replay every dead job.`,
+	RunE: runQueueReplay,
+}
+
+func init() {
+	queueCmd.PersistentFlags().StringVar(&queueURL, "queue", defaultQueue, "Job queue directory or sqlite:// URL (or PBOM_QUEUE env)")
+	queueListCmd.Flags().BoolVar(&queueListDead, "dead", false, "List dead-lettered jobs instead of pending ones")
+	queueListCmd.Flags().BoolVar(&queueListJSON, "json", false, "Output jobs as JSON")
+	queueReplayCmd.Flags().BoolVar(&queueReplayAll, "all", false, "Replay every dead-lettered job")
+	queueCmd.AddCommand(queueListCmd)
+	queueCmd.AddCommand(queueReplayCmd)
+}
+
+// openQueueStorage opens a queue location. Queues are polled and
+// rewritten constantly, so only local backends are accepted.
+func openQueueStorage(rawURL string) (storage.Storage, error) {
+	if strings.HasPrefix(rawURL, "s3://") {
+		return nil, fmt.Errorf("queue must be a local directory or sqlite:// URL, not %s", rawURL)
+	}
+	st, err := storage.Open(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("opening queue: %w", err)
+	}
+	return st, nil
+}
+
+// openQueue opens the queue named by --queue or PBOM_QUEUE. The returned
+// function releases it.
+func openQueue(cmd *cobra.Command) (*queue.Queue, func(), error) {
+	if !cmd.Flags().Changed("queue") {
+		if q := os.Getenv("PBOM_QUEUE"); q != "" {
+			queueURL = q
+		}
+	}
+	st, err := openQueueStorage(queueURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	closeFn := func() {
+		if c, ok := st.(io.Closer); ok {
+			c.Close()
+		}
+	}
+	logger := slog.New(slog.NewTextHandler(cmd.ErrOrStderr(), nil))
+	return queue.New(st, queue.Config{}, logger), closeFn, nil
+}
+
+func runQueueList(cmd *cobra.Command, args []string) error {
+	q, closeFn, err := openQueue(cmd)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	ctx := context.Background()
+	var jobs []*queue.Job
+	if queueListDead {
+		jobs, err = q.Dead(ctx)
+	} else {
+		jobs, err = q.Pending(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("listing jobs: %w", err)
+	}
+
+	if queueListJSON {
+		if jobs == nil {
+			jobs = []*queue.Job{}
+		}
+		pretty, _ := json.MarshalIndent(jobs, "", "  ")
+		fmt.Fprintln(cmd.OutOrStdout(), string(pretty))
+		return nil
+	}
+
+	if len(jobs) == 0 {
+		fmt.Fprintln(cmd.ErrOrStderr(), "no jobs")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "JOB ID\tREPOSITORY\tRUN\tATTEMPTS\tENQUEUED\tLAST ERROR")
+	for _, job := range jobs {
+		repo, run := describeJob(job)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n",
+			job.ID, repo, run, job.Attempts, job.EnqueuedAt.Format(time.RFC3339), job.LastError)
+	}
+	return w.Flush()
+}
+
+// describeJob extracts the repository and run ID of a queued event.
+func describeJob(job *queue.Job) (repo, run string) {
+	var event webhook.WebhookEvent
+	if err := json.Unmarshal(job.Payload, &event); err != nil {
+		return "?", "?"
+	}
+	return event.Repository.FullName, fmt.Sprint(event.WorkflowRun.ID)
+}
+
+func runQueueReplay(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 && !queueReplayAll {
+		return fmt.Errorf("pass job IDs to replay, or --all")
+	}
+	if len(args) > 0 && queueReplayAll {
+		return fmt.Errorf("--all cannot be combined with job IDs")
+	}
+
+	q, closeFn, err := openQueue(cmd)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	jobs, err := q.Replay(context.Background(), args...)
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		fmt.Fprintf(cmd.OutOrStdout(), "replayed %s\n", job.ID)
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "%d jobs returned to the queue\n", len(jobs))
+	return nil
+}
@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	gh "github.com/BuildGuard-Test-Lab/pbom/internal/github"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/webhook"
+	"github.com/spf13/cobra"
+)
+
+var (
+	replayToken      string
+	replayStorage    string
+	replaySigningKey string
+	replayFixtures   string
+	replayRecord     string
+	replayAppID      int64
+	replayAppKey     string
+	replayGitHubURL  string
+	replayCABundle   string
+	replayMaxArtMB   int
+)
+
+var webhookReplayCmd = &cobra.Command{
+	Use:   "replay <file|dir>...",
+	Short: "Run the enricher on recorded workflow_run payloads",
+	Long: `Runs the enrichment pipeline on recorded workflow_run payloads, such as
+those archived by 'pbom webhook --archive' or copied from a webhook's
+"Recent Deliveries" page. Globs are expanded and directories searched
+recursively for .json files, which are replayed in name order. HTTP,
+signature checks, deduplication and the queue are bypassed, and the
+enricher does not wait for collector runs that are still in progress.
+
+By default the enricher calls the GitHub API. --record saves every API
+response to a fixture directory; --fixtures replays one instead, without
+network access. Together they make an enrichment reproducible:
+
+  pbom webhook replay payload.json --record ./fixtures
+  pbom webhook replay payload.json --fixtures ./fixtures --storage ./out
+
+With --app-id and --app-key the enricher authenticates as the GitHub App
+installation named in each payload.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runWebhookReplay,
+}
+
+func init() {
+	webhookReplayCmd.Flags().StringVar(&replayToken, "token", "", "GitHub token (or GITHUB_TOKEN env)")
+	webhookReplayCmd.Flags().StringVar(&replayGitHubURL, "github-url", "", "GitHub Enterprise Server API URL (or GITHUB_API_URL env; default https://api.github.com)")
+	webhookReplayCmd.Flags().StringVar(&replayCABundle, "github-ca-bundle", "", "PEM CA bundle for the GitHub API (or PBOM_GITHUB_CA_BUNDLE env)")
+	webhookReplayCmd.Flags().IntVar(&replayMaxArtMB, "max-artifact-mb", 64, "Largest artifact to download, in MiB")
+	webhookReplayCmd.Flags().Int64Var(&replayAppID, "app-id", 0, "GitHub App ID to authenticate as instead of a token (or GITHUB_APP_ID env)")
+	webhookReplayCmd.Flags().StringVar(&replayAppKey, "app-key", "", "GitHub App PEM private key file (or GITHUB_APP_PRIVATE_KEY_FILE env)")
+	webhookReplayCmd.Flags().StringVar(&replayStorage, "storage", "./pbom-data", "Storage URL or directory for enriched PBOMs (or PBOM_STORAGE env)")
+	webhookReplayCmd.Flags().StringVar(&replaySigningKey, "signing-key", "", "PEM private key to sign stored PBOMs with (or PBOM_SIGNING_KEY env)")
+	webhookReplayCmd.Flags().StringVar(&replayFixtures, "fixtures", "", "Answer GitHub API requests from this fixture directory")
+	webhookReplayCmd.Flags().StringVar(&replayRecord, "record", "", "Record GitHub API responses to this fixture directory")
+}
+
+func runWebhookReplay(cmd *cobra.Command, args []string) error {
+	if replayFixtures != "" && replayRecord != "" {
+		return fmt.Errorf("--fixtures and --record cannot be combined")
+	}
+	if replayToken == "" {
+		replayToken = os.Getenv("GITHUB_TOKEN")
+	}
+	if !cmd.Flags().Changed("storage") {
+		if s := os.Getenv("PBOM_STORAGE"); s != "" {
+			replayStorage = s
+		}
+	}
+	if replaySigningKey == "" {
+		replaySigningKey = os.Getenv("PBOM_SIGNING_KEY")
+	}
+	githubURL, transport, err := loadGitHubEndpoint(replayGitHubURL, replayCABundle)
+	if err != nil {
+		return err
+	}
+	if replayMaxArtMB <= 0 {
+		return fmt.Errorf("--max-artifact-mb must be positive")
+	}
+	app, err := loadGitHubApp(replayAppID, replayAppKey, githubURL, transport)
+	if err != nil {
+		return err
+	}
+	if replayToken == "" && app == nil && replayFixtures == "" {
+		return fmt.Errorf("GitHub token (--token or GITHUB_TOKEN) or App (--app-id and --app-key) required unless replaying --fixtures")
+	}
+
+	files, err := expandPaths(args)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no .json payloads found")
+	}
+
+	signer, err := loadSigningKey(replaySigningKey)
+	if err != nil {
+		return err
+	}
+
+	store, err := storage.Open(replayStorage)
+	if err != nil {
+		return fmt.Errorf("opening storage: %w", err)
+	}
+	if c, ok := store.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	client := gh.NewClientWithBase(replayToken, githubURL)
+	client.SetMaxDownloadSize(int64(replayMaxArtMB) << 20)
+	rt := transport
+	switch {
+	case replayFixtures != "":
+		if rt, err = gh.NewReplayer(replayFixtures); err != nil {
+			return err
+		}
+	case replayRecord != "":
+		rec, err := gh.NewRecorder(replayRecord, transport)
+		if err != nil {
+			return err
+		}
+		rec.SetMaxBodySize(int64(replayMaxArtMB) << 20)
+		rt = rec
+	}
+	if app != nil {
+		app.SetMaxDownloadSize(int64(replayMaxArtMB) << 20)
+	}
+	if rt != nil {
+		client.SetTransport(rt)
+		if app != nil {
+			app.SetTransport(rt)
+		}
+	}
+
+	logger := slog.New(slog.NewTextHandler(cmd.ErrOrStderr(), nil))
+	enricher := webhook.NewEnricher(client, store, signer, logger)
+	enricher.WaitForCollector(false)
+	if app != nil {
+		enricher.UseApp(app)
+	}
+
+	ctx := context.Background()
+	var failed int
+	for _, file := range files {
+		payload, err := os.ReadFile(file)
+		if err != nil {
+			failed++
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s: %v\n", file, err)
+			continue
+		}
+		event, err := enricher.Replay(ctx, payload)
+		switch {
+		case errors.Is(err, webhook.ErrIgnored):
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s: skipped (%v)\n", file, err)
+		case err != nil:
+			failed++
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s: %v\n", file, err)
+		default:
+			key := webhook.StorageKey(event.Repository.Owner.Login, event.Repository.Name, event.WorkflowRun.ID, event.WorkflowRun.RunAttempt)
+			fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\n", file, key)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d payloads failed", failed, len(files))
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// errValidationFailed is returned after a machine-readable report has been
+// written for documents that failed validation.
+var errValidationFailed = errors.New("validation failed")
+
+// fileReport holds the validation result of one PBOM file. Err is set when
+// the file could not be read or parsed at all.
+type fileReport struct {
+	File     string
+	Findings []finding
+	Err      error
+}
+
+func (r fileReport) valid() bool {
+	if r.Err != nil {
+		return false
+	}
+	for _, f := range r.Findings {
+		if f.Severity == severityError {
+			return false
+		}
+	}
+	return true
+}
+
+func checkFormat(format string) error {
+	switch format {
+	case "text", "json", "sarif":
+		return nil
+	default:
+		return fmt.Errorf("unsupported format %q (expected text, json or sarif)", format)
+	}
+}
+
+// writeReport prints validation results in the requested format and
+// returns an error if any file is invalid.
+func writeReport(cmd *cobra.Command, format string, reports []fileReport) error {
+	valid := true
+	for _, r := range reports {
+		valid = valid && r.valid()
+	}
+
+	switch format {
+	case "json":
+		if err := writeJSONReport(cmd, reports, valid); err != nil {
+			return err
+		}
+	case "sarif":
+		if err := writeSARIFReport(cmd, reports); err != nil {
+			return err
+		}
+	default:
+		return writeTextReport(cmd, reports)
+	}
+
+	if !valid {
+		return errValidationFailed
+	}
+	return nil
+}
+
+func writeTextReport(cmd *cobra.Command, reports []fileReport) error {
+	if len(reports) > 1 {
+		return writeTextSummary(cmd, reports)
+	}
+
+	out := cmd.OutOrStdout()
+	r := reports[0]
+	if r.Err != nil {
+		return r.Err
+	}
+
+	var errs []string
+	for _, f := range r.Findings {
+		if f.Severity == severityWarning {
+			fmt.Fprintf(out, "warning: %s\n", f)
+		} else {
+			errs = append(errs, f.String())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("validation failed:\n  %s", strings.Join(errs, "\n  "))
+	}
+
+	fmt.Fprintln(out, "valid")
+	return nil
+}
+
+// writeTextSummary prints one line per file, its findings indented below,
+// and a closing summary.
+func writeTextSummary(cmd *cobra.Command, reports []fileReport) error {
+	out := cmd.OutOrStdout()
+	var invalid, warned int
+
+	for _, r := range reports {
+		status := "ok"
+		warnings := 0
+		for _, f := range r.Findings {
+			if f.Severity == severityWarning {
+				warnings++
+			}
+		}
+		switch {
+		case !r.valid():
+			status = "FAIL"
+			invalid++
+		case warnings > 0:
+			status = "warn"
+			warned++
+		}
+
+		fmt.Fprintf(out, "%-4s  %s\n", status, r.File)
+		if r.Err != nil {
+			fmt.Fprintf(out, "      error: %v\n", r.Err)
+		}
+		for _, f := range r.Findings {
+			fmt.Fprintf(out, "      %s: %s\n", f.Severity, f)
+		}
+	}
+
+	fmt.Fprintf(out, "\n%d files: %d valid, %d invalid (%d with warnings)\n",
+		len(reports), len(reports)-invalid, invalid, warned)
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d files failed validation", invalid, len(reports))
+	}
+	return nil
+}
+
+type jsonReport struct {
+	Valid   bool             `json:"valid"`
+	Results []jsonFileResult `json:"results"`
+}
+
+type jsonFileResult struct {
+	File     string    `json:"file"`
+	Valid    bool      `json:"valid"`
+	Error    string    `json:"error,omitempty"`
+	Findings []finding `json:"findings"`
+}
+
+func writeJSONReport(cmd *cobra.Command, reports []fileReport, valid bool) error {
+	doc := jsonReport{Valid: valid, Results: []jsonFileResult{}}
+	for _, r := range reports {
+		res := jsonFileResult{File: r.File, Valid: r.valid(), Findings: r.Findings}
+		if r.Err != nil {
+			res.Error = r.Err.Error()
+		}
+		if res.Findings == nil {
+			res.Findings = []finding{}
+		}
+		doc.Results = append(doc.Results, res)
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling report: %w", err)
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), string(data))
+	return nil
+}
+
+// SARIF 2.1.0 subset accepted by GitHub code scanning.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+}
+
+func writeSARIFReport(cmd *cobra.Command, reports []fileReport) error {
+	ruleIDs := map[string]bool{}
+	results := []sarifResult{}
+
+	add := func(file, ruleID, level, message, path string) {
+		ruleIDs[ruleID] = true
+		loc := sarifLocation{
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: file},
+				Region:           sarifRegion{StartLine: 1},
+			},
+		}
+		if path != "" {
+			loc.LogicalLocations = []sarifLogicalLocation{{FullyQualifiedName: path}}
+		}
+		results = append(results, sarifResult{
+			RuleID:    ruleID,
+			Level:     level,
+			Message:   sarifMessage{Text: message},
+			Locations: []sarifLocation{loc},
+		})
+	}
+
+	for _, r := range reports {
+		if r.Err != nil {
+			add(r.File, "unreadable-document", "error", r.Err.Error(), "")
+			continue
+		}
+		for _, f := range r.Findings {
+			path := f.Path
+			if path == "" {
+				path = "/"
+			}
+			add(r.File, f.RuleID, f.Severity, f.Message, path)
+		}
+	}
+
+	ids := make([]string, 0, len(ruleIDs))
+	for id := range ruleIDs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	sarifRules := []sarifRule{}
+	for _, id := range ids {
+		sarifRules = append(sarifRules, sarifRule{ID: id, ShortDescription: sarifMessage{Text: ruleDescription(id)}})
+	}
+
+	doc := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "pbom",
+				InformationURI: "https://github.com/BuildGuard-Test-Lab/pbom",
+				Rules:          sarifRules,
+			}},
+			Results: results,
+		}},
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling SARIF: %w", err)
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), string(data))
+	return nil
+}
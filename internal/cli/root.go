@@ -12,7 +12,7 @@ var rootCmd = &cobra.Command{
 An SBOM tells you what is inside the artifact.
 A PBOM tells you how it got there.
 
-Use pbom to generate, validate, inspect, and push pipeline metadata
+Use pbom to generate, validate, inspect, push, and pull pipeline metadata
 across your GitHub Actions and Kargo environments.`,
 	SilenceUsage: true,
 }
@@ -21,10 +21,20 @@ func init() {
 	rootCmd.AddCommand(generateCmd)
 	rootCmd.AddCommand(validateCmd)
 	rootCmd.AddCommand(inspectCmd)
+	rootCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(signCmd)
+	rootCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
 	rootCmd.AddCommand(pushCmd)
+	rootCmd.AddCommand(pullCmd)
+	rootCmd.AddCommand(discoverCmd)
+	rootCmd.AddCommand(lookupCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(filterCmd)
 	rootCmd.AddCommand(webhookCmd)
+	rootCmd.AddCommand(queueCmd)
+	rootCmd.AddCommand(backfillCmd)
 }
 
 func Execute() error {
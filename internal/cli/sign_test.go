@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/dsse"
+	"github.com/spf13/cobra"
+)
+
+// writeKeyPair writes a PEM private key and its public key to dir.
+func writeKeyPair(t *testing.T, dir, name string, key any, pub any) (string, string) {
+	t.Helper()
+	priv, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privPath := filepath.Join(dir, name+".key")
+	pubPath := filepath.Join(dir, name+".pub")
+	if err := os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: priv}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return privPath, pubPath
+}
+
+func TestSignVerifyValidate(t *testing.T) {
+	dir := t.TempDir()
+	ec, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPub, ed, _ := ed25519.GenerateKey(rand.Reader)
+	ecKey, ecPub := writeKeyPair(t, dir, "ec", ec, &ec.PublicKey)
+	_, edPubPath := writeKeyPair(t, dir, "ed", ed, edPub)
+
+	signed := filepath.Join(dir, "signed.json")
+	signKey, signOutput = ecKey, signed
+	cmd := &cobra.Command{}
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	if err := runSign(cmd, []string{"../../schema/example.pbom.json"}); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	// Signed documents validate and inspect like plain ones.
+	if r := validateFile(signed); !r.valid() {
+		t.Errorf("signed PBOM does not validate: %v %v", r.Err, r.Findings)
+	}
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	inspectJSON = false
+	if err := runInspect(cmd, []string{signed}); err != nil {
+		t.Fatalf("inspect: %v", err)
+	}
+	if !strings.Contains(out.String(), "SIGNATURES") {
+		t.Errorf("inspect output does not list signatures:\n%s", out.String())
+	}
+
+	tests := []struct {
+		name    string
+		keys    []string
+		bundle  string
+		wantErr bool
+	}{
+		{"signing key", []string{ecPub}, "", false},
+		{"trust bundle", nil, ecPub, false},
+		{"untrusted key", []string{edPubPath}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifyKeys, verifyTrustBundle = tt.keys, tt.bundle
+			err := runVerify(cmd, []string{signed})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verify err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Tampering with the payload breaks the signature.
+	data, _ := os.ReadFile(signed)
+	env, err := dsse.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Payload = bytes.Replace(env.Payload, []byte("acme-corp"), []byte("evil-corp"), 1)
+	data, _ = json.Marshal(env)
+	tampered := filepath.Join(dir, "tampered.json")
+	if err := os.WriteFile(tampered, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	verifyKeys, verifyTrustBundle = []string{ecPub}, ""
+	if err := runVerify(cmd, []string{tampered}); err == nil {
+		t.Error("tampered envelope verified")
+	}
+
+	if err := runVerify(cmd, []string{"../../schema/example.pbom.json"}); err == nil {
+		t.Error("unsigned document verified")
+	}
+}
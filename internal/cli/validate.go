@@ -2,101 +2,401 @@ package cli
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 
+	"github.com/BuildGuard-Test-Lab/pbom/internal/dsse"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/jsonschema"
 	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+	schemafile "github.com/BuildGuard-Test-Lab/pbom/schema"
 	"github.com/spf13/cobra"
 )
 
 var validateCmd = &cobra.Command{
-	Use:   "validate <file>",
-	Short: "Validate a PBOM document",
+	Use:   "validate <file|dir|glob>...",
+	Short: "Validate one or more PBOM documents",
 	Long: `Checks that a PBOM JSON file is well-formed and contains all required fields.
 
 Validates:
-  - JSON structure matches the PBOM schema
+  - The document against the published JSON Schema (schema/pbom.schema.json):
+    types, formats (uuid, date-time), enums and unknown fields. Violations
+    are reported with the JSON pointer of the offending value.
   - Required fields are present (source.repository, source.commit_sha, build.workflow_run_id, etc.)
   - Commit SHA format (40-char hex)
-  - Artifact digests format (sha256:64-char hex)`,
-	Args: cobra.ExactArgs(1),
+  - Artifact digests format (sha256:64-char hex)
+
+Soft problems (missing build.completed_at, vulnerability snapshots without
+a scanner name, a branch without a ref) are reported as warnings and do
+not fail validation.
+
+Output formats (--format):
+  text   Human-readable list of findings (default)
+  json   Structured findings: path, rule ID, severity and message
+  sarif  SARIF 2.1.0, suitable for GitHub code scanning upload
+
+Arguments may be files, directories (searched recursively for *.json) or
+glob patterns (quote them to stop the shell expanding them). Multiple
+files are validated concurrently (--jobs) and a per-file result plus a
+summary is printed.
+
+Exits non-zero if any file has an error-severity finding.
+
+Example:
+  pbom validate pbom.json
+  pbom validate ./pbom-data
+  pbom validate --format json 'pbom-data/acme-corp_*.pbom.json'`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: runValidate,
 }
 
+var (
+	validateFormat string
+	validateJobs   int
+)
+
+func init() {
+	validateCmd.Flags().StringVar(&validateFormat, "format", "text", "Output format: text, json or sarif")
+	validateCmd.Flags().IntVarP(&validateJobs, "jobs", "j", runtime.NumCPU(), "Number of files to validate concurrently")
+}
+
 func runValidate(cmd *cobra.Command, args []string) error {
-	data, err := os.ReadFile(args[0])
+	if err := checkFormat(validateFormat); err != nil {
+		return err
+	}
+
+	paths, err := expandPaths(args)
+	if err != nil {
+		return err
+	}
+
+	return writeReport(cmd, validateFormat, validateFiles(paths, validateJobs))
+}
+
+// expandPaths resolves file, directory and glob arguments into a
+// deduplicated list of files, preserving argument order. Directories are
+// walked recursively for *.json files.
+func expandPaths(args []string) ([]string, error) {
+	var paths []string
+	seen := map[string]bool{}
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+
+	for _, arg := range args {
+		matches := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			var err error
+			matches, err = filepath.Glob(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid glob %q: %w", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %q", arg)
+			}
+		}
+
+		for _, m := range matches {
+			info, err := os.Stat(m)
+			if err != nil || !info.IsDir() {
+				// Missing files are reported per file by validateFile.
+				add(m)
+				continue
+			}
+			err = filepath.WalkDir(m, func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() && strings.HasSuffix(p, ".json") {
+					add(p)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("walking %s: %w", m, err)
+			}
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no PBOM files found in %s", strings.Join(args, ", "))
+	}
+	return paths, nil
+}
+
+// validateFiles checks files with a bounded pool of workers. Results are
+// returned in the order of paths.
+func validateFiles(paths []string, jobs int) []fileReport {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	reports := make([]fileReport, len(paths))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(jobs, len(paths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				reports[i] = validateFile(paths[i])
+			}
+		}()
+	}
+	for i := range paths {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return reports
+}
+
+// validateFile reads and checks a single PBOM file.
+func validateFile(path string) fileReport {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fileReport{File: path, Err: fmt.Errorf("reading file: %w", err)}
+	}
+
+	_, findings, err := checkPBOM(data)
+	if err != nil {
+		return fileReport{File: path, Err: err}
+	}
+	return fileReport{File: path, Findings: findings}
+}
+
+// Finding severities.
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// finding is a single validation result.
+type finding struct {
+	// Path is the JSON pointer of the offending value ("" for the root).
+	Path     string `json:"path"`
+	RuleID   string `json:"rule_id"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func (f finding) String() string {
+	path := f.Path
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Sprintf("%s: %s [%s]", path, f.Message, f.RuleID)
+}
+
+// rules describes every rule ID validate can report. Schema violations use
+// "schema/<keyword>" and are described generically.
+var rules = map[string]string{
+	"missing-field":        "A field required by the PBOM specification is missing or empty.",
+	"unsupported-version":  "The pbom_version is not supported by this CLI.",
+	"outdated-version":     "The document uses an older pbom_version and should be migrated.",
+	"commit-sha-format":    "source.commit_sha must be a full 40-character lowercase hex SHA.",
+	"digest-format":        "Artifact digests must be sha256:<64-char lowercase hex>.",
+	"missing-completed-at": "build.completed_at is not set, so build duration is unknown.",
+	"vuln-scanner-missing": "A vulnerability snapshot does not name the scanner that produced it.",
+	"branch-without-ref":   "source.branch is set but source.ref is not.",
+	"unreadable-document":  "The file could not be read or is not valid JSON.",
+}
+
+// ruleDescription returns the description of a rule ID.
+func ruleDescription(id string) string {
+	if d, ok := rules[id]; ok {
+		return d
+	}
+	if kw, ok := strings.CutPrefix(id, "schema/"); ok {
+		return fmt.Sprintf("The document violates the %q constraint of the PBOM JSON Schema.", kw)
+	}
+	return id
+}
+
+// pbomSchema is the published JSON Schema, compiled once at startup.
+var pbomSchema = jsonschema.MustCompile(schemafile.JSON)
+
+// checkPBOM checks a PBOM document against the JSON Schema and returns its
+// violations followed by semantic findings. Signed documents are checked by
+// their DSSE payload; signatures are left to 'pbom verify'. The error is
+// non-nil only for malformed JSON. The returned PBOM is nil when the
+// document does not decode; its schema errors are then in the findings.
+func checkPBOM(data []byte) (*schema.PBOM, []finding, error) {
+	data, _, err := dsse.Unwrap(data, schema.MediaType)
 	if err != nil {
-		return fmt.Errorf("reading file: %w", err)
+		return nil, nil, err
+	}
+
+	var findings []finding
+
+	// Documents at an older known version are checked in their migrated
+	// form; unknown versions fall through and are reported below.
+	upgraded, from, err := schema.Migrate(data)
+	switch {
+	case err == nil && from != schema.Version:
+		findings = append(findings, finding{
+			Path:     "/pbom_version",
+			RuleID:   "outdated-version",
+			Severity: severityWarning,
+			Message:  fmt.Sprintf("pbom_version %q is outdated and was checked as %q after migration (run 'pbom migrate')", from, schema.Version),
+		})
+		data = upgraded
+	case err != nil && !errors.Is(err, schema.ErrUnsupportedVersion):
+		return nil, nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	// The schema runs on the raw document so that type and format errors
+	// are reported with a pointer rather than as a decoding failure.
+	violations, err := pbomSchema.Validate(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	// The semantic checks overlap the schema (required fields, SHA and
+	// digest patterns, the version); report each problem only once.
+	reported := map[string]bool{}
+	var missing []string
+	for _, v := range violations {
+		findings = append(findings, finding{
+			Path:     v.Path,
+			RuleID:   "schema/" + v.Keyword,
+			Severity: severityError,
+			Message:  v.Message,
+		})
+		if v.Keyword == "required" {
+			missing = append(missing, v.Path+"/"+pointerEscaper.Replace(v.Property))
+		} else {
+			reported[v.Path] = true
+		}
 	}
 
 	var pbom schema.PBOM
 	if err := json.Unmarshal(data, &pbom); err != nil {
-		return fmt.Errorf("invalid JSON: %w", err)
+		if len(violations) == 0 {
+			return nil, nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return nil, findings, nil
 	}
+	for _, f := range validatePBOM(&pbom) {
+		if f.Severity != severityError || !(reported[f.Path] || underMissing(f.Path, missing)) {
+			findings = append(findings, f)
+		}
+	}
+	return &pbom, findings, nil
+}
 
-	var errs []string
+// underMissing reports whether path is, or lies under, one of the missing
+// properties.
+func underMissing(path string, missing []string) bool {
+	for _, m := range missing {
+		if path == m || strings.HasPrefix(path, m+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// pointerEscaper escapes a property name as a JSON pointer token.
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// errorMessages returns the error-severity findings formatted one per line.
+func errorMessages(findings []finding) []string {
+	var msgs []string
+	for _, f := range findings {
+		if f.Severity == severityError {
+			msgs = append(msgs, f.String())
+		}
+	}
+	return msgs
+}
+
+// validatePBOM runs the semantic checks on a decoded PBOM and returns one
+// finding per problem found.
+func validatePBOM(pbom *schema.PBOM) []finding {
+	var findings []finding
+	add := func(severity, rule, path, format string, args ...any) {
+		findings = append(findings, finding{Path: path, RuleID: rule, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+	missing := func(path, field string) {
+		add(severityError, "missing-field", path, "missing %s", field)
+	}
 
 	if pbom.PBOMVersion == "" {
-		errs = append(errs, "missing pbom_version")
+		missing("/pbom_version", "pbom_version")
 	} else if pbom.PBOMVersion != schema.Version {
-		errs = append(errs, fmt.Sprintf("unsupported pbom_version %q (expected %q)", pbom.PBOMVersion, schema.Version))
+		add(severityError, "unsupported-version", "/pbom_version", "unsupported pbom_version %q (expected %q)", pbom.PBOMVersion, schema.Version)
 	}
 
 	if pbom.ID == "" {
-		errs = append(errs, "missing id")
+		missing("/id", "id")
 	}
 
 	if pbom.Timestamp.IsZero() {
-		errs = append(errs, "missing timestamp")
+		missing("/timestamp", "timestamp")
 	}
 
 	if pbom.Source.Repository == "" {
-		errs = append(errs, "missing source.repository")
+		missing("/source/repository", "source.repository")
 	}
 
 	if pbom.Source.CommitSHA == "" {
-		errs = append(errs, "missing source.commit_sha")
+		missing("/source/commit_sha", "source.commit_sha")
 	} else if !isValidSHA(pbom.Source.CommitSHA) {
-		errs = append(errs, fmt.Sprintf("invalid source.commit_sha %q (expected 40-char hex)", pbom.Source.CommitSHA))
+		add(severityError, "commit-sha-format", "/source/commit_sha", "invalid source.commit_sha %q (expected 40-char hex)", pbom.Source.CommitSHA)
+	}
+
+	if pbom.Source.Branch != "" && pbom.Source.Ref == "" {
+		add(severityWarning, "branch-without-ref", "/source/ref", "source.branch %q has no source.ref", pbom.Source.Branch)
 	}
 
 	if pbom.Build.WorkflowRunID == "" {
-		errs = append(errs, "missing build.workflow_run_id")
+		missing("/build/workflow_run_id", "build.workflow_run_id")
 	}
 
 	if pbom.Build.WorkflowName == "" {
-		errs = append(errs, "missing build.workflow_name")
+		missing("/build/workflow_name", "build.workflow_name")
 	}
 
 	if pbom.Build.Actor == "" {
-		errs = append(errs, "missing build.actor")
+		missing("/build/actor", "build.actor")
 	}
 
 	if pbom.Build.Status == "" {
-		errs = append(errs, "missing build.status")
+		missing("/build/status", "build.status")
+	}
+
+	if pbom.Build.CompletedAt == nil {
+		add(severityWarning, "missing-completed-at", "/build/completed_at", "build.completed_at is not set")
 	}
 
 	for i, a := range pbom.Artifacts {
+		base := fmt.Sprintf("/artifacts/%d", i)
 		if a.Name == "" {
-			errs = append(errs, fmt.Sprintf("missing artifacts[%d].name", i))
+			missing(base+"/name", fmt.Sprintf("artifacts[%d].name", i))
 		}
 		if a.Type == "" {
-			errs = append(errs, fmt.Sprintf("missing artifacts[%d].type", i))
+			missing(base+"/type", fmt.Sprintf("artifacts[%d].type", i))
 		}
 		if a.Digest == "" {
-			errs = append(errs, fmt.Sprintf("missing artifacts[%d].digest", i))
+			missing(base+"/digest", fmt.Sprintf("artifacts[%d].digest", i))
 		} else if !isValidDigest(a.Digest) {
-			errs = append(errs, fmt.Sprintf("invalid artifacts[%d].digest %q (expected sha256:<64-char hex>)", i, a.Digest))
+			add(severityError, "digest-format", base+"/digest", "invalid artifacts[%d].digest %q (expected sha256:<64-char hex>)", i, a.Digest)
+		}
+		if a.Vulnerabilities != nil && a.Vulnerabilities.Scanner == "" {
+			add(severityWarning, "vuln-scanner-missing", base+"/vulnerabilities/scanner", "artifacts[%d].vulnerabilities has no scanner name", i)
 		}
 	}
 
-	if len(errs) > 0 {
-		return fmt.Errorf("validation failed:\n  %s", strings.Join(errs, "\n  "))
-	}
-
-	fmt.Fprintln(cmd.OutOrStdout(), "valid")
-	return nil
+	return findings
 }
 
 func isValidSHA(s string) bool {
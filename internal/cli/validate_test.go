@@ -1,6 +1,18 @@
 package cli
 
-import "testing"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+	"github.com/spf13/cobra"
+)
 
 func TestIsValidSHA(t *testing.T) {
 	tests := []struct {
@@ -49,25 +61,396 @@ func TestIsValidDigest(t *testing.T) {
 	}
 }
 
-func TestMapTrigger(t *testing.T) {
+func TestExamplePBOMMatchesSchema(t *testing.T) {
+	data, err := os.ReadFile("../../schema/example.pbom.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, findings, err := checkPBOM(data)
+	if err != nil {
+		t.Fatalf("checkPBOM: %v", err)
+	}
+	for _, f := range findings {
+		t.Errorf("example PBOM: %s %s", f.Severity, f)
+	}
+}
+
+// TestStructsMatchSchema marshals a fully-populated PBOM and checks it
+// against the published schema, so a field added to the Go structs but
+// not to schema/pbom.schema.json (or vice versa) fails the build.
+func TestStructsMatchSchema(t *testing.T) {
+	now := time.Date(2026, 1, 28, 14, 30, 0, 0, time.UTC)
+	digest := "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	pbom := schema.PBOM{
+		PBOMVersion: schema.Version,
+		ID:          "f47ac10b-58cc-4372-a567-0e02b2c3d479",
+		Timestamp:   now,
+		Source: schema.Source{
+			Repository: "acme-corp/app",
+			CommitSHA:  "a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2",
+			Branch:     "main",
+			Ref:        "refs/heads/main",
+			Author:     "jane.doe",
+		},
+		Build: schema.Build{
+			WorkflowRunID:   "1",
+			RunAttempt:      2,
+			WorkflowName:    "CI",
+			WorkflowFile:    ".github/workflows/ci.yml",
+			Trigger:         "push",
+			Actor:           "jane.doe",
+			Runner:          &schema.Runner{OS: "Linux", Arch: "X64", Name: "r", SelfHosted: true},
+			ToolVersions:    map[string]string{"go": "1.23"},
+			SecretsAccessed: []string{"TOKEN"},
+			StartedAt:       &now,
+			CompletedAt:     &now,
+			Status:          "success",
+			Skeleton: &schema.SkeletonSource{
+				RunID:      "2",
+				ArtifactID: 3,
+				Digest:     digest,
+				PBOMID:     "0b7e5e3c-2f1d-4c8a-9e6b-1d2f3a4b5c6d",
+				Verified:   true,
+			},
+		},
+		Artifacts: []schema.Artifact{{
+			Name:            "app",
+			Type:            "container-image",
+			Digest:          digest,
+			URI:             "ghcr.io/acme-corp/app@" + digest,
+			Tags:            []string{"v1"},
+			Provenance:      &schema.Provenance{SLSALevel: 3, BuilderID: "https://github.com/actions/runner", AttestationURI: "https://example.com/att"},
+			Vulnerabilities: &schema.Vulnerabilities{Scanner: "trivy", ScannedAt: &now, Critical: 1},
+		}},
+		Promotion: &schema.Promotion{
+			FreightID:           "f",
+			Stage:               "prod",
+			PromotedBy:          "jane.doe",
+			PromotedAt:          &now,
+			EnvironmentSnapshot: []schema.CoDeployedService{{Name: "svc", Digest: digest, Version: "v1"}},
+		},
+	}
+
+	data, err := json.Marshal(pbom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	violations, err := pbomSchema.Validate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range violations {
+		t.Errorf("schema violation: %s", v.Error())
+	}
+}
+
+func TestCheckPBOMReportsSchemaViolations(t *testing.T) {
+	data, err := os.ReadFile("../../schema/example.pbom.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	doc["id"] = "not-a-uuid"
+	doc["unexpected"] = true
+	doc["build"].(map[string]any)["trigger"] = "nightly"
+	data, _ = json.Marshal(doc)
+
+	_, findings, err := checkPBOM(data)
+	if err != nil {
+		t.Fatalf("checkPBOM: %v", err)
+	}
+	joined := strings.Join(errorMessages(findings), "\n")
+	for _, want := range []string{"/id:", "/unexpected:", "/build/trigger:"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected violation at %s, got:\n%s", want, joined)
+		}
+	}
+}
+
+func TestCheckPBOMReportsEachProblemOnce(t *testing.T) {
+	data, err := os.ReadFile("../../schema/example.pbom.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	source := doc["source"].(map[string]any)
+	delete(source, "repository")
+	source["commit_sha"] = "abc"
+	doc["build"].(map[string]any)["actor"] = "" // allowed by the schema
+	data, _ = json.Marshal(doc)
+
+	_, findings, err := checkPBOM(data)
+	if err != nil {
+		t.Fatalf("checkPBOM: %v", err)
+	}
+	var got []string
+	for _, f := range findings {
+		if f.Severity == severityError {
+			got = append(got, f.Path+" "+f.RuleID)
+		}
+	}
+	want := []string{"/source schema/required", "/source/commit_sha schema/pattern", "/build/actor missing-field"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("errors = %q, want %q", got, want)
+	}
+}
+
+func TestCheckPBOMReportsTypeErrors(t *testing.T) {
+	data, err := os.ReadFile("../../schema/example.pbom.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	doc["timestamp"] = "yesterday"
+	doc["build"].(map[string]any)["run_attempt"] = "1"
+	data, _ = json.Marshal(doc)
+
+	pbom, findings, err := checkPBOM(data)
+	if err != nil {
+		t.Fatalf("checkPBOM: %v", err)
+	}
+	if pbom != nil {
+		t.Error("expected no PBOM for a document that does not decode")
+	}
+	joined := strings.Join(errorMessages(findings), "\n")
+	for _, want := range []string{"/timestamp:", "/build/run_attempt:"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected violation at %s, got:\n%s", want, joined)
+		}
+	}
+}
+
+func TestCheckPBOMMissingParent(t *testing.T) {
+	data, err := os.ReadFile("../../schema/example.pbom.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	delete(doc, "build")
+	data, _ = json.Marshal(doc)
+
+	_, findings, err := checkPBOM(data)
+	if err != nil {
+		t.Fatalf("checkPBOM: %v", err)
+	}
+	var got []string
+	for _, f := range findings {
+		if f.Severity == severityError {
+			got = append(got, f.Path+" "+f.RuleID)
+		}
+	}
+	want := []string{" schema/required"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("errors = %q, want %q", got, want)
+	}
+}
+
+func TestValidatePBOMWarnings(t *testing.T) {
+	pbom := schema.PBOM{
+		PBOMVersion: schema.Version,
+		ID:          "f47ac10b-58cc-4372-a567-0e02b2c3d479",
+		Timestamp:   time.Now(),
+		Source: schema.Source{
+			Repository: "acme-corp/app",
+			CommitSHA:  "a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2",
+			Branch:     "main",
+		},
+		Build: schema.Build{WorkflowRunID: "1", WorkflowName: "CI", Actor: "a", Status: "success"},
+		Artifacts: []schema.Artifact{{
+			Name:            "app",
+			Type:            "container-image",
+			Digest:          "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			Vulnerabilities: &schema.Vulnerabilities{High: 1},
+		}},
+	}
+
+	got := map[string]string{}
+	for _, f := range validatePBOM(&pbom) {
+		if f.Severity != severityWarning {
+			t.Errorf("unexpected %s finding: %s", f.Severity, f)
+		}
+		got[f.RuleID] = f.Path
+	}
+	want := map[string]string{
+		"branch-without-ref":   "/source/ref",
+		"missing-completed-at": "/build/completed_at",
+		"vuln-scanner-missing": "/artifacts/0/vulnerabilities/scanner",
+	}
+	for rule, path := range want {
+		if got[rule] != path {
+			t.Errorf("rule %s: path = %q, want %q", rule, got[rule], path)
+		}
+	}
+}
+
+func TestWriteReportFormats(t *testing.T) {
+	reports := []fileReport{{
+		File: "a.pbom.json",
+		Findings: []finding{
+			{Path: "/id", RuleID: "schema/format", Severity: severityError, Message: "bad id"},
+			{Path: "/build/completed_at", RuleID: "missing-completed-at", Severity: severityWarning, Message: "unset"},
+		},
+	}}
+
+	for _, format := range []string{"json", "sarif"} {
+		t.Run(format, func(t *testing.T) {
+			var out bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetOut(&out)
+			if err := writeReport(cmd, format, reports); err != errValidationFailed {
+				t.Fatalf("writeReport error = %v, want errValidationFailed", err)
+			}
+			var doc map[string]any
+			if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+				t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+			}
+			for _, want := range []string{`"schema/format"`, `"missing-completed-at"`, `"/build/completed_at"`} {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %s:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	warnOnly := []fileReport{{File: "a.pbom.json", Findings: reports[0].Findings[1:]}}
+	if err := writeReport(cmd, "text", warnOnly); err != nil {
+		t.Fatalf("warnings must not fail validation: %v", err)
+	}
+	if !strings.Contains(out.String(), "warning: /build/completed_at") || !strings.HasSuffix(out.String(), "valid\n") {
+		t.Errorf("unexpected text output:\n%s", out.String())
+	}
+}
+
+func TestWriteReportTypeErrors(t *testing.T) {
+	data, err := os.ReadFile("../../schema/example.pbom.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	doc["timestamp"] = "yesterday"
+	doc["build"].(map[string]any)["run_attempt"] = "1"
+	data, _ = json.Marshal(doc)
+	path := filepath.Join(t.TempDir(), "a.pbom.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reports := []fileReport{validateFile(path)}
+
+	for _, format := range []string{"json", "sarif"} {
+		t.Run(format, func(t *testing.T) {
+			var out bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetOut(&out)
+			if err := writeReport(cmd, format, reports); err != errValidationFailed {
+				t.Fatalf("writeReport error = %v, want errValidationFailed", err)
+			}
+			for _, want := range []string{`"/timestamp"`, `"/build/run_attempt"`, `"schema/type"`} {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %s:\n%s", want, out.String())
+				}
+			}
+			if strings.Contains(out.String(), "unreadable-document") {
+				t.Errorf("type errors reported as an unreadable document:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestExpandPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.pbom.json", "b.pbom.json", "notes.txt", "sub/c.pbom.json"} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
 	tests := []struct {
-		input string
-		want  string
+		name    string
+		args    []string
+		want    int
+		wantErr bool
 	}{
-		{"push", "push"},
-		{"pull_request", "pull_request"},
-		{"workflow_dispatch", "workflow_dispatch"},
-		{"schedule", "schedule"},
-		{"release", "release"},
-		{"", ""},
-		{"repository_dispatch", "other"},
-		{"workflow_call", "other"},
+		{"directory recursive", []string{dir}, 3, false},
+		{"glob", []string{filepath.Join(dir, "*.pbom.json")}, 2, false},
+		{"deduplicated", []string{filepath.Join(dir, "a.pbom.json"), dir}, 3, false},
+		{"missing file kept for reporting", []string{filepath.Join(dir, "missing.json")}, 1, false},
+		{"glob without matches", []string{filepath.Join(dir, "*.yaml")}, 0, true},
 	}
 	for _, tt := range tests {
-		t.Run(tt.input, func(t *testing.T) {
-			if got := mapTrigger(tt.input); got != tt.want {
-				t.Errorf("mapTrigger(%q) = %q, want %q", tt.input, got, tt.want)
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandPaths(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expandPaths error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.want {
+				t.Errorf("expandPaths = %v, want %d paths", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestValidateFiles(t *testing.T) {
+	example, err := os.ReadFile("../../schema/example.pbom.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	var paths []string
+	for i := 0; i < 20; i++ {
+		p := filepath.Join(dir, fmt.Sprintf("%02d.pbom.json", i))
+		data := example
+		if i%5 == 0 {
+			data = []byte("not json")
+		}
+		if err := os.WriteFile(p, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+
+	reports := validateFiles(paths, 4)
+	if len(reports) != len(paths) {
+		t.Fatalf("got %d reports, want %d", len(reports), len(paths))
+	}
+	for i, r := range reports {
+		if r.File != paths[i] {
+			t.Errorf("report %d is for %s, want %s", i, r.File, paths[i])
+		}
+		if wantValid := i%5 != 0; r.valid() != wantValid {
+			t.Errorf("%s: valid = %v, want %v", r.File, r.valid(), wantValid)
+		}
+	}
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	if err := writeReport(cmd, "text", reports); err == nil {
+		t.Error("expected batch with invalid files to fail")
+	}
+	if !strings.Contains(out.String(), "20 files: 16 valid, 4 invalid") {
+		t.Errorf("summary missing:\n%s", out.String())
+	}
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/dsse"
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyKeys        []string
+	verifyTrustBundle string
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify <file>",
+	Short: "Verify the signature of a DSSE-signed PBOM",
+	Long: `Checks that a signed PBOM (see 'pbom sign') carries at least one valid
+signature from a trusted key.
+
+Trusted keys are PEM public keys given with --key (repeatable) and/or a
+trust bundle: a PEM file holding any number of PUBLIC KEY or CERTIFICATE
+blocks. Unsigned documents always fail verification.
+
+Example:
+  pbom verify --key cosign.pub pbom.signed.json
+  pbom verify --trust-bundle /etc/pbom/trusted-keys.pem pbom.signed.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runVerify,
+}
+
+func init() {
+	verifyCmd.Flags().StringArrayVar(&verifyKeys, "key", nil, "Path to a PEM public key (repeatable)")
+	verifyCmd.Flags().StringVar(&verifyTrustBundle, "trust-bundle", "", "Path to a PEM bundle of trusted public keys or certificates")
+	verifyCmd.MarkFlagsOneRequired("key", "trust-bundle")
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	trusted, err := loadTrustedKeys(verifyKeys, verifyTrustBundle)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("reading file: %w", err)
+	}
+
+	_, env, err := dsse.Unwrap(data, schema.MediaType)
+	if err != nil {
+		return err
+	}
+	if env == nil {
+		return errors.New("document is not signed (no DSSE envelope)")
+	}
+
+	keyID, err := env.Verify(trusted)
+	if err != nil {
+		return fmt.Errorf("verification failed: %w", err)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "verified: signed by key %s\n", keyID)
+	return nil
+}
+
+// loadTrustedKeys reads the public keys from each key file and the trust
+// bundle.
+func loadTrustedKeys(keyFiles []string, bundle string) ([]crypto.PublicKey, error) {
+	files := append([]string{}, keyFiles...)
+	if bundle != "" {
+		files = append(files, bundle)
+	}
+
+	var keys []crypto.PublicKey
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading key: %w", err)
+		}
+		parsed, err := dsse.ParsePublicKeys(data)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s: %w", path, err)
+		}
+		keys = append(keys, parsed...)
+	}
+	return keys, nil
+}
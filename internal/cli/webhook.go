@@ -2,12 +2,20 @@ package cli
 
 import (
 	"context"
+	"crypto"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 
+	"github.com/BuildGuard-Test-Lab/pbom/internal/dsse"
+	gh "github.com/BuildGuard-Test-Lab/pbom/internal/github"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/queue"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
 	"github.com/BuildGuard-Test-Lab/pbom/internal/webhook"
 	"github.com/spf13/cobra"
 )
@@ -17,24 +25,103 @@ var (
 	webhookSecret     string
 	webhookToken      string
 	webhookStorageDir string
+	webhookStorage    string
+	webhookAPIToken   string
+	webhookQueue      string
+	webhookWorkers    int
+	webhookAttempts   int
+	webhookSigningKey string
+	webhookArchive    string
+	webhookAppID      int64
+	webhookAppKey     string
+	webhookCacheDir   string
+	webhookGitHubURL  string
+	webhookCABundle   string
+	webhookMaxArtMB   int
 )
 
 var webhookCmd = &cobra.Command{
 	Use:   "webhook",
 	Short: "Start the PBOM webhook listener for GitHub org events",
 	Long: `Starts an HTTP server that listens for workflow_run.completed webhook
-events from GitHub. For each developer CI completion, it:
+events from GitHub. Accepted events are persisted to a durable queue
+before the listener responds, then processed by a bounded pool of
+workers. For each developer CI completion, a worker:
 
-  1. Downloads the skeleton PBOM from the companion collector run
+  1. Downloads the skeleton PBOM from the companion collector run and
+     checks it against the artifact digest GitHub reports
   2. Queries the GitHub API for runner details, secrets, and artifacts
   3. Enriches the PBOM with the collected data
-  4. Stores the enriched PBOM locally
+  4. Stores the enriched PBOM in the configured storage backend
+
+Failed runs are retried with exponential backoff; after --max-attempts
+they move to a dead-letter list (see 'pbom queue').
+
+Redeliveries are acknowledged without reprocessing: the listener records
+each X-GitHub-Delivery ID and (repository, run ID, run attempt) for 30
+days in the queue storage. To force re-enrichment, POST the signed event
+to /webhook?force=1 with "Authorization: Bearer <api token>".
+
+GitHub API responses are cached in memory with their ETags and
+revalidated with conditional requests, which GitHub does not count
+against the rate limit when nothing changed.
+
+Prometheus metrics are served unauthenticated on GET /metrics: webhook
+deliveries by event and outcome, signature failures, enrichment step
+durations, GitHub API calls by endpoint and status, skeleton found,
+rejected (digest mismatch) and fallback counts, and queue depth. /status
+reports the number of events enriched and when the last one finished.
+
+With an API token configured it also serves a read-only query API:
+
+  GET /api/v1/pboms                    Filter by repo, sha, branch, workflow,
+                                       conclusion, since, until; paginate
+                                       with limit and page_token
+  GET /api/v1/pboms/{id}               A single PBOM
+  GET /api/v1/artifacts/{digest}/pboms PBOMs of builds that produced a digest
+
+Requests must send "Authorization: Bearer <token>". Queries by ID, repo,
+branch, sha and digest are answered from the storage index; run
+'pbom lookup --reindex' once to index PBOMs stored by older releases.
 
 Configuration via flags or environment variables:
   --addr / PBOM_WEBHOOK_ADDR           Listen address (default :8080)
   --secret / PBOM_WEBHOOK_SECRET       GitHub webhook secret
   --token / GITHUB_TOKEN               GitHub token for API access
-  --storage-dir / PBOM_STORAGE_DIR     Directory for enriched PBOMs`,
+  --github-url / GITHUB_API_URL        API URL of a GitHub Enterprise Server,
+                                       e.g. https://ghe.example.com/api/v3
+  --github-ca-bundle / PBOM_GITHUB_CA_BUNDLE
+                                       PEM CA certificates to trust for the
+                                       GitHub API in addition to the system's
+  --app-id / GITHUB_APP_ID             Authenticate as this GitHub App
+                                       instead of with a token, using the
+                                       installation each event came from
+  --app-key / GITHUB_APP_PRIVATE_KEY_FILE
+                                       The GitHub App's PEM private key
+  --storage / PBOM_STORAGE             Storage URL for enriched PBOMs
+  --storage-dir / PBOM_STORAGE_DIR     Directory for enriched PBOMs when
+                                       --storage is not set
+  --signing-key / PBOM_SIGNING_KEY     PEM private key to sign stored PBOMs
+  --api-token / PBOM_API_TOKEN         Bearer token enabling the query API
+  --queue / PBOM_QUEUE                 Queue directory or sqlite:// URL
+  --max-artifact-mb / PBOM_MAX_ARTIFACT_MB
+                                       Largest artifact to download, in MiB
+                                       (default 64)
+  --github-cache / PBOM_GITHUB_CACHE   Directory persisting the GitHub API
+                                       response cache across restarts;
+                                       entries unused for a day are removed
+  --archive / PBOM_ARCHIVE             Directory or storage URL archiving
+                                       raw verified payloads, one file per
+                                       delivery under {date}/ (see
+                                       'pbom webhook replay')
+
+Storage URLs:
+  ./pbom-data, file:///var/lib/pbom    One file per PBOM in a directory
+  sqlite:///var/lib/pbom/pbom.db       SQLite database file
+  s3://bucket/prefix                   S3 bucket (AWS_ACCESS_KEY_ID,
+                                       AWS_SECRET_ACCESS_KEY, AWS_REGION)
+  s3://bucket/prefix?endpoint=http://minio:9000
+                                       S3-compatible service (MinIO, ...)`,
 	RunE: runWebhook,
 }
 
@@ -42,7 +129,21 @@ func init() {
 	webhookCmd.Flags().StringVar(&webhookAddr, "addr", ":8080", "Listen address")
 	webhookCmd.Flags().StringVar(&webhookSecret, "secret", "", "GitHub webhook secret (or PBOM_WEBHOOK_SECRET env)")
 	webhookCmd.Flags().StringVar(&webhookToken, "token", "", "GitHub token (or GITHUB_TOKEN env)")
+	webhookCmd.Flags().StringVar(&webhookGitHubURL, "github-url", "", "GitHub Enterprise Server API URL (or GITHUB_API_URL env; default https://api.github.com)")
+	webhookCmd.Flags().StringVar(&webhookCABundle, "github-ca-bundle", "", "PEM CA bundle for the GitHub API (or PBOM_GITHUB_CA_BUNDLE env)")
+	webhookCmd.Flags().Int64Var(&webhookAppID, "app-id", 0, "GitHub App ID to authenticate as instead of a token (or GITHUB_APP_ID env)")
+	webhookCmd.Flags().StringVar(&webhookAppKey, "app-key", "", "GitHub App PEM private key file (or GITHUB_APP_PRIVATE_KEY_FILE env)")
 	webhookCmd.Flags().StringVar(&webhookStorageDir, "storage-dir", "./pbom-data", "Storage directory (or PBOM_STORAGE_DIR env)")
+	webhookCmd.Flags().StringVar(&webhookStorage, "storage", "", "Storage URL: dir, file://, sqlite:// or s3:// (or PBOM_STORAGE env)")
+	webhookCmd.Flags().StringVar(&webhookAPIToken, "api-token", "", "Bearer token that enables the query API (or PBOM_API_TOKEN env)")
+	webhookCmd.Flags().StringVar(&webhookQueue, "queue", defaultQueue, "Job queue directory or sqlite:// URL (or PBOM_QUEUE env)")
+	webhookCmd.Flags().IntVar(&webhookWorkers, "workers", 4, "Maximum concurrent enrichment jobs")
+	webhookCmd.Flags().IntVar(&webhookAttempts, "max-attempts", 8, "Attempts per job before it is dead-lettered")
+	webhookCmd.Flags().StringVar(&webhookSigningKey, "signing-key", "", "PEM private key to sign stored PBOMs with (or PBOM_SIGNING_KEY env)")
+	webhookCmd.Flags().StringVar(&webhookArchive, "archive", "", "Archive verified workflow_run payloads to this directory or storage URL (or PBOM_ARCHIVE env)")
+	webhookCmd.Flags().StringVar(&webhookCacheDir, "github-cache", "", "Persist cached GitHub API responses in this directory (or PBOM_GITHUB_CACHE env)")
+	webhookCmd.Flags().IntVar(&webhookMaxArtMB, "max-artifact-mb", 64, "Largest artifact to download, in MiB (or PBOM_MAX_ARTIFACT_MB env)")
+	webhookCmd.AddCommand(webhookReplayCmd)
 }
 
 func runWebhook(cmd *cobra.Command, args []string) error {
@@ -58,28 +159,128 @@ func runWebhook(cmd *cobra.Command, args []string) error {
 			webhookStorageDir = dir
 		}
 	}
+	if webhookStorage == "" {
+		webhookStorage = os.Getenv("PBOM_STORAGE")
+	}
+	if webhookStorage == "" {
+		webhookStorage = webhookStorageDir
+	}
+	if !cmd.Flags().Changed("queue") {
+		if q := os.Getenv("PBOM_QUEUE"); q != "" {
+			webhookQueue = q
+		}
+	}
 	if !cmd.Flags().Changed("addr") {
 		if addr := os.Getenv("PBOM_WEBHOOK_ADDR"); addr != "" {
 			webhookAddr = addr
 		}
 	}
 
+	if webhookSigningKey == "" {
+		webhookSigningKey = os.Getenv("PBOM_SIGNING_KEY")
+	}
+
+	if webhookAPIToken == "" {
+		webhookAPIToken = os.Getenv("PBOM_API_TOKEN")
+	}
+
+	if webhookArchive == "" {
+		webhookArchive = os.Getenv("PBOM_ARCHIVE")
+	}
+
+	if !cmd.Flags().Changed("max-artifact-mb") {
+		if v := os.Getenv("PBOM_MAX_ARTIFACT_MB"); v != "" {
+			mb, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("PBOM_MAX_ARTIFACT_MB %q is not a number", v)
+			}
+			webhookMaxArtMB = mb
+		}
+	}
+	if webhookMaxArtMB <= 0 {
+		return fmt.Errorf("--max-artifact-mb must be positive")
+	}
+
+	if webhookCacheDir == "" {
+		webhookCacheDir = os.Getenv("PBOM_GITHUB_CACHE")
+	}
+
 	if webhookSecret == "" {
 		return fmt.Errorf("webhook secret required (--secret or PBOM_WEBHOOK_SECRET)")
 	}
-	if webhookToken == "" {
-		return fmt.Errorf("GitHub token required (--token or GITHUB_TOKEN)")
+	githubURL, transport, err := loadGitHubEndpoint(webhookGitHubURL, webhookCABundle)
+	if err != nil {
+		return err
+	}
+	app, err := loadGitHubApp(webhookAppID, webhookAppKey, githubURL, transport)
+	if err != nil {
+		return err
+	}
+	if webhookToken == "" && app == nil {
+		return fmt.Errorf("GitHub token (--token or GITHUB_TOKEN) or App (--app-id and --app-key) required")
+	}
+
+	signer, err := loadSigningKey(webhookSigningKey)
+	if err != nil {
+		return err
+	}
+
+	store, err := storage.Open(webhookStorage)
+	if err != nil {
+		return fmt.Errorf("opening storage: %w", err)
+	}
+	if c, ok := store.(io.Closer); ok {
+		defer c.Close()
 	}
 
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
 	}))
 
+	queueStore, err := openQueueStorage(webhookQueue)
+	if err != nil {
+		return err
+	}
+	if c, ok := queueStore.(io.Closer); ok {
+		defer c.Close()
+	}
+	var archive storage.Storage
+	if webhookArchive != "" {
+		if archive, err = storage.Open(webhookArchive); err != nil {
+			return fmt.Errorf("opening archive: %w", err)
+		}
+		if c, ok := archive.(io.Closer); ok {
+			defer c.Close()
+		}
+	}
+
+	var cache *gh.Cache
+	if webhookCacheDir != "" {
+		if cache, err = gh.OpenCache(webhookCacheDir); err != nil {
+			return err
+		}
+	}
+
+	jobs := queue.New(queueStore, queue.Config{
+		Workers:     webhookWorkers,
+		MaxAttempts: webhookAttempts,
+	}, logger)
+
 	cfg := webhook.Config{
-		Addr:          webhookAddr,
-		WebhookSecret: webhookSecret,
-		GitHubToken:   webhookToken,
-		StorageDir:    webhookStorageDir,
+		Addr:            webhookAddr,
+		WebhookSecret:   webhookSecret,
+		GitHubToken:     webhookToken,
+		GitHubURL:       githubURL,
+		GitHubTransport: transport,
+		GitHubApp:       app,
+		GitHubCache:     cache,
+		MaxArtifactSize: int64(webhookMaxArtMB) << 20,
+		Storage:         store,
+		Queue:           jobs,
+		Dedupe:          webhook.NewDeduper(queueStore, 0),
+		SigningKey:      signer,
+		APIToken:        webhookAPIToken,
+		Archive:         archive,
 	}
 
 	srv := webhook.NewServer(cfg, logger)
@@ -89,3 +290,91 @@ func runWebhook(cmd *cobra.Command, args []string) error {
 
 	return srv.Start(ctx)
 }
+
+// loadSigningKey reads a PEM private key. An empty path returns nil, which
+// stores PBOMs unsigned.
+func loadSigningKey(path string) (crypto.Signer, error) {
+	if path == "" {
+		return nil, nil
+	}
+	keyData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading signing key: %w", err)
+	}
+	signer, err := dsse.ParsePrivateKey(keyData)
+	if err != nil {
+		return nil, fmt.Errorf("loading signing key %s: %w", path, err)
+	}
+	return signer, nil
+}
+
+// loadGitHubEndpoint resolves the GitHub API URL, falling back to
+// GITHUB_API_URL and then github.com, and a transport trusting the CA
+// bundle at caPath (or PBOM_GITHUB_CA_BUNDLE). The transport is nil when
+// no bundle is given.
+func loadGitHubEndpoint(rawURL, caPath string) (string, http.RoundTripper, error) {
+	if rawURL == "" {
+		rawURL = os.Getenv("GITHUB_API_URL")
+	}
+	baseURL := gh.DefaultBaseURL
+	if rawURL != "" {
+		var err error
+		if baseURL, err = gh.ParseBaseURL(rawURL); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if caPath == "" {
+		caPath = os.Getenv("PBOM_GITHUB_CA_BUNDLE")
+	}
+	if caPath == "" {
+		return baseURL, nil, nil
+	}
+	pemData, err := os.ReadFile(caPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("reading GitHub CA bundle: %w", err)
+	}
+	transport, err := gh.NewTransport(pemData)
+	if err != nil {
+		return "", nil, fmt.Errorf("loading GitHub CA bundle %s: %w", caPath, err)
+	}
+	return baseURL, transport, nil
+}
+
+// loadGitHubApp returns the GitHub App configured by --app-id and --app-key
+// or GITHUB_APP_ID and GITHUB_APP_PRIVATE_KEY_FILE, or nil if neither is
+// set. The app calls the API at baseURL, through transport if not nil.
+func loadGitHubApp(appID int64, keyPath, baseURL string, transport http.RoundTripper) (*gh.App, error) {
+	if appID == 0 {
+		if v := os.Getenv("GITHUB_APP_ID"); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("GITHUB_APP_ID %q is not a number", v)
+			}
+			appID = id
+		}
+	}
+	if keyPath == "" {
+		keyPath = os.Getenv("GITHUB_APP_PRIVATE_KEY_FILE")
+	}
+	switch {
+	case appID == 0 && keyPath == "":
+		return nil, nil
+	case appID == 0:
+		return nil, fmt.Errorf("GitHub App key given without an app ID (--app-id or GITHUB_APP_ID)")
+	case keyPath == "":
+		return nil, fmt.Errorf("GitHub App ID given without a private key (--app-key or GITHUB_APP_PRIVATE_KEY_FILE)")
+	}
+	keyData, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading GitHub App key: %w", err)
+	}
+	app, err := gh.NewAppWithBase(appID, keyData, baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("loading GitHub App key %s: %w", keyPath, err)
+	}
+	if transport != nil {
+		app.SetTransport(transport)
+	}
+	return app, nil
+}
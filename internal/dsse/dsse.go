@@ -0,0 +1,260 @@
+// Package dsse signs and verifies Dead Simple Signing Envelopes
+// (https://github.com/secure-systems-lab/dsse) with ECDSA and Ed25519 keys
+// loaded from PEM files.
+package dsse
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// ErrNoValidSignature is returned when none of an envelope's signatures
+// verifies against the trusted keys.
+var ErrNoValidSignature = errors.New("no valid signature from a trusted key")
+
+// Envelope is a DSSE envelope. Payload and signatures are base64-encoded
+// in the JSON form.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     []byte      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature is one signature over an envelope's PAE encoding.
+type Signature struct {
+	KeyID string `json:"keyid,omitempty"`
+	Sig   []byte `json:"sig"`
+}
+
+// PAE returns the DSSE pre-authentication encoding of a payload, which is
+// the byte string actually signed.
+func PAE(payloadType string, payload []byte) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "DSSEv1 %d %s %d ", len(payloadType), payloadType, len(payload))
+	b.Write(payload)
+	return b.Bytes()
+}
+
+// Parse decodes a DSSE envelope. It fails if data is not an envelope.
+func Parse(data []byte) (*Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("parsing DSSE envelope: %w", err)
+	}
+	if env.PayloadType == "" || env.Payload == nil || env.Signatures == nil {
+		return nil, errors.New("not a DSSE envelope: payloadType, payload and signatures are required")
+	}
+	return &env, nil
+}
+
+// IsEnvelope reports whether data looks like a DSSE envelope rather than
+// a bare document.
+func IsEnvelope(data []byte) bool {
+	var probe struct {
+		PayloadType *string          `json:"payloadType"`
+		Payload     *string          `json:"payload"`
+		Signatures  *json.RawMessage `json:"signatures"`
+	}
+	if json.Unmarshal(data, &probe) != nil {
+		return false
+	}
+	return probe.PayloadType != nil && probe.Payload != nil && probe.Signatures != nil
+}
+
+// Unwrap returns the payload of data if it is an envelope of the given
+// payload type, or data itself if it is not an envelope. The envelope is
+// returned so callers can report or verify it; signatures are not checked.
+func Unwrap(data []byte, payloadType string) ([]byte, *Envelope, error) {
+	if !IsEnvelope(data) {
+		return data, nil, nil
+	}
+	env, err := Parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if env.PayloadType != payloadType {
+		return nil, nil, fmt.Errorf("DSSE payload type is %q, expected %q", env.PayloadType, payloadType)
+	}
+	return env.Payload, env, nil
+}
+
+// Sign creates an envelope around payload signed by signer.
+func Sign(payloadType string, payload []byte, signer crypto.Signer) (*Envelope, error) {
+	env := &Envelope{PayloadType: payloadType, Payload: payload, Signatures: []Signature{}}
+	if err := env.AddSignature(signer); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// AddSignature appends a signature by signer over the envelope's payload.
+func (e *Envelope) AddSignature(signer crypto.Signer) error {
+	keyID, err := KeyID(signer.Public())
+	if err != nil {
+		return err
+	}
+
+	msg := PAE(e.PayloadType, e.Payload)
+	var sig []byte
+	switch pub := signer.Public().(type) {
+	case ed25519.PublicKey:
+		sig, err = signer.Sign(rand.Reader, msg, crypto.Hash(0))
+	case *ecdsa.PublicKey:
+		h, hashFn := ecdsaHash(pub.Curve)
+		h.Write(msg)
+		sig, err = signer.Sign(rand.Reader, h.Sum(nil), hashFn)
+	default:
+		return fmt.Errorf("unsupported key type %T (expected ECDSA or Ed25519)", pub)
+	}
+	if err != nil {
+		return fmt.Errorf("signing: %w", err)
+	}
+
+	e.Signatures = append(e.Signatures, Signature{KeyID: keyID, Sig: sig})
+	return nil
+}
+
+// Verify checks the envelope's signatures against the trusted keys and
+// returns the key ID of the first signature that verifies.
+func (e *Envelope) Verify(keys []crypto.PublicKey) (string, error) {
+	if len(e.Signatures) == 0 {
+		return "", errors.New("envelope has no signatures")
+	}
+
+	msg := PAE(e.PayloadType, e.Payload)
+	for _, sig := range e.Signatures {
+		for _, key := range keys {
+			if verifySig(key, msg, sig.Sig) {
+				return KeyID(key)
+			}
+		}
+	}
+	return "", ErrNoValidSignature
+}
+
+func verifySig(key crypto.PublicKey, msg, sig []byte) bool {
+	switch pub := key.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(pub, msg, sig)
+	case *ecdsa.PublicKey:
+		h, _ := ecdsaHash(pub.Curve)
+		h.Write(msg)
+		return ecdsa.VerifyASN1(pub, h.Sum(nil), sig)
+	default:
+		return false
+	}
+}
+
+// ecdsaHash picks the digest matching the curve size, as in ECDSA-SHA2
+// signature suites.
+func ecdsaHash(curve elliptic.Curve) (hash.Hash, crypto.Hash) {
+	switch curve.Params().BitSize {
+	case 384:
+		return sha512.New384(), crypto.SHA384
+	case 521:
+		return sha512.New(), crypto.SHA512
+	default:
+		return sha256.New(), crypto.SHA256
+	}
+}
+
+// KeyID identifies a public key by the hex SHA-256 of its PKIX encoding.
+func KeyID(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("encoding public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ParsePrivateKey reads an unencrypted ECDSA or Ed25519 private key from
+// PEM ("PRIVATE KEY" or "EC PRIVATE KEY").
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "ENCRYPTED PRIVATE KEY":
+		return nil, errors.New("encrypted private keys are not supported")
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key: %w", err)
+	}
+
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		return k, nil
+	case ed25519.PrivateKey:
+		return k, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T (expected ECDSA or Ed25519)", key)
+	}
+}
+
+// ParsePublicKeys reads every ECDSA or Ed25519 public key from a PEM
+// trust bundle. "PUBLIC KEY" blocks and "CERTIFICATE" blocks are accepted;
+// other blocks are ignored.
+func ParsePublicKeys(data []byte) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		var key any
+		switch block.Type {
+		case "PUBLIC KEY":
+			k, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("parsing public key: %w", err)
+			}
+			key = k
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("parsing certificate: %w", err)
+			}
+			key = cert.PublicKey
+		default:
+			continue
+		}
+
+		switch key.(type) {
+		case *ecdsa.PublicKey, ed25519.PublicKey:
+			keys = append(keys, key)
+		default:
+			return nil, fmt.Errorf("unsupported key type %T (expected ECDSA or Ed25519)", key)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no public keys found in PEM data")
+	}
+	return keys, nil
+}
@@ -0,0 +1,160 @@
+package dsse
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"testing"
+)
+
+func TestPAE(t *testing.T) {
+	// Test vector from the DSSE protocol specification.
+	got := string(PAE("http://example.com/HelloWorld", []byte("hello world")))
+	want := "DSSEv1 29 http://example.com/HelloWorld 11 hello world"
+	if got != want {
+		t.Errorf("PAE = %q, want %q", got, want)
+	}
+}
+
+func testKeys(t *testing.T) map[string]crypto.Signer {
+	t.Helper()
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ed, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]crypto.Signer{"ecdsa-p256": p256, "ecdsa-p384": p384, "ed25519": ed}
+}
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	for name, key := range testKeys(t) {
+		t.Run(name, func(t *testing.T) {
+			env, err := Sign("application/test", []byte(`{"a":1}`), key)
+			if err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+
+			// Through JSON, as it would be stored on disk.
+			data, err := json.Marshal(env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !IsEnvelope(data) {
+				t.Fatal("IsEnvelope = false for a signed envelope")
+			}
+			parsed, err := Parse(data)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			keyID, err := parsed.Verify([]crypto.PublicKey{key.Public()})
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if want, _ := KeyID(key.Public()); keyID != want {
+				t.Errorf("keyID = %s, want %s", keyID, want)
+			}
+
+			parsed.Payload = []byte(`{"a":2}`)
+			if _, err := parsed.Verify([]crypto.PublicKey{key.Public()}); !errors.Is(err, ErrNoValidSignature) {
+				t.Errorf("tampered payload: err = %v, want ErrNoValidSignature", err)
+			}
+		})
+	}
+}
+
+func TestVerifyUntrustedKey(t *testing.T) {
+	keys := testKeys(t)
+	env, err := Sign("application/test", []byte("x"), keys["ed25519"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.Verify([]crypto.PublicKey{keys["ecdsa-p256"].Public()}); !errors.Is(err, ErrNoValidSignature) {
+		t.Errorf("err = %v, want ErrNoValidSignature", err)
+	}
+
+	// A second signature from a trusted key is enough.
+	if err := env.AddSignature(keys["ecdsa-p256"]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.Verify([]crypto.PublicKey{keys["ecdsa-p256"].Public()}); err != nil {
+		t.Errorf("Verify with second signer: %v", err)
+	}
+}
+
+func TestUnwrap(t *testing.T) {
+	bare := []byte(`{"pbom_version":"1.0.0"}`)
+	payload, env, err := Unwrap(bare, "application/test")
+	if err != nil || env != nil || string(payload) != string(bare) {
+		t.Errorf("bare document: payload=%s env=%v err=%v", payload, env, err)
+	}
+
+	signed, err := Sign("application/test", bare, testKeys(t)["ed25519"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(signed)
+	payload, env, err = Unwrap(data, "application/test")
+	if err != nil || env == nil || string(payload) != string(bare) {
+		t.Errorf("envelope: payload=%s env=%v err=%v", payload, env, err)
+	}
+
+	if _, _, err := Unwrap(data, "application/other"); err == nil {
+		t.Error("expected error for mismatched payload type")
+	}
+}
+
+func TestParseKeysPEM(t *testing.T) {
+	var bundle []byte
+	for name, key := range testKeys(t) {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+		if err != nil {
+			t.Fatalf("%s: ParsePrivateKey: %v", name, err)
+		}
+		got, _ := KeyID(parsed.Public())
+		if want, _ := KeyID(key.Public()); got != want {
+			t.Errorf("%s: parsed key differs", name)
+		}
+
+		pub, err := x509.MarshalPKIXPublicKey(key.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub})...)
+	}
+
+	keys, err := ParsePublicKeys(bundle)
+	if err != nil {
+		t.Fatalf("ParsePublicKeys: %v", err)
+	}
+	if len(keys) != 3 {
+		t.Errorf("got %d keys, want 3", len(keys))
+	}
+
+	ec, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	sec1, _ := x509.MarshalECPrivateKey(ec)
+	if _, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1})); err != nil {
+		t.Errorf("EC PRIVATE KEY: %v", err)
+	}
+
+	if _, err := ParsePublicKeys([]byte("not pem")); err == nil {
+		t.Error("expected error for empty bundle")
+	}
+}
@@ -0,0 +1,231 @@
+package export
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+)
+
+// CycloneDXSpecVersion is the CycloneDX version produced by ToCycloneDX;
+// 1.5 is the first with formulation/workflow support.
+const CycloneDXSpecVersion = "1.5"
+
+// Property names used where CycloneDX has no dedicated field.
+const (
+	propRepository   = "pbom:source:repository"
+	propCommitSHA    = "pbom:source:commit_sha"
+	propBranch       = "pbom:source:branch"
+	propRef          = "pbom:source:ref"
+	propWorkflowFile = "pbom:build:workflow_file"
+	propTrigger      = "pbom:build:trigger"
+	propActor        = "pbom:build:actor"
+	propStatus       = "pbom:build:status"
+	propRunnerOS     = "pbom:build:runner_os"
+	propRunnerArch   = "pbom:build:runner_arch"
+	propArtifactType = "pbom:artifact:type"
+	propTag          = "pbom:artifact:tag"
+)
+
+// CycloneDX is a CycloneDX 1.5 BOM, limited to the fields PBOM data maps to.
+type CycloneDX struct {
+	BOMFormat    string         `json:"bomFormat"`
+	SpecVersion  string         `json:"specVersion"`
+	SerialNumber string         `json:"serialNumber,omitempty"`
+	Version      int            `json:"version"`
+	Metadata     CDXMetadata    `json:"metadata"`
+	Components   []CDXComponent `json:"components,omitempty"`
+	Formulation  []CDXFormula   `json:"formulation,omitempty"`
+}
+
+// CDXMetadata describes the BOM itself.
+type CDXMetadata struct {
+	Timestamp time.Time `json:"timestamp"`
+	Tools     *CDXTools `json:"tools,omitempty"`
+}
+
+// CDXTools lists the tools that produced the BOM.
+type CDXTools struct {
+	Components []CDXComponent `json:"components"`
+}
+
+// CDXComponent is a software component: a produced artifact or a build tool.
+type CDXComponent struct {
+	BOMRef             string           `json:"bom-ref,omitempty"`
+	Type               string           `json:"type"`
+	Name               string           `json:"name"`
+	Version            string           `json:"version,omitempty"`
+	Hashes             []CDXHash        `json:"hashes,omitempty"`
+	ExternalReferences []CDXExternalRef `json:"externalReferences,omitempty"`
+	Properties         []CDXProperty    `json:"properties,omitempty"`
+}
+
+// CDXHash is a component hash.
+type CDXHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+// CDXExternalRef points at a resource outside the BOM.
+type CDXExternalRef struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// CDXProperty is a name/value pair.
+type CDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CDXFormula describes how components were made.
+type CDXFormula struct {
+	BOMRef     string         `json:"bom-ref"`
+	Components []CDXComponent `json:"components,omitempty"`
+	Workflows  []CDXWorkflow  `json:"workflows,omitempty"`
+}
+
+// CDXWorkflow is one execution of a build workflow.
+type CDXWorkflow struct {
+	BOMRef     string        `json:"bom-ref"`
+	UID        string        `json:"uid"`
+	Name       string        `json:"name,omitempty"`
+	TaskTypes  []string      `json:"taskTypes"`
+	TimeStart  *time.Time    `json:"timeStart,omitempty"`
+	TimeEnd    *time.Time    `json:"timeEnd,omitempty"`
+	Inputs     []CDXIO       `json:"inputs,omitempty"`
+	Outputs    []CDXIO       `json:"outputs,omitempty"`
+	Properties []CDXProperty `json:"properties,omitempty"`
+}
+
+// CDXIO is a workflow input or output resource.
+type CDXIO struct {
+	Resource CDXResourceRef `json:"resource"`
+}
+
+// CDXResourceRef references a resource by bom-ref or external reference.
+type CDXResourceRef struct {
+	Ref               string          `json:"ref,omitempty"`
+	ExternalReference *CDXExternalRef `json:"externalReference,omitempty"`
+}
+
+// cdxHashAlgs maps OCI digest algorithms to CycloneDX hash algorithm names.
+var cdxHashAlgs = map[string]string{
+	"sha1":   "SHA-1",
+	"sha256": "SHA-256",
+	"sha384": "SHA-384",
+	"sha512": "SHA-512",
+}
+
+// cdxComponentTypes maps PBOM artifact types to CycloneDX component types.
+var cdxComponentTypes = map[string]string{
+	"container-image": "container",
+	"binary":          "application",
+	"package":         "library",
+	"archive":         "file",
+	"other":           "file",
+}
+
+// ToCycloneDX maps a PBOM onto a CycloneDX BOM. Artifacts become
+// components; the build becomes a formulation workflow whose inputs are
+// the source commit and whose outputs are the artifacts, with tool
+// versions listed as formulation components.
+func ToCycloneDX(p *schema.PBOM) (*CycloneDX, error) {
+	bom := &CycloneDX{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: CycloneDXSpecVersion,
+		Version:     1,
+		Metadata: CDXMetadata{
+			Timestamp: p.Timestamp,
+			Tools:     &CDXTools{Components: []CDXComponent{{Type: "application", Name: "pbom"}}},
+		},
+	}
+	if p.ID != "" {
+		bom.SerialNumber = "urn:uuid:" + p.ID
+	}
+
+	repoURL := githubURL + "/" + p.Source.Repository
+
+	wf := CDXWorkflow{
+		BOMRef:    "workflow-run",
+		UID:       p.Build.WorkflowRunID,
+		Name:      p.Build.WorkflowName,
+		TaskTypes: []string{"build"},
+		TimeStart: p.Build.StartedAt,
+		TimeEnd:   p.Build.CompletedAt,
+		Inputs: []CDXIO{{Resource: CDXResourceRef{
+			ExternalReference: &CDXExternalRef{Type: "vcs", URL: repoURL + "/commit/" + p.Source.CommitSHA},
+		}}},
+	}
+	props := &wf.Properties
+	addProp(props, propRepository, p.Source.Repository)
+	addProp(props, propCommitSHA, p.Source.CommitSHA)
+	addProp(props, propBranch, p.Source.Branch)
+	addProp(props, propRef, p.Source.Ref)
+	addProp(props, propWorkflowFile, p.Build.WorkflowFile)
+	addProp(props, propTrigger, p.Build.Trigger)
+	addProp(props, propActor, p.Build.Actor)
+	addProp(props, propStatus, p.Build.Status)
+	if r := p.Build.Runner; r != nil {
+		addProp(props, propRunnerOS, r.OS)
+		addProp(props, propRunnerArch, r.Arch)
+	}
+
+	for i, a := range p.Artifacts {
+		alg, hex, ok := strings.Cut(a.Digest, ":")
+		cdxAlg := cdxHashAlgs[alg]
+		if !ok || cdxAlg == "" {
+			return nil, fmt.Errorf("artifact %s: unsupported digest %q", a.Name, a.Digest)
+		}
+
+		c := CDXComponent{
+			BOMRef: fmt.Sprintf("artifact-%d", i),
+			Type:   cdxComponentTypes[a.Type],
+			Name:   a.Name,
+			Hashes: []CDXHash{{Alg: cdxAlg, Content: hex}},
+		}
+		if c.Type == "" {
+			c.Type = "file"
+		}
+		if a.URI != "" {
+			c.ExternalReferences = []CDXExternalRef{{Type: "distribution", URL: a.URI}}
+		}
+		addProp(&c.Properties, propArtifactType, a.Type)
+		for _, tag := range a.Tags {
+			addProp(&c.Properties, propTag, tag)
+		}
+
+		bom.Components = append(bom.Components, c)
+		wf.Outputs = append(wf.Outputs, CDXIO{Resource: CDXResourceRef{Ref: c.BOMRef}})
+	}
+
+	formula := CDXFormula{BOMRef: "formula", Workflows: []CDXWorkflow{wf}}
+	for _, name := range sortedKeys(p.Build.ToolVersions) {
+		formula.Components = append(formula.Components, CDXComponent{
+			BOMRef:  "tool-" + name,
+			Type:    "application",
+			Name:    name,
+			Version: p.Build.ToolVersions[name],
+		})
+	}
+	bom.Formulation = []CDXFormula{formula}
+
+	return bom, nil
+}
+
+func addProp(props *[]CDXProperty, name, value string) {
+	if value != "" {
+		*props = append(*props, CDXProperty{Name: name, Value: value})
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
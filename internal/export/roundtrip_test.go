@@ -0,0 +1,188 @@
+package export
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+)
+
+// lineage is the subset of a PBOM every export format must preserve.
+type lineage struct {
+	Repository   string
+	CommitSHA    string
+	WorkflowRun  string
+	WorkflowName string
+	ToolVersions map[string]string
+	Digests      map[string]string // artifact name -> digest
+}
+
+func lineageOf(p *schema.PBOM) lineage {
+	l := lineage{
+		Repository:   p.Source.Repository,
+		CommitSHA:    p.Source.CommitSHA,
+		WorkflowRun:  p.Build.WorkflowRunID,
+		WorkflowName: p.Build.WorkflowName,
+		ToolVersions: p.Build.ToolVersions,
+		Digests:      map[string]string{},
+	}
+	for _, a := range p.Artifacts {
+		l.Digests[a.Name] = a.Digest
+	}
+	return l
+}
+
+// cycloneDXLineage reads the lineage back out of serialized CycloneDX.
+func cycloneDXLineage(t *testing.T, data []byte) lineage {
+	t.Helper()
+	var bom CycloneDX
+	if err := json.Unmarshal(data, &bom); err != nil {
+		t.Fatal(err)
+	}
+	if len(bom.Formulation) != 1 || len(bom.Formulation[0].Workflows) != 1 {
+		t.Fatalf("expected one formula with one workflow, got %+v", bom.Formulation)
+	}
+
+	f := bom.Formulation[0]
+	wf := f.Workflows[0]
+	l := lineage{
+		WorkflowRun:  wf.UID,
+		WorkflowName: wf.Name,
+		ToolVersions: map[string]string{},
+		Digests:      map[string]string{},
+	}
+	for _, p := range wf.Properties {
+		switch p.Name {
+		case propRepository:
+			l.Repository = p.Value
+		case propCommitSHA:
+			l.CommitSHA = p.Value
+		}
+	}
+	for _, c := range f.Components {
+		l.ToolVersions[c.Name] = c.Version
+	}
+
+	algs := map[string]string{}
+	for oci, cdx := range cdxHashAlgs {
+		algs[cdx] = oci
+	}
+	for _, c := range bom.Components {
+		l.Digests[c.Name] = algs[c.Hashes[0].Alg] + ":" + c.Hashes[0].Content
+	}
+	return l
+}
+
+// spdxLineage reads the lineage back out of serialized SPDX.
+func spdxLineage(t *testing.T, data []byte) lineage {
+	t.Helper()
+	var doc struct {
+		Context string            `json:"@context"`
+		Graph   []json.RawMessage `json:"@graph"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Context != SPDXContext {
+		t.Errorf("@context = %q", doc.Context)
+	}
+
+	l := lineage{ToolVersions: map[string]string{}, Digests: map[string]string{}}
+	for _, raw := range doc.Graph {
+		var elem struct {
+			Type string `json:"type"`
+		}
+		json.Unmarshal(raw, &elem)
+		switch elem.Type {
+		case "build_Build":
+			var b SPDXBuild
+			json.Unmarshal(raw, &b)
+			l.WorkflowRun = b.BuildID
+			l.WorkflowName = b.Name
+			l.CommitSHA = b.ConfigSourceDigest[0].HashValue
+			for _, p := range b.Parameter {
+				if p.Key == paramRepository {
+					l.Repository = p.Value
+				}
+			}
+			for _, e := range b.Environment {
+				l.ToolVersions[e.Key] = e.Value
+			}
+		case "software_Package":
+			var pkg SPDXPackage
+			json.Unmarshal(raw, &pkg)
+			l.Digests[pkg.Name] = pkg.VerifiedUsing[0].Algorithm + ":" + pkg.VerifiedUsing[0].HashValue
+		}
+	}
+	return l
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	multi := loadExample(t)
+	multi.Artifacts = append(multi.Artifacts, schema.Artifact{
+		Name:   "payments-cli",
+		Type:   "binary",
+		Digest: "sha512:" + strings.Repeat("ab", 64),
+	})
+
+	examples := map[string]*schema.PBOM{
+		"example":        loadExample(t),
+		"multi-artifact": multi,
+	}
+
+	formats := map[string]struct {
+		export func(*schema.PBOM) (any, error)
+		read   func(*testing.T, []byte) lineage
+	}{
+		"cyclonedx": {func(p *schema.PBOM) (any, error) { return ToCycloneDX(p) }, cycloneDXLineage},
+		"spdx":      {func(p *schema.PBOM) (any, error) { return ToSPDX(p) }, spdxLineage},
+	}
+
+	for name, p := range examples {
+		for format, f := range formats {
+			t.Run(name+"/"+format, func(t *testing.T) {
+				doc, err := f.export(p)
+				if err != nil {
+					t.Fatalf("export: %v", err)
+				}
+				data, err := json.Marshal(doc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, want := f.read(t, data), lineageOf(p); !reflect.DeepEqual(got, want) {
+					t.Errorf("lineage lost in %s export:\n got  %+v\n want %+v", format, got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestCycloneDXWorkflowLinksArtifacts(t *testing.T) {
+	bom, err := ToCycloneDX(loadExample(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bom.BOMFormat != "CycloneDX" || bom.SpecVersion != CycloneDXSpecVersion {
+		t.Errorf("bomFormat=%q specVersion=%q", bom.BOMFormat, bom.SpecVersion)
+	}
+	if bom.SerialNumber != "urn:uuid:f47ac10b-58cc-4372-a567-0e02b2c3d479" {
+		t.Errorf("serialNumber = %q", bom.SerialNumber)
+	}
+	wf := bom.Formulation[0].Workflows[0]
+	if len(wf.Outputs) != 1 || wf.Outputs[0].Resource.Ref != bom.Components[0].BOMRef {
+		t.Errorf("workflow outputs %+v do not reference artifact %q", wf.Outputs, bom.Components[0].BOMRef)
+	}
+	if bom.Components[0].Type != "container" {
+		t.Errorf("component type = %q, want container", bom.Components[0].Type)
+	}
+}
+
+func TestCycloneDXRejectsUnknownDigest(t *testing.T) {
+	p := loadExample(t)
+	p.Artifacts[0].Digest = "md5:abc"
+	if _, err := ToCycloneDX(p); err == nil {
+		t.Error("expected error for unsupported digest algorithm")
+	}
+}
@@ -0,0 +1,214 @@
+// Package export translates PBOM documents into other supply chain formats
+// so tools that do not understand PBOM can consume its data.
+package export
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+)
+
+const (
+	// StatementType is the in-toto Statement v1 type URI.
+	StatementType = "https://in-toto.io/Statement/v1"
+	// SLSAProvenanceType is the SLSA v1.0 provenance predicate type.
+	SLSAProvenanceType = "https://slsa.dev/provenance/v1"
+	// GitHubWorkflowBuildType is the SLSA build type for GitHub Actions
+	// workflows.
+	GitHubWorkflowBuildType = "https://slsa-framework.github.io/github-actions-buildtypes/workflow/v1"
+
+	githubURL = "https://github.com"
+)
+
+// Statement is an in-toto Statement v1.
+type Statement struct {
+	Type          string    `json:"_type"`
+	Subject       []Subject `json:"subject"`
+	PredicateType string    `json:"predicateType"`
+	Predicate     any       `json:"predicate"`
+}
+
+// Subject is an artifact an in-toto Statement is about.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// SLSAProvenance is the SLSA v1.0 provenance predicate.
+type SLSAProvenance struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+// BuildDefinition describes the inputs of a build.
+type BuildDefinition struct {
+	BuildType            string               `json:"buildType"`
+	ExternalParameters   map[string]any       `json:"externalParameters"`
+	InternalParameters   map[string]any       `json:"internalParameters,omitempty"`
+	ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies,omitempty"`
+}
+
+// RunDetails describes the build platform and this particular run.
+type RunDetails struct {
+	Builder  Builder        `json:"builder"`
+	Metadata *BuildMetadata `json:"metadata,omitempty"`
+}
+
+// Builder identifies the build platform.
+type Builder struct {
+	ID string `json:"id"`
+}
+
+// BuildMetadata holds run-specific information.
+type BuildMetadata struct {
+	InvocationID string     `json:"invocationId,omitempty"`
+	StartedOn    *time.Time `json:"startedOn,omitempty"`
+	FinishedOn   *time.Time `json:"finishedOn,omitempty"`
+}
+
+// ResourceDescriptor identifies an input or output of a build.
+type ResourceDescriptor struct {
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest,omitempty"`
+	Name   string            `json:"name,omitempty"`
+}
+
+// ToSLSAProvenance maps a PBOM onto an in-toto Statement carrying a SLSA
+// v1.0 provenance predicate, with one subject per artifact.
+func ToSLSAProvenance(p *schema.PBOM) (*Statement, error) {
+	if len(p.Artifacts) == 0 {
+		return nil, errors.New("PBOM has no artifacts; a provenance statement needs at least one subject")
+	}
+
+	subjects := make([]Subject, 0, len(p.Artifacts))
+	for _, a := range p.Artifacts {
+		digest, err := digestSet(a.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("artifact %s: %w", a.Name, err)
+		}
+		subjects = append(subjects, Subject{Name: subjectName(a), Digest: digest})
+	}
+
+	repoURL := githubURL + "/" + p.Source.Repository
+
+	workflow := map[string]any{
+		"repository": repoURL,
+		"path":       workflowPath(p.Build.WorkflowFile),
+	}
+	if p.Source.Ref != "" {
+		workflow["ref"] = p.Source.Ref
+	}
+
+	github := map[string]any{}
+	if p.Build.Trigger != "" {
+		github["event_name"] = p.Build.Trigger
+	}
+	if p.Build.Actor != "" {
+		github["actor"] = p.Build.Actor
+	}
+	if r := p.Build.Runner; r != nil {
+		github["runner_environment"] = runnerEnvironment(r)
+		if r.OS != "" {
+			github["runner_os"] = r.OS
+		}
+		if r.Arch != "" {
+			github["runner_arch"] = r.Arch
+		}
+	}
+	internal := map[string]any{}
+	if len(github) > 0 {
+		internal["github"] = github
+	}
+	if len(p.Build.ToolVersions) > 0 {
+		internal["tool_versions"] = p.Build.ToolVersions
+	}
+
+	source := ResourceDescriptor{
+		URI:    "git+" + repoURL,
+		Digest: map[string]string{"gitCommit": p.Source.CommitSHA},
+	}
+	if p.Source.Ref != "" {
+		source.URI += "@" + p.Source.Ref
+	}
+
+	pred := SLSAProvenance{
+		BuildDefinition: BuildDefinition{
+			BuildType:            GitHubWorkflowBuildType,
+			ExternalParameters:   map[string]any{"workflow": workflow},
+			ResolvedDependencies: []ResourceDescriptor{source},
+		},
+		RunDetails: RunDetails{
+			Builder: Builder{ID: builderID(p)},
+			Metadata: &BuildMetadata{
+				StartedOn:  p.Build.StartedAt,
+				FinishedOn: p.Build.CompletedAt,
+			},
+		},
+	}
+	if len(internal) > 0 {
+		pred.BuildDefinition.InternalParameters = internal
+	}
+	if p.Build.WorkflowRunID != "" {
+		pred.RunDetails.Metadata.InvocationID = repoURL + "/actions/runs/" + p.Build.WorkflowRunID
+	}
+
+	return &Statement{
+		Type:          StatementType,
+		Subject:       subjects,
+		PredicateType: SLSAProvenanceType,
+		Predicate:     pred,
+	}, nil
+}
+
+// digestSet converts an "alg:hex" digest into an in-toto DigestSet.
+func digestSet(digest string) (map[string]string, error) {
+	alg, hex, ok := strings.Cut(digest, ":")
+	if !ok || alg == "" || hex == "" {
+		return nil, fmt.Errorf("invalid digest %q (expected <algorithm>:<hex>)", digest)
+	}
+	return map[string]string{alg: hex}, nil
+}
+
+// subjectName prefers the artifact URI without its digest (the image name
+// for container images) and falls back to the artifact name.
+func subjectName(a schema.Artifact) string {
+	if a.URI != "" {
+		name, _, _ := strings.Cut(a.URI, "@")
+		return name
+	}
+	return a.Name
+}
+
+// workflowPath strips the "owner/repo/" prefix and "@ref" suffix from a
+// GITHUB_WORKFLOW_REF-style value, leaving the path in the repository.
+func workflowPath(file string) string {
+	file, _, _ = strings.Cut(file, "@")
+	if i := strings.Index(file, ".github/"); i > 0 {
+		return file[i:]
+	}
+	return file
+}
+
+func runnerEnvironment(r *schema.Runner) string {
+	if r.SelfHosted {
+		return "self-hosted"
+	}
+	return "github-hosted"
+}
+
+// builderID uses the builder recorded in an artifact's provenance if there
+// is one, otherwise the GitHub Actions runner the build ran on.
+func builderID(p *schema.PBOM) string {
+	for _, a := range p.Artifacts {
+		if a.Provenance != nil && a.Provenance.BuilderID != "" {
+			return a.Provenance.BuilderID
+		}
+	}
+	if p.Build.Runner != nil {
+		return githubURL + "/actions/runner/" + runnerEnvironment(p.Build.Runner)
+	}
+	return githubURL + "/actions/runner"
+}
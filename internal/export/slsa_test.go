@@ -0,0 +1,132 @@
+package export
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+)
+
+func loadExample(t *testing.T) *schema.PBOM {
+	t.Helper()
+	data, err := os.ReadFile("../../schema/example.pbom.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p schema.PBOM
+	if err := json.Unmarshal(data, &p); err != nil {
+		t.Fatal(err)
+	}
+	return &p
+}
+
+func TestToSLSAProvenance(t *testing.T) {
+	p := loadExample(t)
+	p.Artifacts = append(p.Artifacts, schema.Artifact{
+		Name:   "payments-cli",
+		Type:   "binary",
+		Digest: "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae",
+	})
+
+	st, err := ToSLSAProvenance(p)
+	if err != nil {
+		t.Fatalf("ToSLSAProvenance: %v", err)
+	}
+
+	// Check the wire format rather than the Go structs.
+	data, err := json.Marshal(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Type          string    `json:"_type"`
+		PredicateType string    `json:"predicateType"`
+		Subject       []Subject `json:"subject"`
+		Predicate     struct {
+			BuildDefinition struct {
+				BuildType          string `json:"buildType"`
+				ExternalParameters struct {
+					Workflow map[string]string `json:"workflow"`
+				} `json:"externalParameters"`
+				InternalParameters struct {
+					ToolVersions map[string]string `json:"tool_versions"`
+				} `json:"internalParameters"`
+				ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies"`
+			} `json:"buildDefinition"`
+			RunDetails struct {
+				Builder  Builder       `json:"builder"`
+				Metadata BuildMetadata `json:"metadata"`
+			} `json:"runDetails"`
+		} `json:"predicate"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Type != StatementType || doc.PredicateType != SLSAProvenanceType {
+		t.Errorf("_type=%q predicateType=%q", doc.Type, doc.PredicateType)
+	}
+	if len(doc.Subject) != 2 {
+		t.Fatalf("got %d subjects, want 2", len(doc.Subject))
+	}
+	if s := doc.Subject[0]; s.Name != "ghcr.io/acme-corp/payments-service" ||
+		s.Digest["sha256"] != "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08" {
+		t.Errorf("subject[0] = %+v", s)
+	}
+	if s := doc.Subject[1]; s.Name != "payments-cli" {
+		t.Errorf("subject[1] = %+v", s)
+	}
+
+	bd := doc.Predicate.BuildDefinition
+	if bd.BuildType != GitHubWorkflowBuildType {
+		t.Errorf("buildType = %q", bd.BuildType)
+	}
+	if wf := bd.ExternalParameters.Workflow; wf["path"] != ".github/workflows/ci.yml" ||
+		wf["repository"] != "https://github.com/acme-corp/payments-service" || wf["ref"] != "refs/heads/main" {
+		t.Errorf("workflow = %v", wf)
+	}
+	if bd.InternalParameters.ToolVersions["go"] != "1.22.4" {
+		t.Errorf("tool_versions = %v", bd.InternalParameters.ToolVersions)
+	}
+	if len(bd.ResolvedDependencies) != 1 || bd.ResolvedDependencies[0].Digest["gitCommit"] != p.Source.CommitSHA {
+		t.Errorf("resolvedDependencies = %+v", bd.ResolvedDependencies)
+	}
+
+	rd := doc.Predicate.RunDetails
+	if rd.Builder.ID != "https://github.com/actions/runner" {
+		t.Errorf("builder.id = %q", rd.Builder.ID)
+	}
+	if rd.Metadata.InvocationID != "https://github.com/acme-corp/payments-service/actions/runs/7890123456" {
+		t.Errorf("invocationId = %q", rd.Metadata.InvocationID)
+	}
+	if rd.Metadata.FinishedOn == nil || !rd.Metadata.FinishedOn.Equal(*p.Build.CompletedAt) {
+		t.Errorf("finishedOn = %v", rd.Metadata.FinishedOn)
+	}
+}
+
+func TestToSLSAProvenanceErrors(t *testing.T) {
+	p := loadExample(t)
+	p.Artifacts[0].Digest = "nodigest"
+	if _, err := ToSLSAProvenance(p); err == nil {
+		t.Error("expected error for malformed digest")
+	}
+
+	p.Artifacts = nil
+	if _, err := ToSLSAProvenance(p); err == nil {
+		t.Error("expected error for PBOM without artifacts")
+	}
+}
+
+func TestWorkflowPath(t *testing.T) {
+	tests := map[string]string{
+		".github/workflows/ci.yml":                          ".github/workflows/ci.yml",
+		"acme/app/.github/workflows/ci.yml@refs/heads/main": ".github/workflows/ci.yml",
+		"": "",
+	}
+	for in, want := range tests {
+		if got := workflowPath(in); got != want {
+			t.Errorf("workflowPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+)
+
+const (
+	// SPDXSpecVersion is the SPDX version produced by ToSPDX.
+	SPDXSpecVersion = "3.0.1"
+	// SPDXContext is the JSON-LD context of SPDX 3.0.1 documents.
+	SPDXContext = "https://spdx.org/rdf/3.0.1/spdx-context.jsonld"
+
+	spdxCreationInfo = "_:creationinfo"
+)
+
+// Build parameter keys used for PBOM fields without an SPDX equivalent.
+const (
+	paramRepository = "repository"
+	paramBranch     = "branch"
+	paramRef        = "ref"
+	paramTrigger    = "trigger"
+	paramActor      = "actor"
+	paramStatus     = "status"
+	paramRunnerOS   = "runner_os"
+	paramRunnerArch = "runner_arch"
+)
+
+// SPDX is an SPDX 3 JSON-LD document.
+type SPDX struct {
+	Context string `json:"@context"`
+	Graph   []any  `json:"@graph"`
+}
+
+// SPDXCreationInfo records who created the SPDX elements and when.
+type SPDXCreationInfo struct {
+	Type        string    `json:"type"`
+	ID          string    `json:"@id"`
+	SpecVersion string    `json:"specVersion"`
+	Created     time.Time `json:"created"`
+	CreatedBy   []string  `json:"createdBy"`
+}
+
+// SPDXAgent is the tool that created the document.
+type SPDXAgent struct {
+	Type         string `json:"type"`
+	SPDXID       string `json:"spdxId"`
+	CreationInfo string `json:"creationInfo"`
+	Name         string `json:"name"`
+}
+
+// SPDXDocument is the root element.
+type SPDXDocument struct {
+	Type               string   `json:"type"`
+	SPDXID             string   `json:"spdxId"`
+	CreationInfo       string   `json:"creationInfo"`
+	ProfileConformance []string `json:"profileConformance"`
+	RootElement        []string `json:"rootElement"`
+}
+
+// SPDXBuild is an element of the SPDX 3 build profile.
+type SPDXBuild struct {
+	Type                   string          `json:"type"`
+	SPDXID                 string          `json:"spdxId"`
+	CreationInfo           string          `json:"creationInfo"`
+	Name                   string          `json:"name,omitempty"`
+	BuildType              string          `json:"build_buildType"`
+	BuildID                string          `json:"build_buildId,omitempty"`
+	BuildStartTime         *time.Time      `json:"build_buildStartTime,omitempty"`
+	BuildEndTime           *time.Time      `json:"build_buildEndTime,omitempty"`
+	ConfigSourceURI        []string        `json:"build_configSourceUri,omitempty"`
+	ConfigSourceDigest     []SPDXHash      `json:"build_configSourceDigest,omitempty"`
+	ConfigSourceEntrypoint []string        `json:"build_configSourceEntrypoint,omitempty"`
+	Parameter              []SPDXDictEntry `json:"build_parameter,omitempty"`
+	Environment            []SPDXDictEntry `json:"build_environment,omitempty"`
+}
+
+// SPDXPackage is a software package produced by the build.
+type SPDXPackage struct {
+	Type             string     `json:"type"`
+	SPDXID           string     `json:"spdxId"`
+	CreationInfo     string     `json:"creationInfo"`
+	Name             string     `json:"name"`
+	PrimaryPurpose   string     `json:"software_primaryPurpose,omitempty"`
+	DownloadLocation string     `json:"software_downloadLocation,omitempty"`
+	VerifiedUsing    []SPDXHash `json:"verifiedUsing,omitempty"`
+	Comment          string     `json:"comment,omitempty"`
+}
+
+// SPDXRelationship links elements, e.g. a build to its outputs.
+type SPDXRelationship struct {
+	Type             string   `json:"type"`
+	SPDXID           string   `json:"spdxId"`
+	CreationInfo     string   `json:"creationInfo"`
+	From             string   `json:"from"`
+	RelationshipType string   `json:"relationshipType"`
+	To               []string `json:"to"`
+}
+
+// SPDXHash is an integrity method.
+type SPDXHash struct {
+	Type      string `json:"type"`
+	Algorithm string `json:"algorithm"`
+	HashValue string `json:"hashValue"`
+}
+
+// SPDXDictEntry is a key/value pair.
+type SPDXDictEntry struct {
+	Type  string `json:"type"`
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// spdxPurposes maps PBOM artifact types to SPDX software purposes.
+var spdxPurposes = map[string]string{
+	"container-image": "container",
+	"binary":          "executable",
+	"package":         "library",
+	"archive":         "archive",
+	"other":           "other",
+}
+
+// ToSPDX maps a PBOM onto an SPDX 3 document using the build profile. The
+// build carries the commit as its config source digest and tool versions
+// as its environment; artifacts are packages linked by hasOutput.
+func ToSPDX(p *schema.PBOM) (*SPDX, error) {
+	ns := "urn:uuid:" + p.ID + "#"
+	repoURL := githubURL + "/" + p.Source.Repository
+
+	build := SPDXBuild{
+		Type:           "build_Build",
+		SPDXID:         ns + "build",
+		CreationInfo:   spdxCreationInfo,
+		Name:           p.Build.WorkflowName,
+		BuildType:      GitHubWorkflowBuildType,
+		BuildID:        p.Build.WorkflowRunID,
+		BuildStartTime: p.Build.StartedAt,
+		BuildEndTime:   p.Build.CompletedAt,
+		ConfigSourceDigest: []SPDXHash{{
+			Type: "Hash", Algorithm: "sha1", HashValue: p.Source.CommitSHA,
+		}},
+	}
+	if path := workflowPath(p.Build.WorkflowFile); path != "" {
+		build.ConfigSourceURI = []string{repoURL + "/blob/" + p.Source.CommitSHA + "/" + path}
+		build.ConfigSourceEntrypoint = []string{path}
+	}
+
+	param := func(key, value string) {
+		if value != "" {
+			build.Parameter = append(build.Parameter, SPDXDictEntry{Type: "DictionaryEntry", Key: key, Value: value})
+		}
+	}
+	param(paramRepository, p.Source.Repository)
+	param(paramBranch, p.Source.Branch)
+	param(paramRef, p.Source.Ref)
+	param(paramTrigger, p.Build.Trigger)
+	param(paramActor, p.Build.Actor)
+	param(paramStatus, p.Build.Status)
+	if r := p.Build.Runner; r != nil {
+		param(paramRunnerOS, r.OS)
+		param(paramRunnerArch, r.Arch)
+	}
+	for _, name := range sortedKeys(p.Build.ToolVersions) {
+		build.Environment = append(build.Environment, SPDXDictEntry{
+			Type: "DictionaryEntry", Key: name, Value: p.Build.ToolVersions[name],
+		})
+	}
+
+	graph := []any{
+		SPDXCreationInfo{
+			Type:        "CreationInfo",
+			ID:          spdxCreationInfo,
+			SpecVersion: SPDXSpecVersion,
+			Created:     p.Timestamp,
+			CreatedBy:   []string{ns + "tool-pbom"},
+		},
+		SPDXAgent{Type: "Tool", SPDXID: ns + "tool-pbom", CreationInfo: spdxCreationInfo, Name: "pbom"},
+		SPDXDocument{
+			Type:               "SpdxDocument",
+			SPDXID:             ns + "document",
+			CreationInfo:       spdxCreationInfo,
+			ProfileConformance: []string{"core", "software", "build"},
+			RootElement:        []string{build.SPDXID},
+		},
+		build,
+	}
+
+	var outputs []string
+	for i, a := range p.Artifacts {
+		alg, hex, ok := strings.Cut(a.Digest, ":")
+		if !ok || alg == "" || hex == "" {
+			return nil, fmt.Errorf("artifact %s: invalid digest %q", a.Name, a.Digest)
+		}
+		pkg := SPDXPackage{
+			Type:             "software_Package",
+			SPDXID:           fmt.Sprintf("%sartifact-%d", ns, i),
+			CreationInfo:     spdxCreationInfo,
+			Name:             a.Name,
+			PrimaryPurpose:   spdxPurposes[a.Type],
+			DownloadLocation: a.URI,
+			VerifiedUsing:    []SPDXHash{{Type: "Hash", Algorithm: alg, HashValue: hex}},
+		}
+		if len(a.Tags) > 0 {
+			pkg.Comment = "tags: " + strings.Join(a.Tags, ", ")
+		}
+		graph = append(graph, pkg)
+		outputs = append(outputs, pkg.SPDXID)
+	}
+
+	if len(outputs) > 0 {
+		graph = append(graph, SPDXRelationship{
+			Type:             "Relationship",
+			SPDXID:           ns + "build-outputs",
+			CreationInfo:     spdxCreationInfo,
+			From:             build.SPDXID,
+			RelationshipType: "hasOutput",
+			To:               outputs,
+		})
+	}
+
+	return &SPDX{Context: SPDXContext, Graph: graph}, nil
+}
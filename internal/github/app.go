@@ -0,0 +1,214 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenRefreshMargin is how long before expiry a cached installation token
+// is replaced. Installation tokens live for an hour.
+const tokenRefreshMargin = 5 * time.Minute
+
+// App authenticates as a GitHub App. It signs short-lived JWTs with the
+// app's private key and exchanges them for installation tokens, which it
+// caches per installation and refreshes before they expire.
+type App struct {
+	id  int64
+	key *rsa.PrivateKey
+	api *Client // authenticates with the app JWT
+
+	now func() time.Time
+
+	mu     sync.Mutex
+	tokens map[int64]installationToken
+}
+
+type installationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewApp creates an App from its ID and PEM-encoded private key, as
+// downloaded from the app's settings page (PKCS #1 or PKCS #8).
+func NewApp(appID int64, privateKeyPEM []byte) (*App, error) {
+	key, err := parseRSAKey(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	a := &App{
+		id:     appID,
+		key:    key,
+		api:    NewClient(""),
+		now:    time.Now,
+		tokens: make(map[int64]installationToken),
+	}
+	a.api.auth = func(context.Context) (string, error) { return a.JWT() }
+	a.api.identity = fmt.Sprintf("app:%d", appID)
+	return a, nil
+}
+
+// NewAppWithBase creates an App registered on the API at baseURL, such as
+// a GitHub Enterprise Server's.
+func NewAppWithBase(appID int64, privateKeyPEM []byte, baseURL string) (*App, error) {
+	a, err := NewApp(appID, privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	a.api.baseURL = strings.TrimSuffix(baseURL, "/")
+	return a, nil
+}
+
+func parseRSAKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("app private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing app private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("app private key is %T, want RSA", parsed)
+	}
+	return key, nil
+}
+
+// SetObserver registers fn to be called after every request made by the
+// app or by its installation clients.
+func (a *App) SetObserver(fn Observer) {
+	a.api.SetObserver(fn)
+}
+
+// SetTransport replaces the HTTP transport of the app and its
+// installation clients.
+func (a *App) SetTransport(rt http.RoundTripper) {
+	a.api.SetTransport(rt)
+}
+
+// SetCache makes the app and its installation clients revalidate GET
+// responses held in cache. Installation clients share cache entries across
+// token refreshes.
+func (a *App) SetCache(cache *Cache) {
+	a.api.SetCache(cache)
+}
+
+// SetMaxDownloadSize sets the artifact size limit of the app's
+// installation clients.
+func (a *App) SetMaxDownloadSize(n int64) {
+	a.api.SetMaxDownloadSize(n)
+}
+
+// JWT returns a signed app JWT (RS256), valid for nine minutes. The
+// issued-at time is backdated a minute to allow for clock drift.
+func (a *App) JWT() (string, error) {
+	now := a.now()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(a.id, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("signing app JWT: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// InstallationToken returns a token for an installation of the app,
+// reusing a cached one until it is about to expire.
+func (a *App) InstallationToken(ctx context.Context, installationID int64) (string, error) {
+	if installationID <= 0 {
+		return "", errors.New("no GitHub App installation selected")
+	}
+
+	// Held across the exchange so concurrent callers share one token.
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if t, ok := a.tokens[installationID]; ok && a.now().Add(tokenRefreshMargin).Before(t.ExpiresAt) {
+		return t.Token, nil
+	}
+
+	path := fmt.Sprintf("/app/installations/%d/access_tokens", installationID)
+	data, err := a.api.post(ctx, "/app/installations/{installation_id}/access_tokens", path)
+	if err != nil {
+		return "", fmt.Errorf("creating installation token: %w", err)
+	}
+	var t installationToken
+	if err := json.Unmarshal(data, &t); err != nil {
+		return "", fmt.Errorf("parsing installation token: %w", err)
+	}
+	if t.Token == "" {
+		return "", errors.New("creating installation token: empty token in response")
+	}
+	a.tokens[installationID] = t
+	return t.Token, nil
+}
+
+// RepoInstallation returns the ID of the app installation covering a
+// repository.
+func (a *App) RepoInstallation(ctx context.Context, owner, repo string) (int64, error) {
+	path := fmt.Sprintf("/repos/%s/%s/installation", owner, repo)
+	return a.installation(ctx, "/repos/{owner}/{repo}/installation", path)
+}
+
+// OrgInstallation returns the ID of the app installation on an
+// organization.
+func (a *App) OrgInstallation(ctx context.Context, org string) (int64, error) {
+	path := fmt.Sprintf("/orgs/%s/installation", url.PathEscape(org))
+	return a.installation(ctx, "/orgs/{org}/installation", path)
+}
+
+func (a *App) installation(ctx context.Context, endpoint, path string) (int64, error) {
+	data, err := a.api.get(ctx, endpoint, path)
+	if err != nil {
+		return 0, fmt.Errorf("finding app installation: %w", err)
+	}
+	var inst struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal(data, &inst); err != nil {
+		return 0, fmt.Errorf("parsing app installation: %w", err)
+	}
+	return inst.ID, nil
+}
+
+// Client returns a client authenticated as the given installation. It
+// shares the app's transport and observer.
+func (a *App) Client(installationID int64) *Client {
+	c := NewClient("")
+	c.auth = func(ctx context.Context) (string, error) {
+		return a.InstallationToken(ctx, installationID)
+	}
+	c.httpClient = a.api.httpClient
+	c.baseURL = a.api.baseURL
+	c.observe = a.api.observe
+	c.cache = a.api.cache
+	c.maxDownload = a.api.maxDownload
+	c.identity = fmt.Sprintf("app:%d/installation:%d", a.id, installationID)
+	return c
+}
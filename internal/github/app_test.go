@@ -0,0 +1,153 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testAppKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestAppJWT(t *testing.T) {
+	key, keyPEM := testAppKey(t)
+	app, err := NewApp(42, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	app.now = func() time.Time { return now }
+
+	jwt, err := app.JWT()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("JWT has %d parts, want 3", len(parts))
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims struct {
+		IAT int64  `json:"iat"`
+		EXP int64  `json:"exp"`
+		ISS string `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.ISS != "42" || claims.IAT != now.Unix()-60 || claims.EXP != now.Unix()+540 {
+		t.Errorf("claims = %+v", claims)
+	}
+}
+
+func TestNewAppRejectsBadKeys(t *testing.T) {
+	if _, err := NewApp(1, []byte("not a key")); err == nil {
+		t.Error("NewApp accepted a non-PEM key")
+	}
+	block := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("garbage")})
+	if _, err := NewApp(1, block); err == nil {
+		t.Error("NewApp accepted a corrupt key")
+	}
+}
+
+func TestAppInstallationTokens(t *testing.T) {
+	_, keyPEM := testAppKey(t)
+	var exchanges atomic.Int32
+	now := time.Unix(1_700_000_000, 0)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/app/installations/7/access_tokens":
+			if !strings.HasPrefix(auth, "Bearer ") || strings.Count(auth, ".") != 2 {
+				http.Error(w, "want app JWT", http.StatusUnauthorized)
+				return
+			}
+			n := exchanges.Add(1)
+			fmt.Fprintf(w, `{"token":"ghs_%d","expires_at":%q}`, n, now.Add(time.Hour).Format(time.RFC3339))
+		case r.URL.Path == "/repos/acme/app/installation", r.URL.Path == "/orgs/acme/installation":
+			w.Write([]byte(`{"id":7}`))
+		case r.URL.Path == "/repos/acme/app/actions/runs/1/jobs":
+			if auth != "Bearer ghs_1" {
+				http.Error(w, "bad credentials "+auth, http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"total_count":0,"jobs":[]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	app, err := NewAppWithBase(42, keyPEM, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for _, find := range []func() (int64, error){
+		func() (int64, error) { return app.RepoInstallation(ctx, "acme", "app") },
+		func() (int64, error) { return app.OrgInstallation(ctx, "acme") },
+	} {
+		if id, err := find(); err != nil || id != 7 {
+			t.Errorf("installation = %d, %v; want 7", id, err)
+		}
+	}
+
+	client := app.Client(7)
+	for range 2 {
+		if _, err := client.GetJobs(ctx, "acme", "app", 1); err != nil {
+			t.Fatalf("GetJobs as installation: %v", err)
+		}
+	}
+	if n := exchanges.Load(); n != 1 {
+		t.Errorf("%d token exchanges for two requests, want 1 (cached)", n)
+	}
+
+	// Within the refresh margin of expiry the token is replaced.
+	now = now.Add(56 * time.Minute)
+	tok, err := app.InstallationToken(ctx, 7)
+	if err != nil || tok != "ghs_2" {
+		t.Errorf("token near expiry = %q, %v; want refreshed ghs_2", tok, err)
+	}
+
+	if _, err := app.InstallationToken(ctx, 0); err == nil {
+		t.Error("InstallationToken(0) succeeded")
+	}
+}
@@ -0,0 +1,168 @@
+package github
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCacheEntries bounds the entries a Cache keeps in memory; the oldest
+// are dropped first.
+const maxCacheEntries = 2000
+
+// maxCacheAge is how long an entry stays on disk without being used. Most
+// URLs name a single run, so they are rarely requested after a day.
+const maxCacheAge = 24 * time.Hour
+
+// maxCachedBody is the largest response body worth caching.
+const maxCachedBody = 1 << 20
+
+// Cache holds GitHub API responses by URL and credentials, with their
+// ETags, so that a client can revalidate them with If-None-Match. GitHub
+// answers an unchanged resource with 304 Not Modified, which does not
+// count against the rate limit. A Cache is safe for concurrent use and
+// may be shared by several clients.
+type Cache struct {
+	dir string // "" keeps entries in memory only
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	order   []string  // keys in insertion order, for eviction
+	pruned  time.Time // when files past maxCacheAge were last removed
+}
+
+type cacheEntry struct {
+	ETag string `json:"etag"`
+	Link string `json:"link,omitempty"` // pagination of list responses
+	Body []byte `json:"body"`
+}
+
+// NewCache returns an in-memory cache.
+func NewCache() *Cache {
+	return &Cache{entries: make(map[string]*cacheEntry), now: time.Now}
+}
+
+// OpenCache returns a cache that also persists entries in dir, one file
+// each, so that they survive restarts. Files unused for a day are removed
+// when the cache is opened and hourly after that.
+func OpenCache(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+	c := NewCache()
+	c.dir = dir
+	c.prune()
+	return c, nil
+}
+
+// cacheKey identifies a response by the credentials it was fetched with
+// and its URL, so that clients with different access never share one.
+func cacheKey(identity, url string) string {
+	sum := sha256.Sum256([]byte(identity + " " + url))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Cache) get(key string) (*cacheEntry, bool) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if c.dir == "" {
+		return e, ok
+	}
+	path := filepath.Join(c.dir, key+".json")
+	if !ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, false
+		}
+		e = new(cacheEntry)
+		if err := json.Unmarshal(data, e); err != nil || e.ETag == "" {
+			return nil, false
+		}
+		c.remember(key, e)
+	}
+	// Mark the file used, so that pruning keeps it.
+	now := c.now()
+	os.Chtimes(path, now, now)
+	return e, true
+}
+
+// put stores an entry. Failing to persist it only costs a later request,
+// so disk errors are ignored.
+func (c *Cache) put(key string, e *cacheEntry) {
+	if len(e.Body) > maxCachedBody {
+		return
+	}
+	c.remember(key, e)
+	if c.dir == "" {
+		return
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(c.dir, ".entry-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(c.dir, key+".json"))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+
+	c.mu.Lock()
+	due := c.now().Sub(c.pruned) >= time.Hour
+	c.mu.Unlock()
+	if due {
+		c.prune()
+	}
+}
+
+// prune removes the files of entries unused for maxCacheAge, and temporary
+// files left by interrupted writes.
+func (c *Cache) prune() {
+	now := c.now()
+	c.mu.Lock()
+	c.pruned = now
+	c.mu.Unlock()
+
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, ".json") && !strings.HasPrefix(name, ".entry-") {
+			continue
+		}
+		if info, err := f.Info(); err == nil && now.Sub(info.ModTime()) > maxCacheAge {
+			os.Remove(filepath.Join(c.dir, name))
+		}
+	}
+}
+
+func (c *Cache) remember(key string, e *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = e
+	for len(c.order) > maxCacheEntries {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+}
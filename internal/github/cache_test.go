@@ -0,0 +1,174 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// etagServer serves a workflow run whose ETag changes with version, and
+// records the credentials and response status of each request.
+type etagServer struct {
+	mu       sync.Mutex
+	version  int
+	statuses []string // "<token> <status>"
+}
+
+func (s *etagServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	etag := fmt.Sprintf(`W/"v%d"`, s.version)
+	status := http.StatusOK
+	if r.Header.Get("If-None-Match") == etag {
+		status = http.StatusNotModified
+	}
+	s.statuses = append(s.statuses, fmt.Sprintf("%s %d", r.Header.Get("Authorization"), status))
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(status)
+	if status == http.StatusOK {
+		fmt.Fprintf(w, `{"id":1,"run_attempt":%d}`, s.version)
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	srv := &etagServer{version: 1}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	ctx := context.Background()
+
+	cache := NewCache()
+	a := NewClientWithBase("token-a", ts.URL)
+	a.SetCache(cache)
+	b := NewClientWithBase("token-b", ts.URL)
+	b.SetCache(cache)
+
+	fetch := func(c *Client) int {
+		t.Helper()
+		run, err := c.GetWorkflowRun(ctx, "acme", "app", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return run.RunAttempt
+	}
+
+	fetch(a)
+	if got := fetch(a); got != 1 {
+		t.Errorf("revalidated run attempt = %d, want 1", got)
+	}
+	fetch(b) // other credentials do not share a's entry
+	srv.mu.Lock()
+	srv.version = 2
+	srv.mu.Unlock()
+	if got := fetch(a); got != 2 {
+		t.Errorf("changed run attempt = %d, want 2", got)
+	}
+
+	want := []string{
+		"Bearer token-a 200",
+		"Bearer token-a 304",
+		"Bearer token-b 200",
+		"Bearer token-a 200",
+	}
+	if fmt.Sprint(srv.statuses) != fmt.Sprint(want) {
+		t.Errorf("requests = %q, want %q", srv.statuses, want)
+	}
+}
+
+func TestCachePersistsAcrossRestarts(t *testing.T) {
+	srv := &etagServer{version: 1}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	for range 2 {
+		cache, err := OpenCache(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := NewClientWithBase("token", ts.URL)
+		c.SetCache(cache)
+		run, err := c.GetWorkflowRun(ctx, "acme", "app", 1)
+		if err != nil || run.RunAttempt != 1 {
+			t.Fatalf("GetWorkflowRun = %+v, %v", run, err)
+		}
+	}
+	want := []string{"Bearer token 200", "Bearer token 304"}
+	if fmt.Sprint(srv.statuses) != fmt.Sprint(want) {
+		t.Errorf("requests = %q, want %q", srv.statuses, want)
+	}
+}
+
+func TestCachedPagesKeepLinks(t *testing.T) {
+	var srv *httptest.Server
+	var notModified int
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		etag := `"page` + page + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		if page == "" {
+			w.Header().Set("Link", `<`+srv.URL+`/repos/acme/app/actions/runs/1/artifacts?per_page=100&page=2>; rel="next"`)
+		}
+		fmt.Fprintf(w, `{"total_count":2,"artifacts":[{"name":"page%s"}]}`, page)
+	}))
+	defer srv.Close()
+
+	c := NewClientWithBase("token", srv.URL)
+	c.SetCache(NewCache())
+	for range 2 {
+		artifacts, err := c.GetArtifacts(context.Background(), "acme", "app", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(artifacts) != 2 {
+			t.Fatalf("artifacts = %+v, want both pages", artifacts)
+		}
+	}
+	if notModified != 2 {
+		t.Errorf("%d pages revalidated, want 2", notModified)
+	}
+}
+
+func TestCachePrunesUnusedFiles(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := OpenCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	cache.put("used", &cacheEntry{ETag: `"a"`})
+	cache.put("unused", &cacheEntry{ETag: `"b"`})
+	os.WriteFile(filepath.Join(dir, ".entry-123"), nil, 0o600)
+	old := now.Add(-maxCacheAge - time.Minute)
+	for _, name := range []string{"used.json", "unused.json", ".entry-123"} {
+		os.Chtimes(filepath.Join(dir, name), old, old)
+	}
+
+	if _, ok := cache.get("used"); !ok {
+		t.Fatal("entry not cached")
+	}
+	// Writes prune at most hourly.
+	now = now.Add(2 * time.Hour)
+	cache.put("new", &cacheEntry{ETag: `"c"`})
+
+	files, _ := os.ReadDir(dir)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	if want := []string{"new.json", "used.json"}; fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Errorf("files = %v, want %v", names, want)
+	}
+}
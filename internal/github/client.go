@@ -1,53 +1,211 @@
 package github
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+const (
+	// defaultMaxWait is the longest a request waits for a rate limit
+	// before failing with ErrRateLimited.
+	defaultMaxWait = 2 * time.Minute
+	// maxRateLimitRetries is how often a rate-limited request is retried.
+	maxRateLimitRetries = 3
+	// secondaryBackoff is the first wait after a secondary rate limit that
+	// gave no Retry-After.
+	secondaryBackoff = time.Minute
+	// maxErrorBody caps how much of an error response is kept.
+	maxErrorBody = 64 << 10
+)
+
 // Client is an authenticated GitHub REST API client.
 type Client struct {
-	token      string
+	auth       func(ctx context.Context) (string, error) // nil sends no credentials
 	httpClient *http.Client
 	baseURL    string
+	observe    Observer
+	cache      *Cache
+	identity   string // credentials the cache keys responses by
+
+	maxDownload int64 // bytes; see DownloadArtifact
+
+	// Rate limit handling; see send.
+	maxWait time.Duration
+	now     func() time.Time
+	sleep   func(ctx context.Context, d time.Duration) error
+
+	mu   sync.Mutex
+	rate *RateLimit // from the last response that reported one
+}
+
+// RateLimit is the primary rate limit state reported by the API.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// RateLimit returns the rate limit reported by the most recent response,
+// or false if no response has reported one yet.
+func (c *Client) RateLimit() (RateLimit, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rate == nil {
+		return RateLimit{}, false
+	}
+	return *c.rate, true
+}
+
+// recordRateLimit keeps the X-RateLimit-* headers of a response.
+func (c *Client) recordRateLimit(h http.Header) {
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	limit, _ := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	reset, _ := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	c.mu.Lock()
+	c.rate = &RateLimit{Limit: limit, Remaining: remaining, Reset: time.Unix(reset, 0)}
+	c.mu.Unlock()
+}
+
+// Observer is notified after every GitHub API request. endpoint is the
+// route template, e.g. "/repos/{owner}/{repo}/actions/runs/{run_id}/jobs",
+// so that it can be used as a metric label; status is 0 when no response
+// was received.
+type Observer func(endpoint string, status int, elapsed time.Duration)
+
+// SetObserver registers fn to be called after every request.
+func (c *Client) SetObserver(fn Observer) {
+	c.observe = fn
+}
+
+// SetTransport replaces the HTTP transport, e.g. with a Recorder or
+// Replayer.
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.httpClient.Transport = rt
+}
+
+// do sends req and reports it to the observer under endpoint.
+func (c *Client) do(req *http.Request, endpoint string) (*http.Response, error) {
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if resp != nil {
+		c.recordRateLimit(resp.Header)
+	}
+	if c.observe != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		c.observe(endpoint, status, time.Since(start))
+	}
+	return resp, err
 }
 
 // NewClient creates a GitHub API client with the given token.
 func NewClient(token string) *Client {
-	return &Client{
-		token: token,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		baseURL: "https://api.github.com",
+	c := &Client{
+		baseURL:     DefaultBaseURL,
+		maxDownload: DefaultMaxDownloadSize,
+		maxWait:     defaultMaxWait,
+		now:         time.Now,
+		sleep:       sleep,
 	}
+	c.httpClient = &http.Client{
+		Timeout:       30 * time.Second,
+		CheckRedirect: c.checkRedirect,
+	}
+	if token != "" {
+		c.auth = func(context.Context) (string, error) { return token, nil }
+		sum := sha256.Sum256([]byte(token))
+		c.identity = "token:" + hex.EncodeToString(sum[:8])
+	}
+	return c
+}
+
+// SetCache makes the client revalidate GET responses held in cache
+// instead of fetching them again. Downloads are not cached.
+func (c *Client) SetCache(cache *Cache) {
+	c.cache = cache
 }
 
-// NewClientWithBase creates a client pointing at a custom base URL (for testing).
+// NewClientWithBase creates a client for the API at baseURL, such as a
+// GitHub Enterprise Server's (see ParseBaseURL) or a test server.
 func NewClientWithBase(token, baseURL string) *Client {
 	c := NewClient(token)
-	c.baseURL = baseURL
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
 	return c
 }
 
-// get performs an authenticated GET and returns the response body bytes.
-func (c *Client) get(ctx context.Context, path string) ([]byte, error) {
-	url := c.baseURL + path
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+// BaseURL returns the API URL the client sends requests to.
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// onAPI reports whether url is under the client's API base URL, the only
+// place its credentials are sent.
+func (c *Client) onAPI(url string) bool {
+	return strings.HasPrefix(url, c.baseURL+"/")
+}
+
+// checkRedirect drops the credentials from redirects that leave the API,
+// such as artifact downloads redirecting to pre-signed storage URLs. Go
+// keeps the Authorization header when the host is unchanged, which is the
+// case for GitHub Enterprise Server storage, and pre-signed URLs are
+// rejected when it is sent.
+func (c *Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
 	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-	if c.token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.token)
+	if !c.onAPI(req.URL.String()) {
+		req.Header.Del("Authorization")
 	}
+	return nil
+}
 
-	resp, err := c.httpClient.Do(req)
+// authorize adds the client's credentials to req.
+func (c *Client) authorize(req *http.Request) error {
+	if c.auth == nil {
+		return nil
+	}
+	token, err := c.auth(req.Context())
 	if err != nil {
-		return nil, fmt.Errorf("executing request: %w", err)
+		return fmt.Errorf("authenticating: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// get performs an authenticated GET and returns the response body bytes.
+// endpoint is the route template of path, reported to the Observer.
+func (c *Client) get(ctx context.Context, endpoint, path string) ([]byte, error) {
+	return c.call(ctx, http.MethodGet, endpoint, path)
+}
+
+// post performs an authenticated POST without a body.
+func (c *Client) post(ctx context.Context, endpoint, path string) ([]byte, error) {
+	return c.call(ctx, http.MethodPost, endpoint, path)
+}
+
+func (c *Client) call(ctx context.Context, method, endpoint, path string) ([]byte, error) {
+	send := c.send
+	if method == http.MethodGet {
+		send = c.sendCached
+	}
+	resp, err := send(ctx, method, endpoint, c.baseURL+path)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -55,34 +213,173 @@ func (c *Client) get(ctx context.Context, path string) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("reading response: %w", err)
 	}
+	return body, nil
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("GitHub API %s returned %d: %s", path, resp.StatusCode, string(body))
+// getPages GETs path and every following page named by the Link header of
+// the previous response, passing each page's body to fn.
+func (c *Client) getPages(ctx context.Context, endpoint, path string, fn func([]byte) error) error {
+	for url := c.baseURL + path; url != ""; {
+		resp, err := c.sendCached(ctx, http.MethodGet, endpoint, url)
+		if err != nil {
+			return err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("reading response: %w", err)
+		}
+		if err := fn(body); err != nil {
+			return err
+		}
+		url = nextPage(resp.Header.Get("Link"))
 	}
+	return nil
+}
 
-	return body, nil
+// nextPage returns the rel="next" URL of a Link header, or "" on the last
+// page.
+func nextPage(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(part, ";")
+		if !ok {
+			continue
+		}
+		target = strings.TrimSpace(target)
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return target[1 : len(target)-1]
+			}
+		}
+	}
+	return ""
 }
 
-// download performs a GET that follows redirects and returns the raw body.
-// Used for artifact ZIP downloads which redirect to Azure blob storage.
-func (c *Client) download(ctx context.Context, url string) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating download request: %w", err)
+// send performs an authenticated request and returns the response if it
+// succeeded; the caller closes its body. Requests refused by a rate limit
+// are retried after the wait GitHub asks for, unless that is longer than
+// maxWait, in which case the error matches ErrRateLimited.
+func (c *Client) send(ctx context.Context, method, endpoint, url string) (*http.Response, error) {
+	return c.sendWith(ctx, method, endpoint, url, nil)
+}
+
+// sendCached is send for a GET whose response is kept in the client's
+// cache, if it has one. A cached response is revalidated with its ETag and
+// served from the cache when GitHub answers 304 Not Modified.
+func (c *Client) sendCached(ctx context.Context, method, endpoint, url string) (*http.Response, error) {
+	if c.cache == nil {
+		return c.send(ctx, method, endpoint, url)
 	}
-	if c.token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.token)
+	key := cacheKey(c.identity, url)
+	cached, ok := c.cache.get(key)
+	var header http.Header
+	if ok {
+		header = http.Header{"If-None-Match": {cached.ETag}}
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.sendWith(ctx, method, endpoint, url, header)
 	if err != nil {
-		return nil, fmt.Errorf("executing download: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		resp.StatusCode, resp.Status = http.StatusOK, "200 OK (cached)"
+		resp.Header.Set("Link", cached.Link)
+		resp.Body = io.NopCloser(bytes.NewReader(cached.Body))
+		return resp, nil
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	c.cache.put(key, &cacheEntry{ETag: etag, Link: resp.Header.Get("Link"), Body: body})
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// sendWith is send with extra request headers. A 304 response counts as
+// success when the request was conditional.
+func (c *Client) sendWith(ctx context.Context, method, endpoint, url string, header http.Header) (*http.Response, error) {
+	if err := c.waitForReset(ctx); err != nil {
+		return nil, err
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if err := c.authorize(req); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.do(req, endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("executing request: %w", err)
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		}
+		if resp.StatusCode == http.StatusNotModified && req.Header.Get("If-None-Match") != "" {
+			return resp, nil
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("download returned %d", resp.StatusCode)
+		apiErr := apiError(resp, body, c.now())
+		if !apiErr.rateLimited || attempt == maxRateLimitRetries {
+			return nil, apiErr
+		}
+		wait := apiErr.RetryAfter
+		if wait == 0 {
+			// A secondary limit without Retry-After: back off
+			// exponentially from a minute, as GitHub recommends.
+			wait = secondaryBackoff << attempt
+		}
+		if wait > c.maxWait {
+			return nil, apiErr
+		}
+		if err := c.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
 	}
+}
 
-	return io.ReadAll(resp.Body)
+// waitForReset holds a request back while the last response reported the
+// primary rate limit exhausted, rather than spend a request on a refusal.
+func (c *Client) waitForReset(ctx context.Context) error {
+	rl, ok := c.RateLimit()
+	if !ok || rl.Remaining > 0 {
+		return nil
+	}
+	wait := rl.Reset.Sub(c.now())
+	if wait <= 0 {
+		return nil
+	}
+	if wait > c.maxWait {
+		return fmt.Errorf("GitHub API rate limit exhausted until %s: %w", rl.Reset.Format(time.RFC3339), ErrRateLimited)
+	}
+	return c.sleep(ctx, wait)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
 }
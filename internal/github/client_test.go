@@ -0,0 +1,200 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testNow is the clock of clients under test.
+var testNow = time.Unix(1_700_000_000, 0)
+
+func TestGetJobsFollowsLinkPages(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/app/actions/runs/1/jobs" {
+			http.NotFound(w, r)
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		if page < 3 {
+			next := fmt.Sprintf("%s/repos/acme/app/actions/runs/1/jobs?per_page=100&page=%d", srv.URL, page+1)
+			w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next", <%s/last>; rel="last"`, next, srv.URL))
+		}
+		fmt.Fprintf(w, `{"total_count":3,"jobs":[{"id":%d}]}`, page)
+	}))
+	defer srv.Close()
+
+	jobs, err := NewClientWithBase("", srv.URL).GetJobs(context.Background(), "acme", "app", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 3 || jobs[0].ID != 1 || jobs[2].ID != 3 {
+		t.Errorf("jobs = %+v, want ids 1-3 from three pages", jobs)
+	}
+}
+
+func TestNextPage(t *testing.T) {
+	tests := []struct {
+		link, want string
+	}{
+		{"", ""},
+		{`<https://api.github.com/x?page=2>; rel="next"`, "https://api.github.com/x?page=2"},
+		{`<https://api.github.com/x?page=1>; rel="prev", <https://api.github.com/x?page=3>; rel="next"`, "https://api.github.com/x?page=3"},
+		{`<https://api.github.com/x?page=1>; rel="first", <https://api.github.com/x?page=1>; rel="prev"`, ""},
+		{`garbage`, ""},
+	}
+	for _, tt := range tests {
+		if got := nextPage(tt.link); got != tt.want {
+			t.Errorf("nextPage(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestRateLimitedRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		refuse   func(w http.ResponseWriter)
+		refusals int32
+		wantWait []time.Duration // sleeps before succeeding
+		wantErr  bool
+	}{
+		{
+			name: "secondary with Retry-After",
+			refuse: func(w http.ResponseWriter) {
+				w.Header().Set("Retry-After", "30")
+				http.Error(w, `{"message":"You have exceeded a secondary rate limit"}`, http.StatusForbidden)
+			},
+			refusals: 1,
+			wantWait: []time.Duration{30 * time.Second},
+		},
+		{
+			name: "secondary without Retry-After backs off",
+			refuse: func(w http.ResponseWriter) {
+				http.Error(w, `{"message":"You have exceeded a secondary rate limit"}`, http.StatusForbidden)
+			},
+			refusals: 2,
+			wantWait: []time.Duration{time.Minute, 2 * time.Minute},
+		},
+		{
+			name: "primary exhausted until reset",
+			refuse: func(w http.ResponseWriter) {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(testNow.Add(90*time.Second).Unix(), 10))
+				http.Error(w, `{"message":"API rate limit exceeded"}`, http.StatusForbidden)
+			},
+			refusals: 1,
+			wantWait: []time.Duration{90 * time.Second},
+		},
+		{
+			name: "wait longer than the maximum fails",
+			refuse: func(w http.ResponseWriter) {
+				w.Header().Set("Retry-After", "3600")
+				http.Error(w, "slow down", http.StatusTooManyRequests)
+			},
+			refusals: 1,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if requests.Add(1) <= tt.refusals {
+					tt.refuse(w)
+					return
+				}
+				w.Write([]byte(`{"id":1}`))
+			}))
+			defer srv.Close()
+
+			c := NewClientWithBase("", srv.URL)
+			c.now = func() time.Time { return testNow }
+			var slept []time.Duration
+			c.sleep = func(ctx context.Context, d time.Duration) error {
+				slept = append(slept, d)
+				return nil
+			}
+
+			_, err := c.GetWorkflowRun(context.Background(), "acme", "app", 1)
+			if tt.wantErr {
+				var apiErr *APIError
+				if !errors.Is(err, ErrRateLimited) || !errors.As(err, &apiErr) || apiErr.RetryAfter != time.Hour {
+					t.Errorf("err = %v, want ErrRateLimited with RetryAfter 1h", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(slept) != fmt.Sprint(tt.wantWait) {
+				t.Errorf("waited %v, want %v", slept, tt.wantWait)
+			}
+		})
+	}
+}
+
+func TestExhaustedRateLimitHoldsRequests(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(testNow.Add(time.Hour).Unix(), 10))
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer srv.Close()
+
+	c := NewClientWithBase("", srv.URL)
+	c.now = func() time.Time { return testNow }
+	ctx := context.Background()
+	if _, err := c.GetWorkflowRun(ctx, "acme", "app", 1); err != nil {
+		t.Fatal(err)
+	}
+	// The last request used up the limit; the next is refused locally.
+	if _, err := c.GetWorkflowRun(ctx, "acme", "app", 1); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("err = %v, want ErrRateLimited", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("%d requests sent, want 1", n)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/app/actions/runs/404":
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+		case "/repos/acme/app/actions/runs/403":
+			http.Error(w, `{"message":"Resource not accessible by integration"}`, http.StatusForbidden)
+		default:
+			http.Error(w, "boom", http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+	c := NewClientWithBase("", srv.URL)
+	ctx := context.Background()
+
+	_, err := c.GetWorkflowRun(ctx, "acme", "app", 404)
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrRateLimited) {
+		t.Errorf("404: err = %v, want ErrNotFound", err)
+	}
+	// A 403 without rate limit headers is a permissions problem.
+	_, err = c.GetWorkflowRun(ctx, "acme", "app", 403)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden || errors.Is(err, ErrRateLimited) {
+		t.Errorf("403: err = %v, want a plain APIError", err)
+	}
+	_, err = c.GetWorkflowRun(ctx, "acme", "app", 500)
+	if errors.Is(err, ErrNotFound) || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("502: err = %v", err)
+	}
+}
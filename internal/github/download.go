@@ -0,0 +1,101 @@
+package github
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// DefaultMaxDownloadSize is the largest artifact a client downloads unless
+// SetMaxDownloadSize changes it. The artifacts the enricher reads hold a
+// few kilobytes of JSON.
+const DefaultMaxDownloadSize = 64 << 20
+
+// ErrTooLarge is matched by errors for artifacts over the download limit.
+var ErrTooLarge = errors.New("artifact exceeds the download size limit")
+
+// ErrDigestMismatch is matched by errors for artifacts whose downloaded
+// bytes do not hash to the digest the API reported.
+var ErrDigestMismatch = errors.New("artifact digest mismatch")
+
+// ArtifactFile is a downloaded artifact archive, kept in a temporary file
+// rather than in memory. Close removes the file.
+type ArtifactFile struct {
+	*os.File
+	Size   int64
+	Digest string // "sha256:<hex>" of the archive as downloaded
+}
+
+// Close closes and removes the temporary file.
+func (f *ArtifactFile) Close() error {
+	err := f.File.Close()
+	if rerr := os.Remove(f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// SetMaxDownloadSize sets the largest artifact, in bytes, DownloadArtifact
+// accepts.
+func (c *Client) SetMaxDownloadSize(n int64) {
+	c.maxDownload = n
+}
+
+// DownloadArtifact streams a workflow artifact ZIP to a temporary file.
+// Artifacts over the client's size limit fail with ErrTooLarge, before
+// the download if the API reported the size. If the API reported a
+// digest, the downloaded bytes must match it or the download fails with
+// ErrDigestMismatch.
+func (c *Client) DownloadArtifact(ctx context.Context, art Artifact) (*ArtifactFile, error) {
+	if art.SizeInBytes > c.maxDownload {
+		return nil, fmt.Errorf("artifact %s is %d bytes, limit %d: %w", art.Name, art.SizeInBytes, c.maxDownload, ErrTooLarge)
+	}
+	url := art.ArchiveDownloadURL
+	if !c.onAPI(url) {
+		// Archive URLs come from API responses and payloads; never send
+		// the token elsewhere. A mismatch usually means a GitHub
+		// Enterprise Server URL is not configured.
+		return nil, fmt.Errorf("download URL %s is not on the GitHub API at %s", url, c.baseURL)
+	}
+
+	// Downloads redirect to blob storage; see checkRedirect.
+	resp, err := c.send(ctx, http.MethodGet, "/repos/{owner}/{repo}/actions/artifacts/{artifact_id}/{archive_format}", url)
+	if err != nil {
+		return nil, fmt.Errorf("downloading artifact %s: %w", art.Name, err)
+	}
+	defer resp.Body.Close()
+
+	tmp, err := os.CreateTemp("", "pbom-artifact-*.zip")
+	if err != nil {
+		return nil, fmt.Errorf("creating download file: %w", err)
+	}
+	f := &ArtifactFile{File: tmp}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(resp.Body, c.maxDownload+1))
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("downloading artifact %s: %w", art.Name, err)
+	}
+	if n > c.maxDownload {
+		f.Close()
+		return nil, fmt.Errorf("artifact %s is over the %d byte limit: %w", art.Name, c.maxDownload, ErrTooLarge)
+	}
+	f.Size = n
+	f.Digest = "sha256:" + hex.EncodeToString(h.Sum(nil))
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if art.Digest != "" && !strings.EqualFold(art.Digest, f.Digest) {
+		f.Close()
+		return nil, fmt.Errorf("artifact %s downloaded as %s, API reported %s: %w", art.Name, f.Digest, art.Digest, ErrDigestMismatch)
+	}
+	return f, nil
+}
@@ -0,0 +1,77 @@
+package github
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// downloadString downloads an artifact and returns its contents.
+func downloadString(ctx context.Context, c *Client, art Artifact) (string, error) {
+	f, err := c.DownloadArtifact(ctx, art)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	return string(data), err
+}
+
+func TestDownloadArtifact(t *testing.T) {
+	const body = "PK fake zip bytes"
+	sum := sha256.Sum256([]byte(body))
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	c := NewClientWithBase("", srv.URL)
+	url := srv.URL + "/repos/acme/app/actions/artifacts/9/zip"
+
+	f, err := c.DownloadArtifact(ctx, Artifact{Name: "pbom-1", ArchiveDownloadURL: url, Digest: digest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Size != int64(len(body)) || f.Digest != digest {
+		t.Errorf("file size %d digest %s, want %d %s", f.Size, f.Digest, len(body), digest)
+	}
+	name := f.Name()
+	f.Close()
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("temporary file %s left behind after Close", name)
+	}
+
+	tests := []struct {
+		name    string
+		art     Artifact
+		max     int64
+		wantErr error
+	}{
+		{"digest mismatch", Artifact{Digest: "sha256:" + strings.Repeat("0", 64)}, DefaultMaxDownloadSize, ErrDigestMismatch},
+		{"reported size over limit", Artifact{SizeInBytes: 1 << 30}, DefaultMaxDownloadSize, ErrTooLarge},
+		{"downloaded size over limit", Artifact{SizeInBytes: 4}, 8, ErrTooLarge},
+		{"no digest reported", Artifact{}, DefaultMaxDownloadSize, nil},
+	}
+	for _, tt := range tests {
+		c.SetMaxDownloadSize(tt.max)
+		tt.art.ArchiveDownloadURL = url
+		_, err := downloadString(ctx, c, tt.art)
+		if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+	if requests != 4 {
+		t.Errorf("%d downloads, want 4 (none for a reported size over the limit)", requests)
+	}
+}
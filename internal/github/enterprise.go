@@ -0,0 +1,68 @@
+package github
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultBaseURL is the REST API of github.com.
+const DefaultBaseURL = "https://api.github.com"
+
+// ParseBaseURL normalizes the REST API URL of a GitHub instance. A GitHub
+// Enterprise Server host given without a path gets the /api/v3 prefix its
+// API is served under, and github.com maps to api.github.com.
+func ParseBaseURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("parsing GitHub URL: %w", err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", fmt.Errorf("GitHub URL %q must be an http(s) URL, e.g. https://ghe.example.com/api/v3", raw)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("GitHub URL %q must not have a query or fragment", raw)
+	}
+	if strings.EqualFold(u.Host, "github.com") || strings.EqualFold(u.Host, "api.github.com") {
+		return DefaultBaseURL, nil
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	if u.Path == "" {
+		u.Path = "/api/v3"
+	}
+	return u.String(), nil
+}
+
+// WebURL returns the web URL of the GitHub instance whose REST API is at
+// baseURL, as returned by ParseBaseURL: https://github.com for
+// api.github.com, otherwise the API URL without its path.
+func WebURL(baseURL string) string {
+	if baseURL == DefaultBaseURL {
+		return "https://github.com"
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return baseURL
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// NewTransport returns an HTTP transport that trusts the certificate
+// authorities in caBundle, PEM encoded, in addition to the system roots.
+// GitHub Enterprise Server instances often use an internal CA.
+func NewTransport(caBundle []byte) (*http.Transport, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(caBundle) {
+		return nil, errors.New("no PEM certificates found in CA bundle")
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	return t, nil
+}
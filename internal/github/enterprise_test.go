@@ -0,0 +1,109 @@
+package github
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseBaseURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{in: "https://ghe.example.com/api/v3", want: "https://ghe.example.com/api/v3"},
+		{in: "https://ghe.example.com/api/v3/", want: "https://ghe.example.com/api/v3"},
+		{in: "https://ghe.example.com", want: "https://ghe.example.com/api/v3"},
+		{in: "http://localhost:8080/", want: "http://localhost:8080/api/v3"},
+		{in: "https://github.com", want: DefaultBaseURL},
+		{in: "https://api.github.com/", want: DefaultBaseURL},
+		{in: "ghe.example.com", wantErr: true},
+		{in: "ftp://ghe.example.com", wantErr: true},
+		{in: "https://ghe.example.com/api/v3?x=1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseBaseURL(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseBaseURL(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestWebURL(t *testing.T) {
+	tests := map[string]string{
+		DefaultBaseURL:                   "https://github.com",
+		"https://ghe.example.com/api/v3": "https://ghe.example.com",
+		"http://localhost:8080/api/v3":   "http://localhost:8080",
+	}
+	for in, want := range tests {
+		if got := WebURL(in); got != want {
+			t.Errorf("WebURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNewTransportTrustsBundle(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	c := NewClientWithBase("", srv.URL+"/api/v3")
+	if _, err := c.GetWorkflowRun(ctx, "acme", "app", 1); err == nil {
+		t.Fatal("untrusted certificate accepted")
+	}
+
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	rt, err := NewTransport(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetTransport(rt)
+	if _, err := c.GetWorkflowRun(ctx, "acme", "app", 1); err != nil {
+		t.Errorf("with CA bundle: %v", err)
+	}
+
+	if _, err := NewTransport([]byte("not pem")); err == nil {
+		t.Error("NewTransport accepted a bundle without certificates")
+	}
+}
+
+func TestDownloadRedirectDropsToken(t *testing.T) {
+	// GitHub Enterprise Server redirects artifact downloads to pre-signed
+	// storage URLs on its own host.
+	var storageAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/v3/repos/acme/app/actions/artifacts/9/zip"):
+			if r.Header.Get("Authorization") != "Bearer token" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			http.Redirect(w, r, "/storage/artifacts/9?sig=abc", http.StatusFound)
+		case r.URL.Path == "/storage/artifacts/9":
+			storageAuth = r.Header.Get("Authorization")
+			w.Write([]byte("zip bytes"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	c := NewClientWithBase("token", srv.URL+"/api/v3")
+	data, err := downloadString(ctx, c, Artifact{ArchiveDownloadURL: srv.URL + "/api/v3/repos/acme/app/actions/artifacts/9/zip"})
+	if err != nil || data != "zip bytes" {
+		t.Fatalf("DownloadArtifact = %q, %v", data, err)
+	}
+	if storageAuth != "" {
+		t.Errorf("storage request sent Authorization %q", storageAuth)
+	}
+
+	if _, err := downloadString(ctx, c, Artifact{ArchiveDownloadURL: "https://evil.example.com/api/v3/repos/acme/app/actions/artifacts/9/zip"}); err == nil {
+		t.Error("download from outside the API base URL succeeded")
+	}
+}
@@ -0,0 +1,77 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is matched by errors for API responses with status 404. The
+// API also answers 404 for resources the token cannot see.
+var ErrNotFound = errors.New("not found")
+
+// ErrRateLimited is matched by errors for requests refused by the primary
+// or a secondary rate limit that the client did not wait out.
+var ErrRateLimited = errors.New("rate limited")
+
+// APIError is an unsuccessful GitHub API response.
+type APIError struct {
+	Path       string
+	StatusCode int
+	Message    string
+	// RetryAfter is how long the API asked the client to wait before
+	// retrying, for rate-limited requests. Zero if it gave no time.
+	RetryAfter time.Duration
+
+	rateLimited bool
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("GitHub API %s returned %d: %s", e.Path, e.StatusCode, e.Message)
+}
+
+// Unwrap makes errors.Is match ErrNotFound and ErrRateLimited.
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.rateLimited:
+		return ErrRateLimited
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	}
+	return nil
+}
+
+// apiError builds the error for an unsuccessful response. now is the
+// current time, for turning a rate limit reset time into a wait.
+func apiError(resp *http.Response, body []byte, now time.Time) *APIError {
+	e := &APIError{
+		Path:       resp.Request.URL.RequestURI(),
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(body)),
+	}
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return e
+	}
+
+	h := resp.Header
+	switch {
+	case h.Get("Retry-After") != "":
+		// Secondary rate limits usually say how long to wait.
+		e.rateLimited = true
+		if secs, err := strconv.Atoi(h.Get("Retry-After")); err == nil && secs > 0 {
+			e.RetryAfter = time.Duration(secs) * time.Second
+		}
+	case h.Get("X-RateLimit-Remaining") == "0":
+		e.rateLimited = true
+		if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			// A reset already past means the window has rolled over.
+			e.RetryAfter = max(time.Unix(reset, 0).Sub(now), time.Second)
+		}
+	case strings.Contains(strings.ToLower(e.Message), "secondary rate limit"):
+		e.rateLimited = true
+	}
+	return e
+}
@@ -0,0 +1,206 @@
+package github
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// A fixture is one recorded API response. Fixtures are stored one per
+// file, named after the request, so a recorded set can be attached to a
+// bug report and replayed offline.
+type fixture struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body"`
+}
+
+// fixtureHeaders are the response headers kept in a fixture; the rest
+// (rate limits, request IDs, cookies) vary between runs or are sensitive.
+var fixtureHeaders = []string{"Content-Type", "Location", "Link", "ETag"}
+
+var unsafeFixtureChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// fixtureName returns the file name for a request: a readable form of the
+// host and path plus a hash of the full method and URL.
+func fixtureName(method string, u *url.URL) string {
+	sum := sha256.Sum256([]byte(method + " " + u.String()))
+	readable := unsafeFixtureChars.ReplaceAllString(u.Host+u.Path, "_")
+	if len(readable) > 100 {
+		readable = readable[:100]
+	}
+	return fmt.Sprintf("%s_%s_%s.json", method, readable, hex.EncodeToString(sum[:6]))
+}
+
+// withoutQuery returns u without its query and fragment. Artifact
+// downloads redirect to blob storage URLs pre-signed in the query.
+func withoutQuery(u *url.URL) *url.URL {
+	stripped := *u
+	stripped.RawQuery, stripped.ForceQuery, stripped.Fragment, stripped.RawFragment = "", false, "", ""
+	return &stripped
+}
+
+// Recorder is an http.RoundTripper that passes requests to another
+// transport and saves every response in a fixture directory.
+type Recorder struct {
+	dir     string
+	next    http.RoundTripper
+	maxBody int64
+
+	mu        sync.Mutex
+	redirects map[string]bool // recorded redirect targets, with query
+}
+
+// NewRecorder records the responses of next in dir, creating it if
+// needed. A nil next uses http.DefaultTransport.
+func NewRecorder(dir string, next http.RoundTripper) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating fixture directory: %w", err)
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{dir: dir, next: next, maxBody: DefaultMaxDownloadSize, redirects: make(map[string]bool)}, nil
+}
+
+// SetMaxBodySize sets the largest response body, in bytes, the recorder
+// saves; it should match the client's SetMaxDownloadSize. Larger
+// responses are passed on without being recorded.
+func (r *Recorder) SetMaxBodySize(n int64) {
+	r.maxBody = n
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, r.maxBody+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("reading response to record: %w", err)
+	}
+	if int64(len(body)) > r.maxBody {
+		// The client enforces its own limit on the rest.
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	// Redirect targets are recorded, and replayed, without their query,
+	// so that shared fixtures hold no pre-signed URLs.
+	u := req.URL
+	r.mu.Lock()
+	if r.redirects[u.String()] {
+		u = withoutQuery(u)
+	}
+	r.mu.Unlock()
+
+	f := fixture{Method: req.Method, URL: u.String(), Status: resp.StatusCode, Body: body}
+	if strings.HasSuffix(req.URL.Path, "/access_tokens") {
+		// Fixture sets are meant to be shared; never record live tokens.
+		f.Body = redactToken(body)
+	}
+	for _, h := range fixtureHeaders {
+		if v := resp.Header.Values(h); len(v) > 0 {
+			if f.Header == nil {
+				f.Header = make(http.Header)
+			}
+			f.Header[h] = v
+		}
+	}
+	if loc, err := resp.Location(); err == nil {
+		r.mu.Lock()
+		r.redirects[loc.String()] = true
+		r.mu.Unlock()
+		f.Header["Location"] = []string{withoutQuery(loc).String()}
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(r.dir, fixtureName(req.Method, u)), data, 0o644); err != nil {
+		return nil, fmt.Errorf("recording fixture: %w", err)
+	}
+	return resp, nil
+}
+
+// redactToken replaces the token of an installation token response.
+func redactToken(body []byte) []byte {
+	var resp map[string]any
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return []byte(`{}`)
+	}
+	if _, ok := resp["token"]; ok {
+		resp["token"] = "REDACTED"
+	}
+	redacted, _ := json.Marshal(resp)
+	return redacted
+}
+
+// Replayer is an http.RoundTripper that answers requests from a fixture
+// directory written by a Recorder. Requests without a fixture fail, so a
+// replay never reaches the network.
+type Replayer struct {
+	dir string
+}
+
+// NewReplayer serves the fixtures in dir.
+func NewReplayer(dir string) (*Replayer, error) {
+	if fi, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("opening fixtures: %w", err)
+	} else if !fi.IsDir() {
+		return nil, fmt.Errorf("fixtures %s is not a directory", dir)
+	}
+	return &Replayer{dir: dir}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	data, err := os.ReadFile(filepath.Join(r.dir, fixtureName(req.Method, req.URL)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no fixture recorded for %s %s", req.Method, req.URL)
+		}
+		return nil, err
+	}
+	var f fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing fixture for %s: %w", req.URL, err)
+	}
+	header := f.Header
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Status, http.StatusText(f.Status)),
+		StatusCode:    f.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(f.Body)),
+		ContentLength: int64(len(f.Body)),
+		Request:       req,
+	}, nil
+}
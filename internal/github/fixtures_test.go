@@ -0,0 +1,94 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplayFixtures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/app/actions/runs/1/jobs":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"total_count":1,"jobs":[{"id":7,"runner_name":"gh-1"}]}`))
+		case "/repos/acme/app/actions/artifacts/9/zip":
+			// Artifact downloads redirect to blob storage.
+			http.Redirect(w, r, "/blob/9?sig=abc", http.StatusFound)
+		case "/blob/9":
+			w.Write([]byte("zip bytes"))
+		case "/repos/acme/app/actions/artifacts/10/zip":
+			w.Write([]byte(strings.Repeat("x", 100)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	base := srv.URL
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	rec, err := NewRecorder(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recording := NewClientWithBase("token", base)
+	recording.SetTransport(rec)
+	if _, err := recording.GetJobs(ctx, "acme", "app", 1); err != nil {
+		t.Fatalf("GetJobs while recording: %v", err)
+	}
+	art := Artifact{Name: "pbom-9", ArchiveDownloadURL: base + "/repos/acme/app/actions/artifacts/9/zip"}
+	f, err := recording.DownloadArtifact(ctx, art)
+	if err != nil {
+		t.Fatalf("DownloadArtifact while recording: %v", err)
+	}
+	f.Close()
+	// Bodies over the limit are passed on, not recorded.
+	rec.SetMaxBodySize(50)
+	recording.SetMaxDownloadSize(50)
+	big := Artifact{Name: "big", ArchiveDownloadURL: base + "/repos/acme/app/actions/artifacts/10/zip"}
+	if _, err := recording.DownloadArtifact(ctx, big); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("oversized download while recording: err = %v, want ErrTooLarge", err)
+	}
+	srv.Close()
+
+	recorded, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range recorded {
+		data, _ := os.ReadFile(filepath.Join(dir, e.Name()))
+		if strings.Contains(string(data), "sig=") {
+			t.Errorf("fixture %s records the signed URL: %s", e.Name(), data)
+		}
+		if strings.Contains(e.Name(), "artifacts_10") {
+			t.Errorf("oversized response recorded in %s", e.Name())
+		}
+	}
+
+	rep, err := NewReplayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replaying := NewClientWithBase("", base)
+	replaying.SetTransport(rep)
+
+	jobs, err := replaying.GetJobs(ctx, "acme", "app", 1)
+	if err != nil || len(jobs) != 1 || jobs[0].RunnerName != "gh-1" {
+		t.Errorf("replayed GetJobs = %+v, %v", jobs, err)
+	}
+	if data, err := downloadString(ctx, replaying, art); err != nil || data != "zip bytes" {
+		t.Errorf("replayed DownloadArtifact = %q, %v", data, err)
+	}
+	if _, err := replaying.GetJobs(ctx, "acme", "app", 2); err == nil || !strings.Contains(err.Error(), "no fixture") {
+		t.Errorf("unrecorded request error = %v, want no fixture", err)
+	}
+
+	if _, err := NewReplayer(dir + "/missing"); err == nil {
+		t.Error("NewReplayer accepted a missing directory")
+	}
+}
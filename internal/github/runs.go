@@ -6,12 +6,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"strconv"
 )
 
 // GetWorkflowRun fetches a single workflow run by ID.
 func (c *Client) GetWorkflowRun(ctx context.Context, owner, repo string, runID int64) (*WorkflowRun, error) {
 	path := fmt.Sprintf("/repos/%s/%s/actions/runs/%d", owner, repo, runID)
-	data, err := c.get(ctx, path)
+	data, err := c.get(ctx, "/repos/{owner}/{repo}/actions/runs/{run_id}", path)
 	if err != nil {
 		return nil, err
 	}
@@ -22,58 +23,112 @@ func (c *Client) GetWorkflowRun(ctx context.Context, owner, repo string, runID i
 	return &run, nil
 }
 
-// ListRunsByCommit lists workflow runs for a specific commit SHA.
+// ListRunsByCommit lists all workflow runs for a specific commit SHA.
 func (c *Client) ListRunsByCommit(ctx context.Context, owner, repo, sha string) ([]WorkflowRun, error) {
-	path := fmt.Sprintf("/repos/%s/%s/actions/runs?head_sha=%s", owner, repo, url.QueryEscape(sha))
-	data, err := c.get(ctx, path)
+	path := fmt.Sprintf("/repos/%s/%s/actions/runs?head_sha=%s&per_page=100", owner, repo, url.QueryEscape(sha))
+	var runs []WorkflowRun
+	err := c.getPages(ctx, "/repos/{owner}/{repo}/actions/runs", path, func(data []byte) error {
+		var resp WorkflowRunsResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return fmt.Errorf("parsing workflow runs: %w", err)
+		}
+		runs = append(runs, resp.WorkflowRuns...)
+		return nil
+	})
+	return runs, err
+}
+
+// ListRunsOptions filters ListRuns. Zero fields are not sent.
+type ListRunsOptions struct {
+	Status  string // e.g. "completed"
+	Created string // date range in GitHub search syntax, e.g. ">=2026-01-01"
+	Page    int    // 1-based
+	PerPage int    // at most 100
+}
+
+// ListRuns lists one page of a repository's workflow runs, newest first.
+// It also returns the total number of runs matching the filters. Unlike
+// the other list calls it does not follow later pages, so that callers can
+// checkpoint between them.
+func (c *Client) ListRuns(ctx context.Context, owner, repo string, opts ListRunsOptions) ([]WorkflowRun, int, error) {
+	q := url.Values{}
+	if opts.Status != "" {
+		q.Set("status", opts.Status)
+	}
+	if opts.Created != "" {
+		q.Set("created", opts.Created)
+	}
+	if opts.Page > 0 {
+		q.Set("page", strconv.Itoa(opts.Page))
+	}
+	if opts.PerPage > 0 {
+		q.Set("per_page", strconv.Itoa(opts.PerPage))
+	}
+	path := fmt.Sprintf("/repos/%s/%s/actions/runs", owner, repo)
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	data, err := c.get(ctx, "/repos/{owner}/{repo}/actions/runs", path)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	var resp WorkflowRunsResponse
 	if err := json.Unmarshal(data, &resp); err != nil {
-		return nil, fmt.Errorf("parsing workflow runs: %w", err)
+		return nil, 0, fmt.Errorf("parsing workflow runs: %w", err)
 	}
-	return resp.WorkflowRuns, nil
+	return resp.WorkflowRuns, resp.TotalCount, nil
+}
+
+// ListOrgRepos lists all of an organization's repositories.
+func (c *Client) ListOrgRepos(ctx context.Context, org string) ([]Repo, error) {
+	path := fmt.Sprintf("/orgs/%s/repos?per_page=100", url.PathEscape(org))
+	var repos []Repo
+	err := c.getPages(ctx, "/orgs/{org}/repos", path, func(data []byte) error {
+		var page []Repo
+		if err := json.Unmarshal(data, &page); err != nil {
+			return fmt.Errorf("parsing repositories: %w", err)
+		}
+		repos = append(repos, page...)
+		return nil
+	})
+	return repos, err
 }
 
 // GetJobs fetches all jobs for a workflow run.
 func (c *Client) GetJobs(ctx context.Context, owner, repo string, runID int64) ([]Job, error) {
-	path := fmt.Sprintf("/repos/%s/%s/actions/runs/%d/jobs", owner, repo, runID)
-	data, err := c.get(ctx, path)
-	if err != nil {
-		return nil, err
-	}
-	var resp JobsResponse
-	if err := json.Unmarshal(data, &resp); err != nil {
-		return nil, fmt.Errorf("parsing jobs: %w", err)
-	}
-	return resp.Jobs, nil
+	path := fmt.Sprintf("/repos/%s/%s/actions/runs/%d/jobs?per_page=100", owner, repo, runID)
+	var jobs []Job
+	err := c.getPages(ctx, "/repos/{owner}/{repo}/actions/runs/{run_id}/jobs", path, func(data []byte) error {
+		var resp JobsResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return fmt.Errorf("parsing jobs: %w", err)
+		}
+		jobs = append(jobs, resp.Jobs...)
+		return nil
+	})
+	return jobs, err
 }
 
 // GetArtifacts fetches all artifacts for a workflow run.
 func (c *Client) GetArtifacts(ctx context.Context, owner, repo string, runID int64) ([]Artifact, error) {
-	path := fmt.Sprintf("/repos/%s/%s/actions/runs/%d/artifacts", owner, repo, runID)
-	data, err := c.get(ctx, path)
-	if err != nil {
-		return nil, err
-	}
-	var resp ArtifactsResponse
-	if err := json.Unmarshal(data, &resp); err != nil {
-		return nil, fmt.Errorf("parsing artifacts: %w", err)
-	}
-	return resp.Artifacts, nil
-}
-
-// DownloadArtifact downloads a workflow artifact ZIP by its archive URL.
-func (c *Client) DownloadArtifact(ctx context.Context, downloadURL string) ([]byte, error) {
-	return c.download(ctx, downloadURL)
+	path := fmt.Sprintf("/repos/%s/%s/actions/runs/%d/artifacts?per_page=100", owner, repo, runID)
+	var artifacts []Artifact
+	err := c.getPages(ctx, "/repos/{owner}/{repo}/actions/runs/{run_id}/artifacts", path, func(data []byte) error {
+		var resp ArtifactsResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return fmt.Errorf("parsing artifacts: %w", err)
+		}
+		artifacts = append(artifacts, resp.Artifacts...)
+		return nil
+	})
+	return artifacts, err
 }
 
 // GetWorkflowContent fetches a workflow YAML file's content from the repo.
 // Returns the decoded file bytes (base64-decoded from the Contents API).
 func (c *Client) GetWorkflowContent(ctx context.Context, owner, repo, path, ref string) ([]byte, error) {
 	apiPath := fmt.Sprintf("/repos/%s/%s/contents/%s?ref=%s", owner, repo, url.PathEscape(path), url.QueryEscape(ref))
-	data, err := c.get(ctx, apiPath)
+	data, err := c.get(ctx, "/repos/{owner}/{repo}/contents/{path}", apiPath)
 	if err != nil {
 		return nil, err
 	}
@@ -5,6 +5,7 @@ import "time"
 // WorkflowRun represents a GitHub Actions workflow run.
 type WorkflowRun struct {
 	ID           int64     `json:"id"`
+	RunAttempt   int       `json:"run_attempt"`
 	Name         string    `json:"name"`
 	HeadSHA      string    `json:"head_sha"`
 	HeadBranch   string    `json:"head_branch"`
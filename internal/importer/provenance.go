@@ -0,0 +1,430 @@
+// Package importer builds PBOM documents from SLSA provenance produced by
+// other tools, such as actions/attest-build-provenance or the
+// slsa-github-generator, so repositories without the collector workflow
+// still get PBOM coverage.
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/dsse"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/export"
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+	"github.com/google/uuid"
+)
+
+const (
+	// InTotoPayloadType is the DSSE payload type of in-toto statements.
+	InTotoPayloadType = "application/vnd.in-toto+json"
+	// SLSAProvenanceV02Type is the SLSA v0.2 provenance predicate type.
+	SLSAProvenanceV02Type = "https://slsa.dev/provenance/v0.2"
+
+	// unknownActor fills build.actor when the provenance does not record
+	// who triggered the build (SLSA v1 from GitHub does not).
+	unknownActor = "unknown"
+)
+
+// Statement is an in-toto Statement whose predicate is decoded later,
+// according to its type.
+type Statement struct {
+	Type          string           `json:"_type"`
+	Subject       []export.Subject `json:"subject"`
+	PredicateType string           `json:"predicateType"`
+	Predicate     json.RawMessage  `json:"predicate"`
+}
+
+// sigstoreBundle is the part of a Sigstore bundle that carries the
+// attestation. Verification material is ignored.
+type sigstoreBundle struct {
+	MediaType    string         `json:"mediaType"`
+	DSSEEnvelope *dsse.Envelope `json:"dsseEnvelope"`
+}
+
+// ParseStatement extracts an in-toto Statement from a bare statement, a
+// DSSE envelope or a Sigstore bundle. Signatures are not verified.
+func ParseStatement(data []byte) (*Statement, error) {
+	var bundle sigstoreBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("parsing JSON: %w", err)
+	}
+
+	switch {
+	case strings.HasPrefix(bundle.MediaType, "application/vnd.dev.sigstore.bundle"):
+		if bundle.DSSEEnvelope == nil {
+			return nil, errors.New("sigstore bundle has no DSSE envelope (message signatures carry no provenance)")
+		}
+		if bundle.DSSEEnvelope.PayloadType != InTotoPayloadType {
+			return nil, fmt.Errorf("sigstore bundle payload type is %q, expected %q", bundle.DSSEEnvelope.PayloadType, InTotoPayloadType)
+		}
+		data = bundle.DSSEEnvelope.Payload
+	case dsse.IsEnvelope(data):
+		payload, _, err := dsse.Unwrap(data, InTotoPayloadType)
+		if err != nil {
+			return nil, err
+		}
+		data = payload
+	}
+
+	var st Statement
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("parsing in-toto statement: %w", err)
+	}
+	if !strings.HasPrefix(st.Type, "https://in-toto.io/Statement/") {
+		return nil, fmt.Errorf("not an in-toto statement (_type %q)", st.Type)
+	}
+	if len(st.Subject) == 0 {
+		return nil, errors.New("in-toto statement has no subjects")
+	}
+	return &st, nil
+}
+
+// FromProvenance reads SLSA provenance (see ParseStatement for the
+// accepted envelopes) and builds a PBOM with one artifact per subject.
+// Data may hold several JSON documents, one per line, as written by
+// 'gh attestation download'; the first SLSA provenance among them is used
+// and documents that are not in-toto statements are skipped. Builders on
+// the GitHub instance at serverURL, such as a GitHub Enterprise Server,
+// are trusted like github.com's (see InferSLSALevel).
+func FromProvenance(data []byte, serverURL string) (*schema.PBOM, error) {
+	docs, err := splitDocuments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var st *Statement
+	var parseErr error
+	for _, doc := range docs {
+		s, err := ParseStatement(doc)
+		if err != nil {
+			if parseErr == nil {
+				parseErr = err
+			}
+			continue
+		}
+		provenance := s.PredicateType == export.SLSAProvenanceType || s.PredicateType == SLSAProvenanceV02Type
+		// Without provenance, the first statement's predicate type is
+		// reported below.
+		if st == nil || provenance {
+			st = s
+		}
+		if provenance {
+			break
+		}
+	}
+	switch {
+	case st == nil && len(docs) == 1:
+		return nil, parseErr
+	case st == nil:
+		return nil, fmt.Errorf("none of the %d documents is an in-toto statement: %w", len(docs), parseErr)
+	}
+
+	var info buildInfo
+	switch st.PredicateType {
+	case export.SLSAProvenanceType:
+		err = info.fromV1(st.Predicate)
+	case SLSAProvenanceV02Type:
+		err = info.fromV02(st.Predicate)
+	default:
+		return nil, fmt.Errorf("unsupported predicate type %q (expected SLSA provenance v1 or v0.2)", st.PredicateType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if info.repository == "" || info.commitSHA == "" {
+		return nil, errors.New("provenance does not identify the source repository and commit")
+	}
+
+	pbom := &schema.PBOM{
+		PBOMVersion: schema.Version,
+		ID:          uuid.New().String(),
+		Timestamp:   time.Now().UTC(),
+		Source: schema.Source{
+			Repository: info.repository,
+			CommitSHA:  info.commitSHA,
+			Ref:        info.ref,
+		},
+		Build: schema.Build{
+			WorkflowRunID: info.runID,
+			WorkflowName:  info.workflowPath,
+			WorkflowFile:  info.workflowPath,
+			Trigger:       schema.TriggerForEvent(info.event),
+			Actor:         info.actor,
+			StartedAt:     info.startedAt,
+			CompletedAt:   info.finishedAt,
+			// Provenance is only generated for builds that produced
+			// artifacts.
+			Status: "success",
+		},
+	}
+	if branch, ok := strings.CutPrefix(info.ref, "refs/heads/"); ok {
+		pbom.Source.Branch = branch
+	}
+	if pbom.Build.Actor == "" {
+		pbom.Build.Actor = unknownActor
+	}
+	if info.runnerEnvironment != "" {
+		pbom.Build.Runner = &schema.Runner{
+			OS:         info.runnerOS,
+			Arch:       info.runnerArch,
+			SelfHosted: info.runnerEnvironment == "self-hosted",
+		}
+	}
+
+	prov := &schema.Provenance{
+		BuilderID: info.builderID,
+		SLSALevel: InferSLSALevel(info.builderID, serverURL),
+	}
+	for _, s := range st.Subject {
+		a, err := artifactFromSubject(s)
+		if err != nil {
+			return nil, err
+		}
+		p := *prov
+		a.Provenance = &p
+		pbom.Artifacts = append(pbom.Artifacts, a)
+	}
+
+	return pbom, nil
+}
+
+// splitDocuments returns each JSON value in data.
+func splitDocuments(data []byte) ([]json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var docs []json.RawMessage
+	for {
+		var doc json.RawMessage
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing JSON: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) == 0 {
+		return nil, errors.New("no JSON document found")
+	}
+	return docs, nil
+}
+
+// buildInfo is the provenance data common to all supported predicate
+// versions.
+type buildInfo struct {
+	builderID         string
+	repository        string // owner/repo
+	commitSHA         string
+	ref               string
+	workflowPath      string
+	runID             string
+	event             string
+	actor             string
+	runnerEnvironment string
+	runnerOS          string
+	runnerArch        string
+	startedAt         *time.Time
+	finishedAt        *time.Time
+}
+
+func (b *buildInfo) fromV1(raw json.RawMessage) error {
+	var pred export.SLSAProvenance
+	if err := json.Unmarshal(raw, &pred); err != nil {
+		return fmt.Errorf("parsing SLSA v1 predicate: %w", err)
+	}
+
+	b.builderID = pred.RunDetails.Builder.ID
+	if md := pred.RunDetails.Metadata; md != nil {
+		b.runID = runIDFromURL(md.InvocationID)
+		b.startedAt = md.StartedOn
+		b.finishedAt = md.FinishedOn
+	}
+
+	if wf, ok := pred.BuildDefinition.ExternalParameters["workflow"].(map[string]any); ok {
+		b.repository = repoFromURL(stringField(wf, "repository"))
+		b.ref = stringField(wf, "ref")
+		b.workflowPath = stringField(wf, "path")
+	}
+	if gh, ok := pred.BuildDefinition.InternalParameters["github"].(map[string]any); ok {
+		b.event = stringField(gh, "event_name")
+		b.actor = stringField(gh, "actor")
+		b.runnerEnvironment = stringField(gh, "runner_environment")
+		b.runnerOS = stringField(gh, "runner_os")
+		b.runnerArch = stringField(gh, "runner_arch")
+	}
+
+	for _, dep := range pred.BuildDefinition.ResolvedDependencies {
+		commit := dep.Digest["gitCommit"]
+		if commit == "" {
+			commit = dep.Digest["sha1"]
+		}
+		if commit == "" {
+			continue
+		}
+		b.commitSHA = commit
+		repo, ref := splitGitURI(dep.URI)
+		if b.repository == "" {
+			b.repository = repo
+		}
+		if b.ref == "" {
+			b.ref = ref
+		}
+		break
+	}
+	return nil
+}
+
+// slsaV02 is the subset of the SLSA v0.2 predicate read by the importer.
+type slsaV02 struct {
+	Builder struct {
+		ID string `json:"id"`
+	} `json:"builder"`
+	Invocation struct {
+		ConfigSource struct {
+			URI        string            `json:"uri"`
+			Digest     map[string]string `json:"digest"`
+			EntryPoint string            `json:"entryPoint"`
+		} `json:"configSource"`
+		Environment map[string]any `json:"environment"`
+	} `json:"invocation"`
+	Metadata struct {
+		BuildInvocationID string     `json:"buildInvocationId"`
+		BuildStartedOn    *time.Time `json:"buildStartedOn"`
+		BuildFinishedOn   *time.Time `json:"buildFinishedOn"`
+	} `json:"metadata"`
+}
+
+func (b *buildInfo) fromV02(raw json.RawMessage) error {
+	var pred slsaV02
+	if err := json.Unmarshal(raw, &pred); err != nil {
+		return fmt.Errorf("parsing SLSA v0.2 predicate: %w", err)
+	}
+
+	cs := pred.Invocation.ConfigSource
+	env := pred.Invocation.Environment
+
+	b.builderID = pred.Builder.ID
+	b.repository, b.ref = splitGitURI(cs.URI)
+	b.commitSHA = cs.Digest["sha1"]
+	b.workflowPath = cs.EntryPoint
+	b.runID = stringField(env, "github_run_id")
+	b.event = stringField(env, "github_event_name")
+	b.actor = stringField(env, "github_actor")
+	b.startedAt = pred.Metadata.BuildStartedOn
+	b.finishedAt = pred.Metadata.BuildFinishedOn
+
+	if b.ref == "" {
+		b.ref = stringField(env, "github_ref")
+	}
+	if b.runID == "" {
+		b.runID = runIDFromURL(pred.Metadata.BuildInvocationID)
+	}
+	return nil
+}
+
+// InferSLSALevel estimates the SLSA build level from the builder ID.
+// Builders from the slsa-github-generator's *_slsa3 reusable workflows run
+// isolated from the calling workflow (level 3); GitHub-hosted runners
+// produce signed, platform-generated provenance (level 2); anything else
+// at least has provenance (level 1). Builders are recognized on github.com
+// and on the GitHub instance at serverURL, if given.
+func InferSLSALevel(builderID, serverURL string) int {
+	id, _, _ := strings.Cut(builderID, "@")
+	if id == "" {
+		return 0
+	}
+	servers := []string{"https://github.com"}
+	if serverURL != "" {
+		servers = append(servers, strings.TrimSuffix(serverURL, "/"))
+	}
+	for _, server := range servers {
+		switch {
+		case strings.HasPrefix(id, server+"/slsa-framework/slsa-github-generator/") && strings.HasSuffix(id, "_slsa3.yml"):
+			return 3
+		case id == server+"/actions/runner/github-hosted", id == server+"/actions/runner":
+			return 2
+		}
+	}
+	return 1
+}
+
+func artifactFromSubject(s export.Subject) (schema.Artifact, error) {
+	alg := "sha256"
+	hex := s.Digest[alg]
+	if hex == "" {
+		for a, h := range s.Digest {
+			alg, hex = a, h
+			break
+		}
+	}
+	if hex == "" {
+		return schema.Artifact{}, fmt.Errorf("subject %q has no digest", s.Name)
+	}
+
+	a := schema.Artifact{
+		Name:   path.Base(s.Name),
+		Type:   "other",
+		Digest: alg + ":" + hex,
+	}
+	if looksLikeImage(s.Name) {
+		a.Type = "container-image"
+		a.URI = s.Name + "@" + a.Digest
+	}
+	return a, nil
+}
+
+// looksLikeImage reports whether a subject name is an image reference
+// such as ghcr.io/org/app: a registry host followed by a repository path.
+func looksLikeImage(name string) bool {
+	host, rest, ok := strings.Cut(name, "/")
+	return ok && rest != "" && (strings.Contains(host, ".") || strings.Contains(host, ":"))
+}
+
+// splitGitURI splits "git+https://github.com/owner/repo@refs/heads/main"
+// into the owner/repo and the ref.
+func splitGitURI(uri string) (repo, ref string) {
+	uri = strings.TrimPrefix(uri, "git+")
+	uri, ref, _ = strings.Cut(uri, "@")
+	return repoFromURL(uri), ref
+}
+
+// repoFromURL returns owner/repo from a repository URL.
+func repoFromURL(raw string) string {
+	u, err := url.Parse(strings.TrimSuffix(raw, ".git"))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return strings.Trim(u.Path, "/")
+}
+
+// runIDFromURL extracts the run ID from a GitHub Actions run URL such as
+// https://github.com/owner/repo/actions/runs/123/attempts/1.
+func runIDFromURL(raw string) string {
+	_, rest, ok := strings.Cut(raw, "/actions/runs/")
+	if !ok {
+		return ""
+	}
+	id, _, _ := strings.Cut(rest, "/")
+	return id
+}
+
+func stringField(m map[string]any, key string) string {
+	switch v := m[key].(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	default:
+		return ""
+	}
+}
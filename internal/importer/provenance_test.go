@@ -0,0 +1,220 @@
+package importer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/export"
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+)
+
+// attestV1 is provenance in the shape produced by
+// actions/attest-build-provenance.
+const attestV1 = `{
+  "_type": "https://in-toto.io/Statement/v1",
+  "subject": [
+    {"name": "ghcr.io/acme-corp/payments-service", "digest": {"sha256": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"}},
+    {"name": "dist/payments-cli", "digest": {"sha256": "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"}}
+  ],
+  "predicateType": "https://slsa.dev/provenance/v1",
+  "predicate": {
+    "buildDefinition": {
+      "buildType": "https://actions.github.io/buildtypes/workflow/v1",
+      "externalParameters": {
+        "workflow": {"ref": "refs/heads/main", "repository": "https://github.com/acme-corp/payments-service", "path": ".github/workflows/release.yml"}
+      },
+      "internalParameters": {
+        "github": {"event_name": "push", "repository_id": "123", "repository_owner_id": "456", "runner_environment": "github-hosted"}
+      },
+      "resolvedDependencies": [
+        {"uri": "git+https://github.com/acme-corp/payments-service@refs/heads/main", "digest": {"gitCommit": "a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2"}}
+      ]
+    },
+    "runDetails": {
+      "builder": {"id": "https://github.com/actions/runner/github-hosted"},
+      "metadata": {"invocationId": "https://github.com/acme-corp/payments-service/actions/runs/7890123456/attempts/1"}
+    }
+  }
+}`
+
+// generatorV02 is provenance in the shape produced by the
+// slsa-github-generator's SLSA 3 generic generator.
+const generatorV02 = `{
+  "_type": "https://in-toto.io/Statement/v0.1",
+  "subject": [{"name": "payments-cli_linux_amd64", "digest": {"sha256": "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"}}],
+  "predicateType": "https://slsa.dev/provenance/v0.2",
+  "predicate": {
+    "builder": {"id": "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_generic_slsa3.yml@refs/tags/v1.10.0"},
+    "buildType": "https://github.com/slsa-framework/slsa-github-generator/generic@v1",
+    "invocation": {
+      "configSource": {
+        "uri": "git+https://github.com/acme-corp/payments-service@refs/tags/v2.4.1",
+        "digest": {"sha1": "a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2"},
+        "entryPoint": ".github/workflows/release.yml"
+      },
+      "environment": {"github_actor": "jane.doe", "github_event_name": "release", "github_run_id": "7890123456", "github_ref": "refs/tags/v2.4.1"}
+    },
+    "metadata": {"buildStartedOn": "2026-01-28T14:25:00Z", "buildFinishedOn": "2026-01-28T14:29:45Z"}
+  }
+}`
+
+// sbomStatement is a non-provenance attestation that the importer skips.
+const sbomStatement = `{"_type":"https://in-toto.io/Statement/v1","subject":[{"name":"x","digest":{"sha256":"ab"}}],"predicateType":"https://spdx.dev/Document/v2.3","predicate":{}}`
+
+func sigstoreBundleOf(statement string) string {
+	return `{
+  "mediaType": "application/vnd.dev.sigstore.bundle.v0.3+json",
+  "verificationMaterial": {"certificate": {"rawBytes": "MIIB"}},
+  "dsseEnvelope": {
+    "payload": "` + base64.StdEncoding.EncodeToString([]byte(statement)) + `",
+    "payloadType": "application/vnd.in-toto+json",
+    "signatures": [{"sig": "MEUCIQ=="}]
+  }
+}`
+}
+
+func TestFromProvenanceV1(t *testing.T) {
+	for name, input := range map[string]string{
+		"statement":           attestV1,
+		"sigstore bundle":     sigstoreBundleOf(attestV1),
+		"multiple documents":  sigstoreBundleOf(sbomStatement) + "\n" + sigstoreBundleOf(attestV1) + "\n",
+		"non-statement first": `{"pbom_version":"1.0.0"}` + "\n" + attestV1,
+	} {
+		t.Run(name, func(t *testing.T) {
+			pbom, err := FromProvenance([]byte(input), "")
+			if err != nil {
+				t.Fatalf("FromProvenance: %v", err)
+			}
+
+			want := schema.Source{
+				Repository: "acme-corp/payments-service",
+				CommitSHA:  "a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2",
+				Branch:     "main",
+				Ref:        "refs/heads/main",
+			}
+			if pbom.Source != want {
+				t.Errorf("Source = %+v, want %+v", pbom.Source, want)
+			}
+			b := pbom.Build
+			if b.WorkflowRunID != "7890123456" || b.WorkflowFile != ".github/workflows/release.yml" || b.Trigger != "push" {
+				t.Errorf("Build = %+v", b)
+			}
+			if b.Runner == nil || b.Runner.SelfHosted {
+				t.Errorf("Runner = %+v, want github-hosted", b.Runner)
+			}
+
+			if len(pbom.Artifacts) != 2 {
+				t.Fatalf("got %d artifacts, want 2", len(pbom.Artifacts))
+			}
+			img := pbom.Artifacts[0]
+			if img.Type != "container-image" || img.Name != "payments-service" ||
+				img.URI != "ghcr.io/acme-corp/payments-service@sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08" {
+				t.Errorf("image artifact = %+v", img)
+			}
+			if bin := pbom.Artifacts[1]; bin.Type != "other" || bin.Name != "payments-cli" || bin.URI != "" {
+				t.Errorf("file artifact = %+v", bin)
+			}
+			if p := img.Provenance; p == nil || p.BuilderID != "https://github.com/actions/runner/github-hosted" || p.SLSALevel != 2 {
+				t.Errorf("Provenance = %+v", p)
+			}
+		})
+	}
+}
+
+func TestFromProvenanceV02(t *testing.T) {
+	pbom, err := FromProvenance([]byte(generatorV02), "")
+	if err != nil {
+		t.Fatalf("FromProvenance: %v", err)
+	}
+	if pbom.Source.Ref != "refs/tags/v2.4.1" || pbom.Source.Branch != "" {
+		t.Errorf("Source = %+v", pbom.Source)
+	}
+	if pbom.Build.Actor != "jane.doe" || pbom.Build.Trigger != "release" || pbom.Build.WorkflowRunID != "7890123456" {
+		t.Errorf("Build = %+v", pbom.Build)
+	}
+	if pbom.Build.CompletedAt == nil {
+		t.Error("CompletedAt not imported")
+	}
+	if p := pbom.Artifacts[0].Provenance; p.SLSALevel != 3 {
+		t.Errorf("SLSALevel = %d, want 3", p.SLSALevel)
+	}
+}
+
+// TestExportImportRoundTrip imports what 'pbom export --format
+// slsa-provenance' produces.
+func TestExportImportRoundTrip(t *testing.T) {
+	orig := &schema.PBOM{
+		Source: schema.Source{Repository: "acme/app", CommitSHA: strings.Repeat("ab", 20), Ref: "refs/heads/main"},
+		Build: schema.Build{
+			WorkflowRunID: "42",
+			WorkflowFile:  ".github/workflows/ci.yml",
+			Trigger:       "push",
+			Actor:         "octocat",
+			Runner:        &schema.Runner{SelfHosted: true},
+		},
+		Artifacts: []schema.Artifact{{Name: "app", Type: "binary", Digest: "sha256:" + strings.Repeat("cd", 32)}},
+	}
+	st, err := export.ToSLSAProvenance(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(st)
+
+	got, err := FromProvenance(data, "")
+	if err != nil {
+		t.Fatalf("FromProvenance: %v", err)
+	}
+	if got.Source.Repository != "acme/app" || got.Source.CommitSHA != orig.Source.CommitSHA || got.Source.Branch != "main" {
+		t.Errorf("Source = %+v", got.Source)
+	}
+	if got.Build.WorkflowRunID != "42" || got.Build.Actor != "octocat" || !got.Build.Runner.SelfHosted {
+		t.Errorf("Build = %+v", got.Build)
+	}
+	if got.Artifacts[0].Digest != orig.Artifacts[0].Digest || got.Artifacts[0].Provenance.SLSALevel != 1 {
+		t.Errorf("Artifact = %+v", got.Artifacts[0])
+	}
+}
+
+func TestFromProvenanceErrors(t *testing.T) {
+	tests := map[string]string{
+		"not json":                 `nope`,
+		"not a statement":          `{"pbom_version":"1.0.0"}`,
+		"no subjects":              `{"_type":"https://in-toto.io/Statement/v1","subject":[],"predicateType":"https://slsa.dev/provenance/v1","predicate":{}}`,
+		"other predicate":          strings.Replace(attestV1, "https://slsa.dev/provenance/v1", "https://spdx.dev/Document", 1),
+		"no source commit":         `{"_type":"https://in-toto.io/Statement/v1","subject":[{"name":"x","digest":{"sha256":"ab"}}],"predicateType":"https://slsa.dev/provenance/v1","predicate":{}}`,
+		"bundle without envelope":  `{"mediaType":"application/vnd.dev.sigstore.bundle.v0.3+json","messageSignature":{}}`,
+		"no statement among many":  `{"pbom_version":"1.0.0"}` + "\n" + `{"mediaType":"application/vnd.dev.sigstore.bundle.v0.3+json","messageSignature":{}}`,
+		"no provenance among many": `{"pbom_version":"1.0.0"}` + "\n" + sbomStatement,
+	}
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := FromProvenance([]byte(input), ""); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestInferSLSALevel(t *testing.T) {
+	tests := []struct {
+		id, server string
+		want       int
+	}{
+		{"", "", 0},
+		{"https://github.com/actions/runner/github-hosted", "", 2},
+		{"https://github.com/actions/runner/self-hosted", "", 1},
+		{"https://github.com/slsa-framework/slsa-github-generator/.github/workflows/builder_go_slsa3.yml@refs/tags/v1.9.0", "", 3},
+		{"https://cloudbuild.googleapis.com/GoogleHostedWorker", "", 1},
+		{"https://ghe.example.com/actions/runner/github-hosted", "", 1},
+		{"https://ghe.example.com/actions/runner/github-hosted", "https://ghe.example.com", 2},
+		{"https://ghe.example.com/slsa-framework/slsa-github-generator/.github/workflows/builder_go_slsa3.yml@refs/tags/v1.9.0", "https://ghe.example.com/", 3},
+		{"https://github.com/actions/runner/github-hosted", "https://ghe.example.com", 2},
+	}
+	for _, tt := range tests {
+		if got := InferSLSALevel(tt.id, tt.server); got != tt.want {
+			t.Errorf("InferSLSALevel(%q, %q) = %d, want %d", tt.id, tt.server, got, tt.want)
+		}
+	}
+}
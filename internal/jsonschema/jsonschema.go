@@ -0,0 +1,410 @@
+// Package jsonschema validates JSON documents against a JSON Schema
+// (draft 2020-12). It implements the subset of keywords used by the PBOM
+// schema and refuses to compile schemas that use anything else, so an
+// unsupported constraint can never be silently ignored.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error is a single schema violation.
+type Error struct {
+	// Path is the JSON pointer (RFC 6901) of the offending value; "" is the
+	// document root.
+	Path string
+	// Keyword is the schema keyword that failed (e.g. "required", "enum").
+	Keyword string
+	Message string
+	// Property is the missing property of a "required" violation, whose
+	// Path is the object lacking it.
+	Property string
+}
+
+func (e Error) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "/"
+	}
+	return path + ": " + e.Message
+}
+
+// Schema is a compiled JSON Schema.
+type Schema struct {
+	root     map[string]any
+	patterns map[string]*regexp.Regexp
+}
+
+// annotationKeywords carry no validation semantics.
+var annotationKeywords = map[string]bool{
+	"$schema": true, "$id": true, "$defs": true, "$comment": true,
+	"title": true, "description": true, "default": true, "examples": true,
+	"deprecated": true, "readOnly": true, "writeOnly": true,
+}
+
+// assertionKeywords are the validation keywords this package implements.
+var assertionKeywords = map[string]bool{
+	"$ref": true, "type": true, "enum": true, "const": true,
+	"properties": true, "required": true, "additionalProperties": true,
+	"items": true, "minItems": true, "maxItems": true,
+	"minLength": true, "maxLength": true, "pattern": true, "format": true,
+	"minimum": true, "maximum": true, "exclusiveMinimum": true, "exclusiveMaximum": true,
+}
+
+// Compile parses a schema document.
+func Compile(data []byte) (*Schema, error) {
+	root, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing schema: %w", err)
+	}
+	obj, ok := root.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("schema root must be an object")
+	}
+
+	s := &Schema{root: obj, patterns: map[string]*regexp.Regexp{}}
+	if err := s.compile(obj, "#"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// MustCompile is like Compile but panics on error. Intended for embedded schemas.
+func MustCompile(data []byte) *Schema {
+	s, err := Compile(data)
+	if err != nil {
+		panic("jsonschema: " + err.Error())
+	}
+	return s
+}
+
+func (s *Schema) compile(node map[string]any, loc string) error {
+	for kw, v := range node {
+		if annotationKeywords[kw] {
+			continue
+		}
+		if !assertionKeywords[kw] {
+			return fmt.Errorf("%s: unsupported keyword %q", loc, kw)
+		}
+		switch kw {
+		case "$ref":
+			ref, _ := v.(string)
+			if _, err := s.resolve(ref); err != nil {
+				return fmt.Errorf("%s: %w", loc, err)
+			}
+		case "pattern":
+			p, _ := v.(string)
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return fmt.Errorf("%s: invalid pattern: %w", loc, err)
+			}
+			s.patterns[p] = re
+		case "format":
+			f, _ := v.(string)
+			if _, ok := formats[f]; !ok {
+				return fmt.Errorf("%s: unsupported format %q", loc, f)
+			}
+		}
+	}
+
+	for _, key := range []string{"properties", "$defs"} {
+		children, _ := node[key].(map[string]any)
+		for name, child := range children {
+			sub, ok := child.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s/%s/%s: schema must be an object", loc, key, name)
+			}
+			if err := s.compile(sub, loc+"/"+key+"/"+name); err != nil {
+				return err
+			}
+		}
+	}
+	for _, key := range []string{"items", "additionalProperties"} {
+		if sub, ok := node[key].(map[string]any); ok {
+			if err := s.compile(sub, loc+"/"+key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolve follows a local "#/..." reference.
+func (s *Schema) resolve(ref string) (map[string]any, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("only local $ref is supported, got %q", ref)
+	}
+	var node any = s.root
+	for _, tok := range strings.Split(strings.TrimPrefix(ref, "#"), "/")[1:] {
+		tok = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+		obj, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		if node, ok = obj[tok]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	obj, ok := node.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("$ref %q does not point at a schema", ref)
+	}
+	return obj, nil
+}
+
+// Validate checks a JSON document against the schema. It returns a parse
+// error for malformed JSON, otherwise every violation found, sorted by path.
+func (s *Schema) Validate(data []byte) ([]Error, error) {
+	doc, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	var errs []Error
+	s.validate(s.root, doc, "", &errs)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+	return errs, nil
+}
+
+func (s *Schema) validate(node map[string]any, v any, path string, errs *[]Error) {
+	fail := func(kw, format string, args ...any) {
+		*errs = append(*errs, Error{Path: path, Keyword: kw, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if ref, ok := node["$ref"].(string); ok {
+		target, _ := s.resolve(ref) // checked at compile time
+		s.validate(target, v, path, errs)
+	}
+
+	if t, ok := node["type"]; ok && !matchesType(t, v) {
+		fail("type", "expected %s, got %s", describeType(t), typeOf(v))
+		return
+	}
+
+	if c, ok := node["const"]; ok && !equal(c, v) {
+		fail("const", "must be %s", render(c))
+	}
+	if enum, ok := node["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if equal(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			allowed := make([]string, len(enum))
+			for i, e := range enum {
+				allowed[i] = render(e)
+			}
+			fail("enum", "%s is not one of [%s]", render(v), strings.Join(allowed, ", "))
+		}
+	}
+
+	switch val := v.(type) {
+	case string:
+		n := len([]rune(val))
+		if min, ok := intKeyword(node, "minLength"); ok && n < min {
+			fail("minLength", "must be at least %d characters", min)
+		}
+		if max, ok := intKeyword(node, "maxLength"); ok && n > max {
+			fail("maxLength", "must be at most %d characters", max)
+		}
+		if p, ok := node["pattern"].(string); ok && !s.patterns[p].MatchString(val) {
+			fail("pattern", "%q does not match pattern %q", val, p)
+		}
+		if f, ok := node["format"].(string); ok && !formats[f](val) {
+			fail("format", "%q is not a valid %s", val, f)
+		}
+
+	case json.Number:
+		x, _ := val.Float64()
+		if min, ok := numKeyword(node, "minimum"); ok && x < min {
+			fail("minimum", "must be >= %v", min)
+		}
+		if max, ok := numKeyword(node, "maximum"); ok && x > max {
+			fail("maximum", "must be <= %v", max)
+		}
+		if min, ok := numKeyword(node, "exclusiveMinimum"); ok && x <= min {
+			fail("exclusiveMinimum", "must be > %v", min)
+		}
+		if max, ok := numKeyword(node, "exclusiveMaximum"); ok && x >= max {
+			fail("exclusiveMaximum", "must be < %v", max)
+		}
+
+	case []any:
+		if min, ok := intKeyword(node, "minItems"); ok && len(val) < min {
+			fail("minItems", "must have at least %d items", min)
+		}
+		if max, ok := intKeyword(node, "maxItems"); ok && len(val) > max {
+			fail("maxItems", "must have at most %d items", max)
+		}
+		if items, ok := node["items"].(map[string]any); ok {
+			for i, item := range val {
+				s.validate(items, item, path+"/"+strconv.Itoa(i), errs)
+			}
+		}
+
+	case map[string]any:
+		if required, ok := node["required"].([]any); ok {
+			for _, r := range required {
+				name, _ := r.(string)
+				if _, present := val[name]; !present {
+					fail("required", "missing required property %q", name)
+					(*errs)[len(*errs)-1].Property = name
+				}
+			}
+		}
+		props, _ := node["properties"].(map[string]any)
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := path + "/" + escape(k)
+			if sub, ok := props[k].(map[string]any); ok {
+				s.validate(sub, val[k], child, errs)
+				continue
+			}
+			switch ap := node["additionalProperties"].(type) {
+			case bool:
+				if !ap {
+					*errs = append(*errs, Error{Path: child, Keyword: "additionalProperties", Message: "unknown property"})
+				}
+			case map[string]any:
+				s.validate(ap, val[k], child, errs)
+			}
+		}
+	}
+}
+
+var (
+	uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+	formats = map[string]func(string) bool{
+		"uuid": uuidPattern.MatchString,
+		"date-time": func(s string) bool {
+			_, err := time.Parse(time.RFC3339Nano, s)
+			return err == nil
+		},
+		"uri": func(s string) bool {
+			scheme, rest, ok := strings.Cut(s, ":")
+			return ok && scheme != "" && rest != ""
+		},
+	}
+)
+
+func decode(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after top-level value")
+	}
+	return v, nil
+}
+
+func typeOf(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if isInteger(val) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func isInteger(n json.Number) bool {
+	f, err := n.Float64()
+	return err == nil && f == math.Trunc(f)
+}
+
+func matchesType(t any, v any) bool {
+	actual := typeOf(v)
+	check := func(name string) bool {
+		return name == actual || (name == "number" && actual == "integer")
+	}
+	switch tt := t.(type) {
+	case string:
+		return check(tt)
+	case []any:
+		for _, x := range tt {
+			if name, _ := x.(string); check(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func describeType(t any) string {
+	if list, ok := t.([]any); ok {
+		names := make([]string, len(list))
+		for i, x := range list {
+			names[i], _ = x.(string)
+		}
+		return strings.Join(names, " or ")
+	}
+	s, _ := t.(string)
+	return s
+}
+
+func equal(a, b any) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	if na, ok := a.(json.Number); ok {
+		if nb, ok := b.(json.Number); ok {
+			fa, _ := na.Float64()
+			fb, _ := nb.Float64()
+			return fa == fb
+		}
+	}
+	return bytes.Equal(ja, jb)
+}
+
+func render(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func intKeyword(node map[string]any, kw string) (int, bool) {
+	f, ok := numKeyword(node, kw)
+	return int(f), ok
+}
+
+func numKeyword(node map[string]any, kw string) (float64, bool) {
+	n, ok := node[kw].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil
+}
+
+// escape encodes a property name as a JSON pointer reference token.
+func escape(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,106 @@
+package jsonschema
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["id", "kind"],
+  "properties": {
+    "id": {"type": "string", "format": "uuid"},
+    "kind": {"type": "string", "enum": ["a", "b"]},
+    "version": {"const": "1.0.0"},
+    "at": {"type": "string", "format": "date-time"},
+    "level": {"type": "integer", "minimum": 0, "maximum": 4},
+    "sha": {"type": "string", "pattern": "^[0-9a-f]{4}$"},
+    "items": {"type": "array", "items": {"$ref": "#/$defs/item"}},
+    "labels": {"type": "object", "additionalProperties": {"type": "string"}}
+  },
+  "$defs": {
+    "item": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["name"],
+      "properties": {"name": {"type": "string"}}
+    }
+  }
+}`
+
+func TestValidate(t *testing.T) {
+	s := MustCompile([]byte(testSchema))
+	const id = `"id": "f47ac10b-58cc-4372-a567-0e02b2c3d479"`
+
+	tests := []struct {
+		name     string
+		doc      string
+		wantPath string
+		wantKw   string
+	}{
+		{"valid", `{` + id + `, "kind": "a", "level": 2, "items": [{"name": "x"}], "labels": {"k": "v"}}`, "", ""},
+		{"missing required", `{` + id + `}`, "", "required"},
+		{"bad uuid", `{"id": "fallback-1", "kind": "a"}`, "/id", "format"},
+		{"enum", `{` + id + `, "kind": "c"}`, "/kind", "enum"},
+		{"const", `{` + id + `, "kind": "a", "version": "2.0.0"}`, "/version", "const"},
+		{"date-time", `{` + id + `, "kind": "a", "at": "yesterday"}`, "/at", "format"},
+		{"integer type", `{` + id + `, "kind": "a", "level": 1.5}`, "/level", "type"},
+		{"maximum", `{` + id + `, "kind": "a", "level": 5}`, "/level", "maximum"},
+		{"pattern", `{` + id + `, "kind": "a", "sha": "XYZ"}`, "/sha", "pattern"},
+		{"unknown field", `{` + id + `, "kind": "a", "extra": true}`, "/extra", "additionalProperties"},
+		{"ref item", `{` + id + `, "kind": "a", "items": [{"name": "x"}, {"nme": "y"}]}`, "/items/1", "required"},
+		{"map values", `{` + id + `, "kind": "a", "labels": {"a/b": 1}}`, "/labels/a~1b", "type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, err := s.Validate([]byte(tt.doc))
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if tt.wantKw == "" {
+				if len(errs) != 0 {
+					t.Fatalf("expected no errors, got %v", errs)
+				}
+				return
+			}
+			for _, e := range errs {
+				if e.Path == tt.wantPath && e.Keyword == tt.wantKw {
+					return
+				}
+			}
+			t.Errorf("expected %s error at %q, got %v", tt.wantKw, tt.wantPath, errs)
+		})
+	}
+}
+
+func TestValidateMalformedJSON(t *testing.T) {
+	s := MustCompile([]byte(testSchema))
+	if _, err := s.Validate([]byte(`{"id":`)); err == nil {
+		t.Error("expected error for malformed JSON")
+	}
+}
+
+func TestCompileRejectsUnsupportedKeywords(t *testing.T) {
+	tests := []string{
+		`{"type": "object", "oneOf": []}`,
+		`{"properties": {"a": {"format": "ipv6"}}}`,
+		`{"$ref": "https://example.com/other.json"}`,
+		`{"$ref": "#/$defs/missing"}`,
+		`{"pattern": "("}`,
+	}
+	for _, schema := range tests {
+		if _, err := Compile([]byte(schema)); err == nil {
+			t.Errorf("Compile(%s) succeeded, want error", schema)
+		}
+	}
+}
+
+func TestErrorString(t *testing.T) {
+	e := Error{Path: "", Message: `missing required property "id"`}
+	if !strings.HasPrefix(e.Error(), "/: ") {
+		t.Errorf("root error = %q, want prefix %q", e.Error(), "/: ")
+	}
+}
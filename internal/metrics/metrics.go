@@ -0,0 +1,332 @@
+// Package metrics collects counters, histograms and gauges and serves them
+// in the Prometheus text exposition format (version 0.0.4). It implements
+// only what the webhook server needs: labelled counters and histograms,
+// and gauges computed at scrape time.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContentType is the media type of the exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are histogram upper bounds in seconds, suited to request
+// and API call latencies.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// metric is a registered metric family.
+type metric interface {
+	name() string
+	write(ctx context.Context, w io.Writer) error
+}
+
+// Registry holds metric families and writes them in registration order.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	names   map[string]bool
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[m.name()] {
+		panic(fmt.Sprintf("metrics: duplicate metric %s", m.name()))
+	}
+	r.names[m.name()] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteTo writes every metric in the exposition format.
+func (r *Registry) WriteTo(ctx context.Context, w io.Writer) error {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	for _, m := range metrics {
+		if err := m.write(ctx, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler serves the registry's metrics.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		r.WriteTo(req.Context(), w)
+	})
+}
+
+// vec maps label values to the state of one series.
+type vec[T any] struct {
+	metricName string
+	help       string
+	kind       string
+	labelNames []string
+
+	mu     sync.Mutex
+	series map[string]*T
+	keys   map[string][]string
+	newT   func() *T
+}
+
+func newVec[T any](name, help, kind string, labels []string, newT func() *T) *vec[T] {
+	return &vec[T]{
+		metricName: name,
+		help:       help,
+		kind:       kind,
+		labelNames: labels,
+		series:     make(map[string]*T),
+		keys:       make(map[string][]string),
+		newT:       newT,
+	}
+}
+
+func (v *vec[T]) name() string { return v.metricName }
+
+// get returns the series for the label values, creating it on first use.
+// The caller must hold v.mu.
+func (v *vec[T]) get(values []string) *T {
+	if len(values) != len(v.labelNames) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", v.metricName, len(v.labelNames), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = v.newT()
+		v.series[key] = s
+		v.keys[key] = append([]string(nil), values...)
+	}
+	return s
+}
+
+// sorted returns the series keys in a stable order.
+func (v *vec[T]) sorted() []string {
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (v *vec[T]) header(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.metricName, escapeHelp(v.help), v.metricName, v.kind)
+	return err
+}
+
+// Counter is a family of monotonically increasing counters.
+type Counter struct {
+	*vec[float64]
+}
+
+// NewCounter registers a counter with the given label names.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{newVec(name, help, "counter", labels, func() *float64 { return new(float64) })}
+	r.register(c)
+	return c
+}
+
+// Inc adds one to the series with the given label values.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds delta, which must not be negative, to a series.
+func (c *Counter) Add(delta float64, values ...string) {
+	if delta < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.mu.Lock()
+	*c.get(values) += delta
+	c.mu.Unlock()
+}
+
+// Value returns the current value of a series.
+func (c *Counter) Value(values ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return *c.get(values)
+}
+
+func (c *Counter) write(_ context.Context, w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.header(w); err != nil {
+		return err
+	}
+	if len(c.labelNames) == 0 && len(c.series) == 0 {
+		c.get(nil) // an unlabelled counter is always exposed
+	}
+	for _, k := range c.sorted() {
+		if err := writeSample(w, c.metricName, c.labelNames, c.keys[k], "", "", *c.series[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Histogram is a family of histograms with shared bucket bounds.
+type Histogram struct {
+	*vec[histogramState]
+	buckets []float64
+}
+
+type histogramState struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram registers a histogram. Nil buckets use DefaultBuckets.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &Histogram{buckets: buckets}
+	h.vec = newVec(name, help, "histogram", labels, func() *histogramState {
+		return &histogramState{counts: make([]uint64, len(buckets))}
+	})
+	r.register(h)
+	return h
+}
+
+// Observe records a value in the series with the given label values.
+func (h *Histogram) Observe(value float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.get(values)
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += value
+}
+
+// Since observes the seconds elapsed since start.
+func (h *Histogram) Since(start time.Time, values ...string) {
+	h.Observe(time.Since(start).Seconds(), values...)
+}
+
+func (h *Histogram) write(_ context.Context, w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.header(w); err != nil {
+		return err
+	}
+	for _, k := range h.sorted() {
+		s, values := h.series[k], h.keys[k]
+		var cumulative uint64
+		for i, le := range h.buckets {
+			cumulative += s.counts[i]
+			if err := writeSample(w, h.metricName+"_bucket", h.labelNames, values, "le", formatFloat(le), float64(cumulative)); err != nil {
+				return err
+			}
+		}
+		if err := writeSample(w, h.metricName+"_bucket", h.labelNames, values, "le", "+Inf", float64(s.count)); err != nil {
+			return err
+		}
+		if err := writeSample(w, h.metricName+"_sum", h.labelNames, values, "", "", s.sum); err != nil {
+			return err
+		}
+		if err := writeSample(w, h.metricName+"_count", h.labelNames, values, "", "", float64(s.count)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GaugeFunc is a gauge whose value is computed at scrape time.
+type GaugeFunc struct {
+	metricName string
+	help       string
+	fn         func(ctx context.Context) (float64, error)
+}
+
+// NewGaugeFunc registers a gauge computed by fn on every scrape. If fn
+// fails the sample is omitted from that scrape.
+func (r *Registry) NewGaugeFunc(name, help string, fn func(ctx context.Context) (float64, error)) *GaugeFunc {
+	g := &GaugeFunc{metricName: name, help: help, fn: fn}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeFunc) name() string { return g.metricName }
+
+func (g *GaugeFunc) write(ctx context.Context, w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.metricName, escapeHelp(g.help), g.metricName); err != nil {
+		return err
+	}
+	v, err := g.fn(ctx)
+	if err != nil {
+		return nil
+	}
+	return writeSample(w, g.metricName, nil, nil, "", "", v)
+}
+
+// writeSample writes one sample line. extraName, if set, is appended to
+// the labels (used for a histogram's "le").
+func writeSample(w io.Writer, name string, labelNames, values []string, extraName, extraValue string, v float64) error {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labelNames) > 0 || extraName != "" {
+		b.WriteByte('{')
+		for i, l := range labelNames {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, "%s=\"%s\"", l, escapeLabel(values[i]))
+		}
+		if extraName != "" {
+			if len(labelNames) > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, "%s=\"%s\"", extraName, extraValue)
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(v))
+	b.WriteByte('\n')
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryExposition(t *testing.T) {
+	r := NewRegistry()
+	deliveries := r.NewCounter("deliveries_total", "Deliveries received.", "event", "outcome")
+	r.NewCounter("signature_failures_total", "Bad signatures.")
+	duration := r.NewHistogram("step_seconds", "Step duration.", []float64{1, 0.1}, "step")
+	r.NewGaugeFunc("queue_depth", "Pending jobs.", func(context.Context) (float64, error) { return 3, nil })
+	r.NewGaugeFunc("broken", "Always fails.", func(context.Context) (float64, error) { return 0, errors.New("down") })
+
+	deliveries.Inc("workflow_run", "accepted")
+	deliveries.Inc("workflow_run", "accepted")
+	deliveries.Inc("push", `odd"value`)
+	duration.Observe(0.05, "jobs")
+	duration.Observe(0.5, "jobs")
+	duration.Observe(7, "jobs")
+
+	if got := deliveries.Value("workflow_run", "accepted"); got != 2 {
+		t.Errorf("Value = %v, want 2", got)
+	}
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	want := `# HELP deliveries_total Deliveries received.
+# TYPE deliveries_total counter
+deliveries_total{event="push",outcome="odd\"value"} 1
+deliveries_total{event="workflow_run",outcome="accepted"} 2
+# HELP signature_failures_total Bad signatures.
+# TYPE signature_failures_total counter
+signature_failures_total 0
+# HELP step_seconds Step duration.
+# TYPE step_seconds histogram
+step_seconds_bucket{step="jobs",le="0.1"} 1
+step_seconds_bucket{step="jobs",le="1"} 2
+step_seconds_bucket{step="jobs",le="+Inf"} 3
+step_seconds_sum{step="jobs"} 7.55
+step_seconds_count{step="jobs"} 3
+# HELP queue_depth Pending jobs.
+# TYPE queue_depth gauge
+queue_depth 3
+# HELP broken Always fails.
+# TYPE broken gauge
+`
+	if got := rec.Body.String(); got != want {
+		t.Errorf("exposition:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRegistryMisuse(t *testing.T) {
+	tests := map[string]func(r *Registry){
+		"duplicate name": func(r *Registry) {
+			r.NewCounter("x", "")
+			r.NewCounter("x", "")
+		},
+		"wrong label count": func(r *Registry) {
+			r.NewCounter("x", "", "a").Inc()
+		},
+		"negative add": func(r *Registry) {
+			r.NewCounter("x", "").Add(-1)
+		},
+	}
+	for name, fn := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if v := recover(); v == nil || !strings.HasPrefix(v.(string), "metrics:") {
+					t.Errorf("recover() = %v, want a metrics panic", v)
+				}
+			}()
+			fn(NewRegistry())
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package oci
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Credential is a username/password pair (or identity token) for a registry.
+type Credential struct {
+	Username      string
+	Password      string
+	IdentityToken string
+}
+
+// DockerConfig holds registry credentials loaded from a Docker config file.
+// Only inline credentials ("auths") are supported; credential helpers
+// (credsStore / credHelpers) are ignored.
+type DockerConfig struct {
+	Auths map[string]dockerAuth `json:"auths"`
+}
+
+type dockerAuth struct {
+	Auth          string `json:"auth"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	IdentityToken string `json:"identitytoken"`
+}
+
+// DefaultDockerConfigPath returns $DOCKER_CONFIG/config.json if set,
+// otherwise ~/.docker/config.json.
+func DefaultDockerConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// LoadDockerConfig reads a Docker config file. A missing file yields an
+// empty config so anonymous pulls and pushes still work.
+func LoadDockerConfig(path string) (*DockerConfig, error) {
+	cfg := &DockerConfig{}
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading docker config: %w", err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing docker config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Lookup returns the credential for a registry host, or nil if none is
+// configured. Docker Hub entries are commonly keyed by their legacy URL.
+func (c *DockerConfig) Lookup(registry string) (*Credential, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	candidates := []string{registry, "https://" + registry, "http://" + registry}
+	if registry == dockerHubRegistry || registry == dockerHubAPIHost {
+		candidates = append(candidates, "https://index.docker.io/v1/", "index.docker.io")
+	}
+
+	for _, key := range candidates {
+		entry, ok := c.Auths[key]
+		if !ok {
+			continue
+		}
+		cred := &Credential{
+			Username:      entry.Username,
+			Password:      entry.Password,
+			IdentityToken: entry.IdentityToken,
+		}
+		if entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("decoding auth for %s: %w", key, err)
+			}
+			user, pass, ok := strings.Cut(string(decoded), ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid auth for %s: expected user:password", key)
+			}
+			cred.Username, cred.Password = user, pass
+		}
+		return cred, nil
+	}
+
+	return nil, nil
+}
+
+// challenge is a parsed WWW-Authenticate header.
+type challenge struct {
+	Scheme string
+	Params map[string]string
+}
+
+// parseChallenge parses headers of the form
+// `Bearer realm="https://auth.example.com/token",service="registry",scope="repository:foo:pull"`.
+func parseChallenge(header string) challenge {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	ch := challenge{Scheme: strings.ToLower(scheme), Params: map[string]string{}}
+
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ,")
+		key, after, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		var value string
+		if strings.HasPrefix(after, `"`) {
+			end := strings.Index(after[1:], `"`)
+			if end < 0 {
+				value, rest = after[1:], ""
+			} else {
+				value, rest = after[1:end+1], after[end+2:]
+			}
+		} else {
+			value, rest, _ = strings.Cut(after, ",")
+		}
+		ch.Params[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+
+	return ch
+}
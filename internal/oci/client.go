@@ -0,0 +1,437 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Media types used by PBOM referrer artifacts.
+const (
+	MediaTypeImageManifest  = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeImageIndex     = "application/vnd.oci.image.index.v1+json"
+	MediaTypeEmptyJSON      = "application/vnd.oci.empty.v1+json"
+	MediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+// maxManifestSize caps manifest and index reads (the distribution spec
+// recommends registries accept at least 4MiB).
+const maxManifestSize = 4 << 20
+
+// ErrNotFound is returned when the registry responds 404 for a manifest or blob.
+var ErrNotFound = errors.New("not found")
+
+// Descriptor describes content stored in a registry.
+type Descriptor struct {
+	MediaType    string            `json:"mediaType"`
+	Digest       string            `json:"digest"`
+	Size         int64             `json:"size"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	Data         []byte            `json:"data,omitempty"`
+}
+
+// Manifest is an OCI image manifest.
+type Manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        Descriptor        `json:"config"`
+	Layers        []Descriptor      `json:"layers"`
+	Subject       *Descriptor       `json:"subject,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// Index is an OCI image index, also used as the referrers list format.
+type Index struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	Manifests     []Descriptor      `json:"manifests"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// Client talks to OCI Distribution registries.
+type Client struct {
+	httpClient *http.Client
+	creds      *DockerConfig
+	plainHTTP  bool
+
+	mu     sync.Mutex
+	tokens map[string]string // host + scope → Authorization header value
+}
+
+// NewClient creates a registry client. creds may be nil for anonymous
+// access. plainHTTP talks to the registry over http instead of https
+// (for local test registries).
+func NewClient(creds *DockerConfig, plainHTTP bool) *Client {
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+		creds:     creds,
+		plainHTTP: plainHTTP,
+		tokens:    make(map[string]string),
+	}
+}
+
+// DigestOf returns the sha256 digest string of data.
+func DigestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Resolve looks up the manifest descriptor a reference points at.
+func (c *Client) Resolve(ctx context.Context, ref Reference) (Descriptor, error) {
+	header := http.Header{}
+	header.Set("Accept", strings.Join([]string{
+		MediaTypeImageManifest, MediaTypeImageIndex,
+		MediaTypeDockerManifest, MediaTypeDockerList,
+	}, ", "))
+
+	path := fmt.Sprintf("/v2/%s/manifests/%s", ref.Repository, ref.Identifier())
+	resp, err := c.do(ctx, ref, http.MethodHead, path, nil, header, "pull")
+	if err != nil {
+		return Descriptor{}, err
+	}
+	resp.Body.Close()
+	if err := checkStatus(resp, path, nil); err != nil {
+		return Descriptor{}, err
+	}
+
+	desc := Descriptor{
+		MediaType: resp.Header.Get("Content-Type"),
+		Digest:    resp.Header.Get("Docker-Content-Digest"),
+		Size:      resp.ContentLength,
+	}
+	if desc.Digest != "" && desc.Size >= 0 {
+		return desc, nil
+	}
+
+	// Some registries omit the digest on HEAD; fall back to a full GET.
+	_, desc, err = c.FetchManifest(ctx, ref, ref.Identifier())
+	return desc, err
+}
+
+// FetchManifest downloads a manifest or index by tag or digest.
+func (c *Client) FetchManifest(ctx context.Context, ref Reference, identifier string) ([]byte, Descriptor, error) {
+	header := http.Header{}
+	header.Set("Accept", strings.Join([]string{
+		MediaTypeImageManifest, MediaTypeImageIndex,
+		MediaTypeDockerManifest, MediaTypeDockerList,
+	}, ", "))
+
+	path := fmt.Sprintf("/v2/%s/manifests/%s", ref.Repository, identifier)
+	resp, err := c.do(ctx, ref, http.MethodGet, path, nil, header, "pull")
+	if err != nil {
+		return nil, Descriptor{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+	if err != nil {
+		return nil, Descriptor{}, fmt.Errorf("reading manifest: %w", err)
+	}
+	if err := checkStatus(resp, path, body); err != nil {
+		return nil, Descriptor{}, err
+	}
+	if len(body) > maxManifestSize {
+		return nil, Descriptor{}, fmt.Errorf("manifest %s exceeds %d bytes", identifier, maxManifestSize)
+	}
+
+	desc := Descriptor{
+		MediaType: resp.Header.Get("Content-Type"),
+		Digest:    DigestOf(body),
+		Size:      int64(len(body)),
+	}
+	if strings.HasPrefix(identifier, "sha256:") && identifier != desc.Digest {
+		return nil, Descriptor{}, fmt.Errorf("manifest digest mismatch: requested %s, got %s", identifier, desc.Digest)
+	}
+	return body, desc, nil
+}
+
+// FetchBlob downloads a blob and verifies its digest. Blobs larger than
+// maxSize bytes are rejected.
+func (c *Client) FetchBlob(ctx context.Context, ref Reference, digest string, maxSize int64) ([]byte, error) {
+	path := fmt.Sprintf("/v2/%s/blobs/%s", ref.Repository, digest)
+	resp, err := c.do(ctx, ref, http.MethodGet, path, nil, nil, "pull")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading blob: %w", err)
+	}
+	if err := checkStatus(resp, path, body); err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxSize {
+		return nil, fmt.Errorf("blob %s exceeds %d bytes", digest, maxSize)
+	}
+	if got := DigestOf(body); got != digest {
+		return nil, fmt.Errorf("blob digest mismatch: expected %s, got %s", digest, got)
+	}
+	return body, nil
+}
+
+// PushBlob uploads data as a blob unless the registry already has it.
+func (c *Client) PushBlob(ctx context.Context, ref Reference, mediaType string, data []byte) (Descriptor, error) {
+	desc := Descriptor{MediaType: mediaType, Digest: DigestOf(data), Size: int64(len(data))}
+
+	path := fmt.Sprintf("/v2/%s/blobs/%s", ref.Repository, desc.Digest)
+	resp, err := c.do(ctx, ref, http.MethodHead, path, nil, nil, "pull,push")
+	if err != nil {
+		return Descriptor{}, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return desc, nil
+	}
+
+	// Start a monolithic upload: POST for a session, then PUT the bytes.
+	path = fmt.Sprintf("/v2/%s/blobs/uploads/", ref.Repository)
+	resp, err = c.do(ctx, ref, http.MethodPost, path, nil, nil, "pull,push")
+	if err != nil {
+		return Descriptor{}, err
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	if err := checkStatus(resp, path, body); err != nil {
+		return Descriptor{}, err
+	}
+
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return Descriptor{}, fmt.Errorf("registry did not return an upload location")
+	}
+	uploadURL, err := c.endpoint(ref).Parse(location)
+	if err != nil {
+		return Descriptor{}, fmt.Errorf("parsing upload location %q: %w", location, err)
+	}
+	q := uploadURL.Query()
+	q.Set("digest", desc.Digest)
+	uploadURL.RawQuery = q.Encode()
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/octet-stream")
+	resp, err = c.do(ctx, ref, http.MethodPut, uploadURL.String(), data, header, "pull,push")
+	if err != nil {
+		return Descriptor{}, err
+	}
+	body, _ = io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	if err := checkStatus(resp, "blob upload", body); err != nil {
+		return Descriptor{}, err
+	}
+
+	return desc, nil
+}
+
+// PushManifest uploads a manifest under a tag or digest. It returns the
+// manifest descriptor and the OCI-Subject response header, which registries
+// implementing the Referrers API set when they processed the subject field.
+func (c *Client) PushManifest(ctx context.Context, ref Reference, identifier, mediaType string, data []byte) (Descriptor, string, error) {
+	desc := Descriptor{MediaType: mediaType, Digest: DigestOf(data), Size: int64(len(data))}
+
+	header := http.Header{}
+	header.Set("Content-Type", mediaType)
+	path := fmt.Sprintf("/v2/%s/manifests/%s", ref.Repository, identifier)
+	resp, err := c.do(ctx, ref, http.MethodPut, path, data, header, "pull,push")
+	if err != nil {
+		return Descriptor{}, "", err
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	if err := checkStatus(resp, path, body); err != nil {
+		return Descriptor{}, "", err
+	}
+
+	return desc, resp.Header.Get("OCI-Subject"), nil
+}
+
+// endpoint returns the base URL of the registry API.
+func (c *Client) endpoint(ref Reference) *url.URL {
+	scheme := "https"
+	if c.plainHTTP {
+		scheme = "http"
+	}
+	return &url.URL{Scheme: scheme, Host: ref.apiHost()}
+}
+
+// do performs a registry request, answering a 401 challenge once with
+// Basic or Bearer credentials. target is either an API path or an
+// absolute URL (upload locations). actions is the repository scope
+// ("pull" or "pull,push") requested when exchanging for a bearer token.
+func (c *Client) do(ctx context.Context, ref Reference, method, target string, body []byte, header http.Header, actions string) (*http.Response, error) {
+	u, err := c.endpoint(ref).Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("building URL: %w", err)
+	}
+	scope := fmt.Sprintf("repository:%s:%s", ref.Repository, actions)
+	cacheKey := u.Host + " " + scope
+
+	send := func(authorization string) (*http.Response, error) {
+		var rd io.Reader
+		if body != nil {
+			rd = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, u.String(), rd)
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		for k, vs := range header {
+			req.Header[k] = vs
+		}
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("executing request: %w", err)
+		}
+		return resp, nil
+	}
+
+	c.mu.Lock()
+	authorization := c.tokens[cacheKey]
+	c.mu.Unlock()
+
+	resp, err := send(authorization)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	authorization, err = c.authorize(ctx, ref, parseChallenge(resp.Header.Get("WWW-Authenticate")), scope)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.tokens[cacheKey] = authorization
+	c.mu.Unlock()
+
+	return send(authorization)
+}
+
+// authorize answers an authentication challenge.
+func (c *Client) authorize(ctx context.Context, ref Reference, ch challenge, scope string) (string, error) {
+	cred, err := c.creds.Lookup(ref.Registry)
+	if err != nil {
+		return "", err
+	}
+
+	switch ch.Scheme {
+	case "basic":
+		if cred == nil {
+			return "", fmt.Errorf("registry %s requires credentials", ref.Registry)
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(cred.Username+":"+cred.Password)), nil
+	case "bearer":
+		token, err := c.fetchToken(ctx, ch, scope, cred)
+		if err != nil {
+			return "", fmt.Errorf("fetching registry token: %w", err)
+		}
+		return "Bearer " + token, nil
+	default:
+		return "", fmt.Errorf("unsupported auth challenge %q from %s", ch.Scheme, ref.Registry)
+	}
+}
+
+// fetchToken exchanges credentials for a bearer token at the challenge realm.
+func (c *Client) fetchToken(ctx context.Context, ch challenge, scope string, cred *Credential) (string, error) {
+	realm := ch.Params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("bearer challenge missing realm")
+	}
+	if s := ch.Params["scope"]; s != "" {
+		scope = s
+	}
+
+	var req *http.Request
+	var err error
+	if cred != nil && cred.IdentityToken != "" {
+		form := url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {cred.IdentityToken},
+			"service":       {ch.Params["service"]},
+			"scope":         {scope},
+			"client_id":     {"pbom"},
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, realm, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		u, err := url.Parse(realm)
+		if err != nil {
+			return "", fmt.Errorf("parsing realm %q: %w", realm, err)
+		}
+		q := u.Query()
+		if svc := ch.Params["service"]; svc != "" {
+			q.Set("service", svc)
+		}
+		q.Set("scope", scope)
+		u.RawQuery = q.Encode()
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return "", err
+		}
+		if cred != nil {
+			req.SetBasicAuth(cred.Username, cred.Password)
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	var tok struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return "", fmt.Errorf("parsing token response: %w", err)
+	}
+	if tok.Token != "" {
+		return tok.Token, nil
+	}
+	if tok.AccessToken != "" {
+		return tok.AccessToken, nil
+	}
+	return "", fmt.Errorf("token response contained no token")
+}
+
+func checkStatus(resp *http.Response, what string, body []byte) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("registry %s: %w", what, ErrNotFound)
+	}
+	return fmt.Errorf("registry %s returned %d: %s", what, resp.StatusCode, strings.TrimSpace(string(body)))
+}
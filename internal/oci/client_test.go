@@ -0,0 +1,234 @@
+package oci
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testArtifactType = "application/vnd.pbom.v1+json"
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Reference
+		wantErr bool
+	}{
+		{"ghcr.io/acme/app:v1", Reference{Registry: "ghcr.io", Repository: "acme/app", Tag: "v1"}, false},
+		{"ghcr.io/acme/app@sha256:abc", Reference{Registry: "ghcr.io", Repository: "acme/app", Digest: "sha256:abc"}, false},
+		{"ghcr.io/acme/app:v1@sha256:abc", Reference{Registry: "ghcr.io", Repository: "acme/app", Tag: "v1", Digest: "sha256:abc"}, false},
+		{"localhost:5000/app", Reference{Registry: "localhost:5000", Repository: "app", Tag: "latest"}, false},
+		{"alpine:3.20", Reference{Registry: "docker.io", Repository: "library/alpine", Tag: "3.20"}, false},
+		{"acme/app", Reference{Registry: "docker.io", Repository: "acme/app", Tag: "latest"}, false},
+		{"ghcr.io/Acme/App:v1", Reference{}, true},
+		{"ghcr.io/acme/app@nodigest", Reference{}, true},
+		{"", Reference{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseReference(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseReference(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseReference(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttachWithReferrersAPI(t *testing.T) {
+	reg, _, host := newTestRegistry(t, true)
+	subjectDigest := reg.putImage("acme/app", "v1")
+
+	client := NewClient(nil, true)
+	ref, _ := ParseReference(host + "/acme/app:v1")
+	payload := []byte(`{"pbom_version":"1.0.0"}`)
+
+	desc, err := client.Attach(context.Background(), ref, testArtifactType, "pbom.json", payload, map[string]string{"dev.pbom.id": "abc"})
+	if err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(reg.manifests[desc.Digest], &m); err != nil {
+		t.Fatalf("stored manifest: %v", err)
+	}
+	if m.ArtifactType != testArtifactType {
+		t.Errorf("artifactType = %q, want %q", m.ArtifactType, testArtifactType)
+	}
+	if m.Subject == nil || m.Subject.Digest != subjectDigest {
+		t.Errorf("subject = %+v, want digest %s", m.Subject, subjectDigest)
+	}
+	if len(m.Layers) != 1 || string(reg.blobs[m.Layers[0].Digest]) != string(payload) {
+		t.Errorf("payload layer not stored correctly: %+v", m.Layers)
+	}
+	if _, ok := reg.tags["acme/app:"+referrersTag(subjectDigest)]; ok {
+		t.Error("tag-schema index written although Referrers API is available")
+	}
+
+	refs, err := client.Referrers(context.Background(), ref, testArtifactType)
+	if err != nil {
+		t.Fatalf("Referrers: %v", err)
+	}
+	if len(refs) != 1 || refs[0].Digest != desc.Digest || refs[0].Annotations["dev.pbom.id"] != "abc" {
+		t.Errorf("Referrers = %+v, want single %s", refs, desc.Digest)
+	}
+}
+
+func TestAttachFallsBackToTagSchema(t *testing.T) {
+	reg, _, host := newTestRegistry(t, false)
+	subjectDigest := reg.putImage("acme/app", "v1")
+
+	client := NewClient(nil, true)
+	ref, _ := ParseReference(host + "/acme/app@" + subjectDigest)
+
+	first, err := client.Attach(context.Background(), ref, testArtifactType, "a.json", []byte(`{"n":1}`), nil)
+	if err != nil {
+		t.Fatalf("Attach first: %v", err)
+	}
+	second, err := client.Attach(context.Background(), ref, testArtifactType, "b.json", []byte(`{"n":2}`), nil)
+	if err != nil {
+		t.Fatalf("Attach second: %v", err)
+	}
+
+	indexDigest, ok := reg.tags["acme/app:"+referrersTag(subjectDigest)]
+	if !ok {
+		t.Fatal("tag-schema referrers index was not written")
+	}
+	var index Index
+	if err := json.Unmarshal(reg.manifests[indexDigest], &index); err != nil {
+		t.Fatalf("parsing index: %v", err)
+	}
+	if len(index.Manifests) != 2 {
+		t.Fatalf("index has %d manifests, want 2", len(index.Manifests))
+	}
+
+	refs, err := client.Referrers(context.Background(), ref, testArtifactType)
+	if err != nil {
+		t.Fatalf("Referrers: %v", err)
+	}
+	got := map[string]bool{}
+	for _, d := range refs {
+		got[d.Digest] = true
+	}
+	if !got[first.Digest] || !got[second.Digest] {
+		t.Errorf("Referrers = %+v, want both %s and %s", refs, first.Digest, second.Digest)
+	}
+}
+
+func TestAttachWithBasicAuthFromDockerConfig(t *testing.T) {
+	reg, _, host := newTestRegistry(t, true)
+	reg.basicAuth = "robot:s3cret"
+	reg.putImage("acme/app", "v1")
+
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.json")
+	auth := base64.StdEncoding.EncodeToString([]byte("robot:s3cret"))
+	if err := os.WriteFile(cfgPath, []byte(`{"auths":{"`+host+`":{"auth":"`+auth+`"}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	creds, err := LoadDockerConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadDockerConfig: %v", err)
+	}
+
+	ref, _ := ParseReference(host + "/acme/app:v1")
+	if _, err := NewClient(creds, true).Attach(context.Background(), ref, testArtifactType, "", []byte(`{}`), nil); err != nil {
+		t.Fatalf("Attach with credentials: %v", err)
+	}
+	if _, err := NewClient(nil, true).Attach(context.Background(), ref, testArtifactType, "", []byte(`{}`), nil); err == nil {
+		t.Fatal("expected anonymous Attach to fail")
+	}
+}
+
+func TestAttachWithBearerToken(t *testing.T) {
+	reg, _, host := newTestRegistry(t, true)
+	reg.bearer = "tok-123"
+	reg.putImage("acme/app", "v1")
+
+	ref, _ := ParseReference(host + "/acme/app:v1")
+	if _, err := NewClient(nil, true).Attach(context.Background(), ref, testArtifactType, "", []byte(`{}`), nil); err != nil {
+		t.Fatalf("Attach with bearer token: %v", err)
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	ch := parseChallenge(`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:a/b:pull,push"`)
+	if ch.Scheme != "bearer" {
+		t.Errorf("Scheme = %q, want bearer", ch.Scheme)
+	}
+	want := map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry.example.com",
+		"scope":   "repository:a/b:pull,push",
+	}
+	for k, v := range want {
+		if ch.Params[k] != v {
+			t.Errorf("Params[%q] = %q, want %q", k, ch.Params[k], v)
+		}
+	}
+}
+
+func TestFetchPayload(t *testing.T) {
+	reg, _, host := newTestRegistry(t, true)
+	reg.putImage("acme/app", "v1")
+
+	client := NewClient(nil, true)
+	ref, _ := ParseReference(host + "/acme/app:v1")
+	payload := []byte(`{"id":"abc"}`)
+
+	desc, err := client.Attach(context.Background(), ref, testArtifactType, "pbom.json", payload, nil)
+	if err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+
+	got, manifest, err := client.FetchPayload(context.Background(), ref, desc.Digest, testArtifactType, 1<<20)
+	if err != nil {
+		t.Fatalf("FetchPayload: %v", err)
+	}
+	if string(got) != string(payload) {
+		t.Errorf("payload = %s, want %s", got, payload)
+	}
+	if manifest.Layers[0].Annotations[AnnotationTitle] != "pbom.json" {
+		t.Errorf("title annotation = %q, want pbom.json", manifest.Layers[0].Annotations[AnnotationTitle])
+	}
+
+	if _, _, err := client.FetchPayload(context.Background(), ref, desc.Digest, testArtifactType, 4); err == nil {
+		t.Error("expected error when payload exceeds maxSize")
+	}
+	if _, _, err := client.FetchPayload(context.Background(), ref, desc.Digest, "application/other", 1<<20); err == nil {
+		t.Error("expected error for missing media type layer")
+	}
+
+	if _, _, err := client.FetchReferrer(context.Background(), ref, desc.Digest, testArtifactType, 1<<20); err != nil {
+		t.Errorf("FetchReferrer of the subject: %v", err)
+	}
+	other := ref
+	other.Tag, other.Digest = "", DigestOf([]byte("another image"))
+	if _, _, err := client.FetchReferrer(context.Background(), other, desc.Digest, testArtifactType, 1<<20); err == nil {
+		t.Error("FetchReferrer returned a referrer of another image")
+	}
+}
+
+func TestReferrersIndexTooLarge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", MediaTypeImageIndex)
+		w.Write([]byte(`{"manifests":[` + strings.Repeat(" ", maxManifestSize) + `]}`))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	ref, _ := ParseReference(u.Host + "/acme/app@" + DigestOf([]byte("image")))
+	_, err := NewClient(nil, true).Referrers(context.Background(), ref, "")
+	if err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("Referrers error = %v, want size limit error", err)
+	}
+}
@@ -0,0 +1,101 @@
+// Package oci is a minimal OCI Distribution client used to attach PBOM
+// documents to container images as referrer artifacts (OCI 1.1) and to
+// read them back.
+package oci
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	dockerHubRegistry = "docker.io"
+	dockerHubAPIHost  = "registry-1.docker.io"
+)
+
+// Reference identifies a manifest in a registry by tag and/or digest.
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses an image reference such as
+// "ghcr.io/acme-corp/my-app:v1", "ghcr.io/acme-corp/my-app@sha256:..." or
+// "alpine:3.20". References without a registry host default to Docker Hub.
+func ParseReference(s string) (Reference, error) {
+	if s == "" {
+		return Reference{}, fmt.Errorf("empty reference")
+	}
+
+	var ref Reference
+	name := s
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Digest = name[i+1:]
+		name = name[:i]
+		if !strings.Contains(ref.Digest, ":") {
+			return Reference{}, fmt.Errorf("invalid digest %q in reference %q", ref.Digest, s)
+		}
+	}
+
+	// A tag is a colon after the last slash (colons before it are ports).
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+	}
+
+	first, rest, found := strings.Cut(name, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Registry = first
+		ref.Repository = rest
+	} else {
+		ref.Registry = dockerHubRegistry
+		ref.Repository = name
+	}
+	if ref.Registry == dockerHubRegistry && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = "library/" + ref.Repository
+	}
+
+	if ref.Repository == "" {
+		return Reference{}, fmt.Errorf("missing repository in reference %q", s)
+	}
+	if ref.Repository != strings.ToLower(ref.Repository) {
+		return Reference{}, fmt.Errorf("repository name must be lowercase: %q", ref.Repository)
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+
+	return ref, nil
+}
+
+// Identifier returns the digest if set, otherwise the tag. This is the
+// value used in /v2/<name>/manifests/<reference> requests.
+func (r Reference) Identifier() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// String formats the reference in its canonical form.
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// apiHost returns the host serving the registry API. Docker Hub is the
+// only registry whose API host differs from its name.
+func (r Reference) apiHost() string {
+	if r.Registry == dockerHubRegistry {
+		return dockerHubAPIHost
+	}
+	return r.Registry
+}
@@ -0,0 +1,285 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Annotation keys set on PBOM referrer manifests.
+const (
+	AnnotationCreated = "org.opencontainers.image.created"
+	AnnotationTitle   = "org.opencontainers.image.title"
+)
+
+// emptyConfig is the OCI 1.1 empty JSON descriptor payload used as the
+// config of artifact manifests.
+var emptyConfig = []byte("{}")
+
+// Attach uploads payload as an artifact whose manifest has subject as its
+// subject. Registries that implement the Referrers API index the manifest
+// themselves; for the rest, the tag-schema referrers index
+// (<alg>-<hex>) is updated instead. Returns the referrer manifest descriptor.
+func (c *Client) Attach(ctx context.Context, subject Reference, artifactType, filename string, payload []byte, annotations map[string]string) (Descriptor, error) {
+	subjectDesc, err := c.Resolve(ctx, subject)
+	if err != nil {
+		return Descriptor{}, fmt.Errorf("resolving %s: %w", subject, err)
+	}
+
+	configDesc, err := c.PushBlob(ctx, subject, MediaTypeEmptyJSON, emptyConfig)
+	if err != nil {
+		return Descriptor{}, fmt.Errorf("pushing config blob: %w", err)
+	}
+	configDesc.Data = emptyConfig
+
+	layerDesc, err := c.PushBlob(ctx, subject, artifactType, payload)
+	if err != nil {
+		return Descriptor{}, fmt.Errorf("pushing payload blob: %w", err)
+	}
+	if filename != "" {
+		layerDesc.Annotations = map[string]string{AnnotationTitle: filename}
+	}
+
+	manifestAnnotations := map[string]string{
+		AnnotationCreated: time.Now().UTC().Format(time.RFC3339),
+	}
+	for k, v := range annotations {
+		manifestAnnotations[k] = v
+	}
+
+	manifest := Manifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeImageManifest,
+		ArtifactType:  artifactType,
+		Config:        configDesc,
+		Layers:        []Descriptor{layerDesc},
+		Subject: &Descriptor{
+			MediaType: subjectDesc.MediaType,
+			Digest:    subjectDesc.Digest,
+			Size:      subjectDesc.Size,
+		},
+		Annotations: manifestAnnotations,
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return Descriptor{}, fmt.Errorf("marshaling manifest: %w", err)
+	}
+
+	desc, ociSubject, err := c.PushManifest(ctx, subject, DigestOf(data), MediaTypeImageManifest, data)
+	if err != nil {
+		return Descriptor{}, fmt.Errorf("pushing referrer manifest: %w", err)
+	}
+	desc.ArtifactType = artifactType
+	desc.Annotations = manifestAnnotations
+
+	if ociSubject == subjectDesc.Digest {
+		return desc, nil
+	}
+
+	// No OCI-Subject header: either an older registry, or one that
+	// supports referrers but doesn't advertise it. Probe before falling back.
+	supported, err := c.referrersAPISupported(ctx, subject, subjectDesc.Digest)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	if supported {
+		return desc, nil
+	}
+
+	if err := c.addToReferrersIndex(ctx, subject, subjectDesc.Digest, desc); err != nil {
+		return Descriptor{}, fmt.Errorf("updating referrers tag index: %w", err)
+	}
+	return desc, nil
+}
+
+// Referrers lists manifests referring to subject, filtered by artifactType
+// when non-empty. It uses the Referrers API and falls back to the
+// tag-schema index for registries without it.
+func (c *Client) Referrers(ctx context.Context, subject Reference, artifactType string) ([]Descriptor, error) {
+	digest := subject.Digest
+	if digest == "" {
+		desc, err := c.Resolve(ctx, subject)
+		if err != nil {
+			return nil, fmt.Errorf("resolving %s: %w", subject, err)
+		}
+		digest = desc.Digest
+	}
+
+	index, err := c.fetchReferrersAPI(ctx, subject, digest, artifactType)
+	if errors.Is(err, ErrNotFound) {
+		index, err = c.fetchReferrersIndex(ctx, subject, digest)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Registries may ignore the artifactType filter; apply it client-side.
+	var result []Descriptor
+	for _, d := range index.Manifests {
+		if artifactType == "" || d.ArtifactType == artifactType {
+			result = append(result, d)
+		}
+	}
+	return result, nil
+}
+
+func (c *Client) fetchReferrersAPI(ctx context.Context, ref Reference, digest, artifactType string) (*Index, error) {
+	path := fmt.Sprintf("/v2/%s/referrers/%s", ref.Repository, digest)
+	if artifactType != "" {
+		path += "?artifactType=" + url.QueryEscape(artifactType)
+	}
+
+	header := http.Header{}
+	header.Set("Accept", MediaTypeImageIndex)
+	resp, err := c.do(ctx, ref, http.MethodGet, path, nil, header, "pull")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading referrers response: %w", err)
+	}
+	if err := checkStatus(resp, path, body); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), MediaTypeImageIndex) {
+		// Some registries answer unknown routes with 200 and an HTML page.
+		return nil, fmt.Errorf("registry %s: %w", path, ErrNotFound)
+	}
+	if len(body) > maxManifestSize {
+		return nil, fmt.Errorf("referrers index for %s exceeds %d bytes", digest, maxManifestSize)
+	}
+
+	var index Index
+	if err := json.Unmarshal(body, &index); err != nil {
+		return nil, fmt.Errorf("parsing referrers index: %w", err)
+	}
+	return &index, nil
+}
+
+func (c *Client) referrersAPISupported(ctx context.Context, ref Reference, digest string) (bool, error) {
+	_, err := c.fetchReferrersAPI(ctx, ref, digest, "")
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// fetchReferrersIndex reads the tag-schema referrers index. A missing
+// tag yields an empty index.
+func (c *Client) fetchReferrersIndex(ctx context.Context, ref Reference, digest string) (*Index, error) {
+	data, _, err := c.FetchManifest(ctx, ref, referrersTag(digest))
+	if errors.Is(err, ErrNotFound) {
+		return &Index{SchemaVersion: 2, MediaType: MediaTypeImageIndex}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("parsing referrers tag index: %w", err)
+	}
+	return &index, nil
+}
+
+func (c *Client) addToReferrersIndex(ctx context.Context, ref Reference, subjectDigest string, desc Descriptor) error {
+	index, err := c.fetchReferrersIndex(ctx, ref, subjectDigest)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range index.Manifests {
+		if d.Digest == desc.Digest {
+			return nil
+		}
+	}
+	index.SchemaVersion = 2
+	index.MediaType = MediaTypeImageIndex
+	index.Manifests = append(index.Manifests, desc)
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("marshaling referrers index: %w", err)
+	}
+
+	_, _, err = c.PushManifest(ctx, ref, referrersTag(subjectDigest), MediaTypeImageIndex, data)
+	return err
+}
+
+// referrersTag returns the tag-schema fallback tag for a digest:
+// "sha256:abc..." → "sha256-abc...", truncated to the 128-char tag limit.
+func referrersTag(digest string) string {
+	tag := strings.Replace(digest, ":", "-", 1)
+	if len(tag) > 128 {
+		tag = tag[:128]
+	}
+	return tag
+}
+
+// FetchPayload downloads a referrer manifest and returns the content of
+// its first layer with the given media type.
+func (c *Client) FetchPayload(ctx context.Context, ref Reference, manifestDigest, mediaType string, maxSize int64) ([]byte, *Manifest, error) {
+	data, _, err := c.FetchManifest(ctx, ref, manifestDigest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching manifest %s: %w", manifestDigest, err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("parsing manifest %s: %w", manifestDigest, err)
+	}
+
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != mediaType {
+			continue
+		}
+		if layer.Size > maxSize {
+			return nil, nil, fmt.Errorf("layer %s is %d bytes (limit %d)", layer.Digest, layer.Size, maxSize)
+		}
+		payload, err := c.FetchBlob(ctx, ref, layer.Digest, maxSize)
+		if err != nil {
+			return nil, nil, fmt.Errorf("fetching layer %s: %w", layer.Digest, err)
+		}
+		return payload, &manifest, nil
+	}
+
+	return nil, nil, fmt.Errorf("manifest %s has no %s layer", manifestDigest, mediaType)
+}
+
+// FetchReferrer is FetchPayload for a referrer of subject. It fails unless
+// the referrer manifest's subject is the image subject names, so that a
+// referrer digest from the user cannot return another image's payload.
+func (c *Client) FetchReferrer(ctx context.Context, subject Reference, manifestDigest, mediaType string, maxSize int64) ([]byte, *Manifest, error) {
+	digest := subject.Digest
+	if digest == "" {
+		desc, err := c.Resolve(ctx, subject)
+		if err != nil {
+			return nil, nil, fmt.Errorf("resolving %s: %w", subject, err)
+		}
+		digest = desc.Digest
+	}
+
+	payload, manifest, err := c.FetchPayload(ctx, subject, manifestDigest, mediaType, maxSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	if manifest.Subject == nil {
+		return nil, nil, fmt.Errorf("manifest %s has no subject, so it is not a referrer of %s", manifestDigest, subject)
+	}
+	if manifest.Subject.Digest != digest {
+		return nil, nil, fmt.Errorf("manifest %s refers to %s, not %s (%s)", manifestDigest, manifest.Subject.Digest, subject, digest)
+	}
+	return payload, manifest, nil
+}
@@ -0,0 +1,217 @@
+package oci
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testRegistry is a minimal in-process OCI Distribution registry covering
+// the endpoints the client uses.
+type testRegistry struct {
+	referrers  bool   // serve the Referrers API
+	basicAuth  string // "user:pass" required when non-empty
+	bearer     string // token required when non-empty (served from /token)
+	mu         sync.Mutex
+	blobs      map[string][]byte
+	manifests  map[string][]byte // digest → content
+	mediaTypes map[string]string // digest → media type
+	tags       map[string]string // repo:tag → digest
+	uploads    int
+}
+
+func newTestRegistry(t *testing.T, referrers bool) (*testRegistry, *httptest.Server, string) {
+	t.Helper()
+	reg := &testRegistry{
+		referrers:  referrers,
+		blobs:      map[string][]byte{},
+		manifests:  map[string][]byte{},
+		mediaTypes: map[string]string{},
+		tags:       map[string]string{},
+	}
+	srv := httptest.NewServer(reg)
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	return reg, srv, u.Host
+}
+
+// putImage stores a minimal image manifest tagged as repo:tag and returns its digest.
+func (r *testRegistry) putImage(repo, tag string) string {
+	m := Manifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeImageManifest,
+		Config:        Descriptor{MediaType: "application/vnd.oci.image.config.v1+json", Digest: DigestOf([]byte("cfg")), Size: 3},
+		Layers:        []Descriptor{},
+	}
+	data, _ := json.Marshal(m)
+	d := DigestOf(data)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.manifests[d] = data
+	r.mediaTypes[d] = MediaTypeImageManifest
+	r.tags[repo+":"+tag] = d
+	return d
+}
+
+func (r *testRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/token" {
+		if r.basicAuth != "" {
+			user, pass, ok := req.BasicAuth()
+			if !ok || user+":"+pass != r.basicAuth {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": r.bearer})
+		return
+	}
+
+	if !r.authorized(w, req) {
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	switch {
+	case strings.Contains(path, "/blobs/uploads/"):
+		r.serveUpload(w, req, path[:strings.Index(path, "/blobs/uploads/")])
+	case strings.Contains(path, "/blobs/"):
+		r.serveBlob(w, req, path[strings.LastIndex(path, "/")+1:])
+	case strings.Contains(path, "/manifests/"):
+		i := strings.Index(path, "/manifests/")
+		r.serveManifest(w, req, path[:i], path[i+len("/manifests/"):])
+	case strings.Contains(path, "/referrers/") && r.referrers:
+		r.serveReferrers(w, req, path[strings.LastIndex(path, "/")+1:])
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (r *testRegistry) authorized(w http.ResponseWriter, req *http.Request) bool {
+	got := req.Header.Get("Authorization")
+	switch {
+	case r.bearer != "":
+		if got == "Bearer "+r.bearer {
+			return true
+		}
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="test"`, req.Host))
+	case r.basicAuth != "":
+		user, pass, ok := req.BasicAuth()
+		if ok && user+":"+pass == r.basicAuth {
+			return true
+		}
+		w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+	default:
+		return true
+	}
+	w.WriteHeader(http.StatusUnauthorized)
+	return false
+}
+
+func (r *testRegistry) serveUpload(w http.ResponseWriter, req *http.Request, repo string) {
+	switch req.Method {
+	case http.MethodPost:
+		r.mu.Lock()
+		r.uploads++
+		id := r.uploads
+		r.mu.Unlock()
+		w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/uploads/%d?state=x", repo, id))
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodPut:
+		data, _ := io.ReadAll(req.Body)
+		digest := req.URL.Query().Get("digest")
+		if DigestOf(data) != digest || req.URL.Query().Get("state") != "x" {
+			http.Error(w, "digest invalid", http.StatusBadRequest)
+			return
+		}
+		r.mu.Lock()
+		r.blobs[digest] = data
+		r.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (r *testRegistry) serveBlob(w http.ResponseWriter, req *http.Request, digest string) {
+	r.mu.Lock()
+	data, ok := r.blobs[digest]
+	r.mu.Unlock()
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+	if req.Method == http.MethodGet {
+		w.Write(data)
+	}
+}
+
+func (r *testRegistry) serveManifest(w http.ResponseWriter, req *http.Request, repo, ident string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if req.Method == http.MethodPut {
+		data, _ := io.ReadAll(req.Body)
+		d := DigestOf(data)
+		if strings.HasPrefix(ident, "sha256:") && ident != d {
+			http.Error(w, "digest mismatch", http.StatusBadRequest)
+			return
+		}
+		r.manifests[d] = data
+		r.mediaTypes[d] = req.Header.Get("Content-Type")
+		if !strings.HasPrefix(ident, "sha256:") {
+			r.tags[repo+":"+ident] = d
+		}
+		var m Manifest
+		if json.Unmarshal(data, &m) == nil && m.Subject != nil && r.referrers {
+			w.Header().Set("OCI-Subject", m.Subject.Digest)
+		}
+		w.Header().Set("Docker-Content-Digest", d)
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	d := ident
+	if !strings.HasPrefix(ident, "sha256:") {
+		d = r.tags[repo+":"+ident]
+	}
+	data, ok := r.manifests[d]
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Content-Type", r.mediaTypes[d])
+	w.Header().Set("Docker-Content-Digest", d)
+	w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+	if req.Method == http.MethodGet {
+		w.Write(data)
+	}
+}
+
+func (r *testRegistry) serveReferrers(w http.ResponseWriter, req *http.Request, subject string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	index := Index{SchemaVersion: 2, MediaType: MediaTypeImageIndex, Manifests: []Descriptor{}}
+	for d, data := range r.manifests {
+		var m Manifest
+		if json.Unmarshal(data, &m) != nil || m.Subject == nil || m.Subject.Digest != subject {
+			continue
+		}
+		index.Manifests = append(index.Manifests, Descriptor{
+			MediaType:    r.mediaTypes[d],
+			Digest:       d,
+			Size:         int64(len(data)),
+			ArtifactType: m.ArtifactType,
+			Annotations:  m.Annotations,
+		})
+	}
+	w.Header().Set("Content-Type", MediaTypeImageIndex)
+	json.NewEncoder(w).Encode(index)
+}
@@ -0,0 +1,477 @@
+// Package queue is a durable work queue for webhook enrichment. Jobs are
+// persisted in a storage.Storage (a local directory or SQLite database)
+// before they are acknowledged, retried with exponential backoff when
+// they fail, and moved to a dead-letter list when they run out of
+// attempts. Dead jobs can be replayed.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
+	"github.com/google/uuid"
+)
+
+const (
+	pendingPrefix = "pending/"
+	deadPrefix    = "dead/"
+)
+
+// Job is a unit of queued work.
+type Job struct {
+	// ID is a time-ordered UUID, so jobs list in enqueue order.
+	ID            string          `json:"id"`
+	Payload       json.RawMessage `json:"payload"`
+	Attempts      int             `json:"attempts"`
+	EnqueuedAt    time.Time       `json:"enqueued_at"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty"`
+	FailedAt      *time.Time      `json:"failed_at,omitempty"`
+}
+
+// Handler processes a job. A nil error completes the job; any other error
+// schedules a retry unless it is wrapped with Permanent.
+type Handler func(ctx context.Context, job *Job) error
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying: the job goes straight to the
+// dead-letter list.
+func Permanent(err error) error { return permanentError{err} }
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var perm permanentError
+	return errors.As(err, &perm)
+}
+
+// Config tunes a Queue. Zero fields take the defaults noted.
+type Config struct {
+	Workers      int           // concurrent handlers (4)
+	MaxAttempts  int           // attempts before dead-lettering (8)
+	BaseDelay    time.Duration // delay after the first failure (30s)
+	MaxDelay     time.Duration // cap on the retry delay (1h)
+	PollInterval time.Duration // how often storage is rescanned for jobs added elsewhere (5s)
+}
+
+func (c Config) withDefaults() Config {
+	if c.Workers <= 0 {
+		c.Workers = 4
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 8
+	}
+	if c.BaseDelay <= 0 {
+		c.BaseDelay = 30 * time.Second
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = time.Hour
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = 5 * time.Second
+	}
+	return c
+}
+
+// Queue is a durable job queue. A queue's storage must be owned by a
+// single running process; other processes may only list and replay.
+type Queue struct {
+	st     storage.Storage
+	cfg    Config
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	due     map[string]time.Time // pending job ID -> next attempt
+	running map[string]bool
+	wake    chan struct{}
+}
+
+// New returns a queue persisted in st.
+func New(st storage.Storage, cfg Config, logger *slog.Logger) *Queue {
+	return &Queue{
+		st:      st,
+		cfg:     cfg.withDefaults(),
+		logger:  logger,
+		now:     time.Now,
+		due:     map[string]time.Time{},
+		running: map[string]bool{},
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+func (q *Queue) String() string { return fmt.Sprint(q.st) }
+
+func jobKey(prefix, id string) string { return prefix + id + ".json" }
+
+func (q *Queue) put(ctx context.Context, prefix string, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return q.st.Put(ctx, jobKey(prefix, job.ID), data)
+}
+
+func (q *Queue) get(ctx context.Context, prefix, id string) (*Job, error) {
+	data, err := q.st.Get(ctx, jobKey(prefix, id))
+	if err != nil {
+		return nil, err
+	}
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("decoding job %s: %w", id, err)
+	}
+	return &job, nil
+}
+
+func (q *Queue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Enqueue persists a new job and returns it. Once Enqueue returns the job
+// survives restarts.
+func (q *Queue) Enqueue(ctx context.Context, payload []byte) (*Job, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	now := q.now().UTC()
+	job := &Job{ID: id.String(), Payload: payload, EnqueuedAt: now, NextAttemptAt: now}
+	if err := q.put(ctx, pendingPrefix, job); err != nil {
+		return nil, fmt.Errorf("enqueuing job: %w", err)
+	}
+
+	q.mu.Lock()
+	q.due[job.ID] = job.NextAttemptAt
+	q.mu.Unlock()
+	q.signal()
+	return job, nil
+}
+
+// Run processes jobs with h until ctx is cancelled, then waits for
+// in-flight jobs to return. Jobs interrupted by cancellation stay pending
+// without using up an attempt.
+func (q *Queue) Run(ctx context.Context, h Handler) error {
+	if err := q.rescan(ctx); err != nil {
+		return err
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < q.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				q.process(ctx, id, h)
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer close(jobs)
+
+	poll := time.NewTicker(q.cfg.PollInterval)
+	defer poll.Stop()
+
+	for {
+		id, wait := q.next()
+		if id != "" {
+			select {
+			case jobs <- id: // blocks until a worker is free
+				continue
+			case <-ctx.Done():
+				q.release(id)
+				return nil
+			}
+		}
+
+		var timer *time.Timer
+		var fire <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			fire = timer.C
+		}
+		select {
+		case <-fire:
+		case <-q.wake:
+		case <-poll.C:
+			q.rescanLogged(ctx)
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// next claims the earliest due job. If none is due it returns the time
+// until the next one (0 when there are none).
+func (q *Queue) next() (string, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	var (
+		bestID  string
+		bestDue time.Time
+	)
+	for id, due := range q.due {
+		if q.running[id] {
+			continue
+		}
+		if bestID == "" || due.Before(bestDue) || due.Equal(bestDue) && id < bestID {
+			bestID, bestDue = id, due
+		}
+	}
+	if bestID == "" {
+		return "", 0
+	}
+	if wait := bestDue.Sub(now); wait > 0 {
+		return "", wait
+	}
+	q.running[bestID] = true
+	return bestID, 0
+}
+
+func (q *Queue) release(id string) {
+	q.mu.Lock()
+	delete(q.running, id)
+	q.mu.Unlock()
+}
+
+// retryLater releases a job that could not be handled because of a
+// storage error, holding it back for BaseDelay.
+func (q *Queue) retryLater(id string) {
+	q.mu.Lock()
+	delete(q.running, id)
+	q.due[id] = q.now().Add(q.cfg.BaseDelay)
+	q.mu.Unlock()
+}
+
+func (q *Queue) finish(id string) {
+	q.mu.Lock()
+	delete(q.running, id)
+	delete(q.due, id)
+	q.mu.Unlock()
+}
+
+func (q *Queue) process(ctx context.Context, id string, h Handler) {
+	log := q.logger.With("job_id", id)
+
+	job, err := q.get(ctx, pendingPrefix, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		q.finish(id) // completed or removed elsewhere
+		return
+	}
+	if err != nil {
+		log.Error("failed to load job", "error", err)
+		q.retryLater(id)
+		return
+	}
+
+	// The attempt is recorded before the handler runs so that a crash
+	// mid-job still counts against the budget.
+	job.Attempts++
+	if err := q.put(ctx, pendingPrefix, job); err != nil {
+		log.Error("failed to record attempt", "error", err)
+		q.retryLater(id)
+		return
+	}
+	herr := h(ctx, job)
+
+	// Bookkeeping below must complete even during shutdown.
+	bctx := context.WithoutCancel(ctx)
+	if herr != nil && ctx.Err() != nil {
+		// Shutting down; try again after restart without using up an
+		// attempt.
+		job.Attempts--
+		if err := q.put(bctx, pendingPrefix, job); err != nil {
+			log.Error("failed to restore attempt count", "error", err)
+		}
+		q.release(id)
+		return
+	}
+	if herr == nil {
+		if err := q.st.Delete(bctx, jobKey(pendingPrefix, id)); err != nil {
+			log.Error("failed to remove completed job", "error", err)
+		}
+		q.finish(id)
+		return
+	}
+
+	now := q.now().UTC()
+	job.LastError = herr.Error()
+	if IsPermanent(herr) || job.Attempts >= q.cfg.MaxAttempts {
+		job.FailedAt = &now
+		if err := q.put(bctx, deadPrefix, job); err != nil {
+			log.Error("failed to dead-letter job", "error", err)
+			q.retryLater(id)
+			return
+		}
+		if err := q.st.Delete(bctx, jobKey(pendingPrefix, id)); err != nil {
+			log.Error("failed to remove dead-lettered job", "error", err)
+		}
+		log.Error("job dead-lettered", "attempts", job.Attempts, "error", herr)
+		q.finish(id)
+		return
+	}
+
+	delay := q.backoff(job.Attempts)
+	job.NextAttemptAt = now.Add(delay)
+	if err := q.put(bctx, pendingPrefix, job); err != nil {
+		log.Error("failed to reschedule job", "error", err)
+	}
+	log.Warn("job failed, will retry", "attempt", job.Attempts, "retry_in", delay, "error", herr)
+
+	q.mu.Lock()
+	q.due[id] = job.NextAttemptAt
+	delete(q.running, id)
+	q.mu.Unlock()
+	q.signal()
+}
+
+// backoff returns the delay before retrying after the given number of
+// attempts: BaseDelay doubled per attempt, capped at MaxDelay.
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := q.cfg.BaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= q.cfg.MaxDelay {
+			return q.cfg.MaxDelay
+		}
+	}
+	return min(delay, q.cfg.MaxDelay)
+}
+
+// rescan picks up pending jobs written by other processes or before a
+// restart.
+func (q *Queue) rescan(ctx context.Context) error {
+	keys, err := q.st.List(ctx, pendingPrefix)
+	if err != nil {
+		return fmt.Errorf("listing pending jobs: %w", err)
+	}
+	for _, key := range keys {
+		id := strings.TrimSuffix(strings.TrimPrefix(key, pendingPrefix), ".json")
+		q.mu.Lock()
+		_, known := q.due[id]
+		q.mu.Unlock()
+		if known {
+			continue
+		}
+		job, err := q.get(ctx, pendingPrefix, id)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		q.mu.Lock()
+		q.due[id] = job.NextAttemptAt
+		q.mu.Unlock()
+	}
+	return nil
+}
+
+func (q *Queue) rescanLogged(ctx context.Context) {
+	if err := q.rescan(ctx); err != nil && ctx.Err() == nil {
+		q.logger.Error("failed to rescan queue", "error", err)
+	}
+}
+
+func (q *Queue) list(ctx context.Context, prefix string) ([]*Job, error) {
+	keys, err := q.st.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	var jobs []*Job
+	for _, key := range keys {
+		id := strings.TrimSuffix(strings.TrimPrefix(key, prefix), ".json")
+		job, err := q.get(ctx, prefix, id)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs, nil
+}
+
+// Pending returns the jobs waiting to run or be retried, oldest first.
+func (q *Queue) Pending(ctx context.Context) ([]*Job, error) {
+	return q.list(ctx, pendingPrefix)
+}
+
+// Dead returns the dead-lettered jobs, oldest first.
+func (q *Queue) Dead(ctx context.Context) ([]*Job, error) {
+	return q.list(ctx, deadPrefix)
+}
+
+// Depth returns the number of pending jobs.
+func (q *Queue) Depth(ctx context.Context) (int, error) {
+	keys, err := q.st.List(ctx, pendingPrefix)
+	return len(keys), err
+}
+
+// Replay moves dead-lettered jobs back to the pending list with a fresh
+// set of attempts. With no ids every dead job is replayed. It returns the
+// replayed jobs.
+func (q *Queue) Replay(ctx context.Context, ids ...string) ([]*Job, error) {
+	var jobs []*Job
+	if len(ids) == 0 {
+		var err error
+		if jobs, err = q.Dead(ctx); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, id := range ids {
+			job, err := q.get(ctx, deadPrefix, id)
+			if errors.Is(err, storage.ErrNotFound) {
+				return nil, fmt.Errorf("no dead-lettered job %s", id)
+			}
+			if err != nil {
+				return nil, err
+			}
+			jobs = append(jobs, job)
+		}
+	}
+
+	now := q.now().UTC()
+	for _, job := range jobs {
+		job.Attempts = 0
+		job.NextAttemptAt = now
+		job.FailedAt = nil
+		if err := q.put(ctx, pendingPrefix, job); err != nil {
+			return nil, fmt.Errorf("replaying job %s: %w", job.ID, err)
+		}
+		if err := q.st.Delete(ctx, jobKey(deadPrefix, job.ID)); err != nil {
+			return nil, fmt.Errorf("replaying job %s: %w", job.ID, err)
+		}
+		q.mu.Lock()
+		q.due[job.ID] = now
+		q.mu.Unlock()
+	}
+	if len(jobs) > 0 {
+		q.signal()
+	}
+	return jobs, nil
+}
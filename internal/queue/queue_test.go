@@ -0,0 +1,256 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
+)
+
+func newTestQueue(t *testing.T, st storage.Storage, cfg Config) *Queue {
+	t.Helper()
+	if st == nil {
+		var err error
+		if st, err = storage.NewFS(t.TempDir()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cfg.BaseDelay == 0 {
+		cfg.BaseDelay = 5 * time.Millisecond
+	}
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = 10 * time.Millisecond
+	}
+	return New(st, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// runUntil runs q until done reports true, then stops it.
+func runUntil(t *testing.T, q *Queue, h Handler, done func() bool) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- q.Run(ctx, h) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			cancel()
+			<-errCh
+			t.Fatal("timed out waiting for queue")
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+}
+
+func mustEnqueue(t *testing.T, q *Queue, payload string) *Job {
+	t.Helper()
+	job, err := q.Enqueue(context.Background(), []byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestQueueProcessesJobs(t *testing.T) {
+	q := newTestQueue(t, nil, Config{Workers: 2})
+	for i := 0; i < 10; i++ {
+		mustEnqueue(t, q, `{"n":1}`)
+	}
+
+	var done, active, maxActive atomic.Int32
+	runUntil(t, q, func(ctx context.Context, job *Job) error {
+		n := active.Add(1)
+		for {
+			m := maxActive.Load()
+			if n <= m || maxActive.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		active.Add(-1)
+		done.Add(1)
+		return nil
+	}, func() bool { return done.Load() == 10 })
+
+	if m := maxActive.Load(); m > 2 {
+		t.Errorf("max concurrent handlers = %d, want <= 2", m)
+	}
+	if n, _ := q.Depth(context.Background()); n != 0 {
+		t.Errorf("depth after completion = %d, want 0", n)
+	}
+}
+
+func TestQueueRetriesWithBackoff(t *testing.T) {
+	q := newTestQueue(t, nil, Config{MaxAttempts: 5})
+	mustEnqueue(t, q, `{}`)
+
+	var attempts []int
+	var mu sync.Mutex
+	var succeeded atomic.Bool
+	runUntil(t, q, func(ctx context.Context, job *Job) error {
+		mu.Lock()
+		attempts = append(attempts, job.Attempts)
+		mu.Unlock()
+		if job.Attempts < 3 {
+			return errors.New("rate limited")
+		}
+		succeeded.Store(true)
+		return nil
+	}, succeeded.Load)
+
+	if len(attempts) != 3 || attempts[0] != 1 || attempts[2] != 3 {
+		t.Errorf("attempts = %v, want [1 2 3]", attempts)
+	}
+	if dead, _ := q.Dead(context.Background()); len(dead) != 0 {
+		t.Errorf("dead jobs = %d, want 0", len(dead))
+	}
+}
+
+func TestQueueDeadLetterAndReplay(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueue(t, nil, Config{MaxAttempts: 3})
+	failing := mustEnqueue(t, q, `{"run":1}`)
+	permanent := mustEnqueue(t, q, `{"run":2}`)
+
+	var calls sync.Map
+	runUntil(t, q, func(ctx context.Context, job *Job) error {
+		n, _ := calls.LoadOrStore(job.ID, new(atomic.Int32))
+		n.(*atomic.Int32).Add(1)
+		if job.ID == permanent.ID {
+			return Permanent(errors.New("malformed payload"))
+		}
+		return errors.New("github unavailable")
+	}, func() bool {
+		dead, _ := q.Dead(ctx)
+		return len(dead) == 2
+	})
+
+	dead, err := q.Dead(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range dead {
+		switch job.ID {
+		case failing.ID:
+			if job.Attempts != 3 || job.LastError != "github unavailable" || job.FailedAt == nil {
+				t.Errorf("failing job = %+v", job)
+			}
+		case permanent.ID:
+			if job.Attempts != 1 {
+				t.Errorf("permanent job attempts = %d, want 1", job.Attempts)
+			}
+		}
+		if string(job.Payload) == "" {
+			t.Errorf("job %s lost its payload", job.ID)
+		}
+	}
+	if n, _ := q.Depth(ctx); n != 0 {
+		t.Errorf("depth = %d, want 0", n)
+	}
+
+	if _, err := q.Replay(ctx, "no-such-job"); err == nil {
+		t.Error("expected error replaying unknown job")
+	}
+	replayed, err := q.Replay(ctx, failing.ID)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if len(replayed) != 1 || replayed[0].Attempts != 0 {
+		t.Fatalf("replayed = %+v", replayed)
+	}
+
+	var ok atomic.Bool
+	runUntil(t, q, func(ctx context.Context, job *Job) error {
+		if job.ID != failing.ID || job.Attempts != 1 {
+			t.Errorf("unexpected job %s attempt %d", job.ID, job.Attempts)
+		}
+		ok.Store(true)
+		return nil
+	}, ok.Load)
+
+	if dead, _ := q.Dead(ctx); len(dead) != 1 || dead[0].ID != permanent.ID {
+		t.Errorf("dead after replay = %v", dead)
+	}
+}
+
+func TestQueueSurvivesRestart(t *testing.T) {
+	st, err := storage.OpenSQLite(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	// A job interrupted by shutdown keeps its attempt budget.
+	first := newTestQueue(t, st, Config{})
+	job := mustEnqueue(t, first, `{"run":7}`)
+	var started atomic.Bool
+	runUntil(t, first, func(ctx context.Context, job *Job) error {
+		started.Store(true)
+		<-ctx.Done()
+		return ctx.Err()
+	}, started.Load)
+
+	pending, err := first.Pending(context.Background())
+	if err != nil || len(pending) != 1 || pending[0].Attempts != 0 {
+		t.Fatalf("pending after shutdown = %+v, %v", pending, err)
+	}
+
+	// A new process picks the job up from storage.
+	second := newTestQueue(t, st, Config{})
+	var got atomic.Value
+	runUntil(t, second, func(ctx context.Context, j *Job) error {
+		got.Store(string(j.Payload))
+		return nil
+	}, func() bool { return got.Load() != nil })
+
+	if got.Load() != `{"run":7}` {
+		t.Errorf("payload = %v", got.Load())
+	}
+	if n, _ := second.Depth(context.Background()); n != 0 {
+		t.Errorf("depth = %d, want 0 (job %s)", n, job.ID)
+	}
+}
+
+func TestQueueRecordsAttemptBeforeHandler(t *testing.T) {
+	q := newTestQueue(t, nil, Config{})
+	mustEnqueue(t, q, `{"run":8}`)
+
+	// What storage holds while the handler runs is what a crashed
+	// process would resume from.
+	var stored atomic.Int32
+	stored.Store(-1)
+	runUntil(t, q, func(ctx context.Context, job *Job) error {
+		pending, err := q.Pending(ctx)
+		if err != nil || len(pending) != 1 {
+			t.Errorf("pending during handler = %+v, %v", pending, err)
+			return nil
+		}
+		stored.Store(int32(pending[0].Attempts))
+		return nil
+	}, func() bool { return stored.Load() >= 0 })
+
+	if got := stored.Load(); got != 1 {
+		t.Errorf("stored attempts during handler = %d, want 1", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	q := New(nil, Config{BaseDelay: time.Second, MaxDelay: 10 * time.Second}, nil)
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, w := range want {
+		if got := q.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// IndexPrefix is the key prefix under which index entries are kept.
+// Document keys must not start with it.
+const IndexPrefix = "index/"
+
+// Index kinds.
+const (
+	IndexDigest = "digest" // artifact digest, e.g. sha256:...
+	IndexSHA    = "sha"    // source commit SHA
+	IndexTag    = "tag"    // artifact tag or image reference
+	IndexID     = "id"     // document ID
+	IndexRepo   = "repo"   // source repository, e.g. acme/app
+	IndexBranch = "branch" // source branch
+)
+
+// IndexTerm is a value a document is indexed under.
+type IndexTerm struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+func (t IndexTerm) String() string { return t.Kind + " " + t.Value }
+
+// normalize lowercases the case-insensitive kinds.
+func (t IndexTerm) normalize() IndexTerm {
+	if t.Kind == IndexDigest || t.Kind == IndexSHA || t.Kind == IndexRepo {
+		t.Value = strings.ToLower(t.Value)
+	}
+	return t
+}
+
+// Hit is a document found through the index.
+type Hit struct {
+	Key  string    // document key
+	ID   string    // document ID recorded at indexing time
+	Term IndexTerm // the indexed term that matched
+}
+
+// Op is one write in a batch: Data replaces the document at Key, or nil
+// Data deletes it.
+type Op struct {
+	Key  string
+	Data []byte
+}
+
+// Batcher is implemented by backends that can apply several writes in a
+// single transaction.
+type Batcher interface {
+	Batch(ctx context.Context, ops []Op) error
+}
+
+// Apply performs ops in a single transaction if s is a Batcher, and in
+// order otherwise.
+func Apply(ctx context.Context, s Storage, ops []Op) error {
+	if b, ok := s.(Batcher); ok {
+		return b.Batch(ctx, ops)
+	}
+	for _, op := range ops {
+		var err error
+		if op.Data == nil {
+			err = s.Delete(ctx, op.Key)
+		} else {
+			err = s.Put(ctx, op.Key, op.Data)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexRecord lists the terms a document is indexed under, so that
+// re-storing it can drop entries that no longer apply.
+type indexRecord struct {
+	ID    string      `json:"id"`
+	Terms []IndexTerm `json:"terms"`
+}
+
+func recordKey(docKey string) string { return IndexPrefix + "docs/" + docKey }
+
+// entryPrefix returns the key prefix of a term's entries. Values are
+// path-escaped so that image references stay a single key segment.
+func entryPrefix(t IndexTerm) string {
+	return IndexPrefix + t.Kind + "/" + url.PathEscape(t.Value)
+}
+
+func entryKey(t IndexTerm, docKey string) string { return entryPrefix(t) + "/" + docKey }
+
+// PutIndexed stores data under key and indexes it under terms, replacing
+// any terms the document was previously indexed under.
+//
+// On backends that implement Batcher the whole update is one transaction.
+// Elsewhere the new index entries are written before the document and
+// stale entries are removed after it, so every stored document is always
+// reachable through its current terms; Lookup callers must tolerate
+// entries whose document no longer carries the term.
+func PutIndexed(ctx context.Context, s Storage, key string, data []byte, id string, terms []IndexTerm) error {
+	if strings.HasPrefix(key, IndexPrefix) {
+		return fmt.Errorf("invalid storage key %q: reserved for the index", key)
+	}
+
+	var old indexRecord
+	switch raw, err := s.Get(ctx, recordKey(key)); {
+	case err == nil:
+		if err := json.Unmarshal(raw, &old); err != nil {
+			return fmt.Errorf("reading index record of %s: %w", key, err)
+		}
+	case !errors.Is(err, ErrNotFound):
+		return err
+	}
+
+	rec := indexRecord{ID: id}
+	current := map[IndexTerm]bool{}
+	var ops []Op
+	for _, t := range terms {
+		t = t.normalize()
+		if t.Value == "" || current[t] {
+			continue
+		}
+		current[t] = true
+		rec.Terms = append(rec.Terms, t)
+		ops = append(ops, Op{Key: entryKey(t, key), Data: []byte(id)})
+	}
+	recData, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	ops = append(ops, Op{Key: recordKey(key), Data: recData}, Op{Key: key, Data: data})
+	for _, t := range old.Terms {
+		if !current[t] {
+			ops = append(ops, Op{Key: entryKey(t, key)})
+		}
+	}
+
+	if err := Apply(ctx, s, ops); err != nil {
+		return fmt.Errorf("indexing %s: %w", key, err)
+	}
+	return nil
+}
+
+// Lookup returns the documents indexed under term. Commit SHAs match by
+// prefix so abbreviated SHAs work; digests and tags must match exactly.
+func Lookup(ctx context.Context, s Storage, term IndexTerm) ([]Hit, error) {
+	term = term.normalize()
+	if term.Value == "" {
+		return nil, nil
+	}
+	prefix := entryPrefix(term)
+	if term.Kind != IndexSHA {
+		prefix += "/"
+	}
+
+	keys, err := s.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	kindPrefix := IndexPrefix + term.Kind + "/"
+	var hits []Hit
+	for _, k := range keys {
+		escaped, docKey, ok := strings.Cut(strings.TrimPrefix(k, kindPrefix), "/")
+		if !ok {
+			continue
+		}
+		value, err := url.PathUnescape(escaped)
+		if err != nil {
+			continue
+		}
+		id, err := s.Get(ctx, k)
+		if errors.Is(err, ErrNotFound) {
+			continue // removed since List
+		}
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, Hit{Key: docKey, ID: string(id), Term: IndexTerm{Kind: term.Kind, Value: value}})
+	}
+	return hits, nil
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	backends := map[string]func(t *testing.T) Storage{
+		"fs": func(t *testing.T) Storage {
+			s, err := NewFS(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+		"sqlite": func(t *testing.T) Storage {
+			s, err := OpenSQLite(filepath.Join(t.TempDir(), "pbom.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { s.Close() })
+			return s
+		},
+		"s3": func(t *testing.T) Storage {
+			creds := S3Credentials{AccessKeyID: "minio", SecretAccessKey: "minio123"}
+			_, srv := newFakeS3(t, "pboms", creds)
+			s, err := NewS3(S3Config{Endpoint: srv.URL, Bucket: "pboms", Credentials: creds})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	}
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) { testIndex(t, open(t)) })
+	}
+}
+
+func hitKeys(t *testing.T, s Storage, term IndexTerm) []string {
+	t.Helper()
+	hits, err := Lookup(context.Background(), s, term)
+	if err != nil {
+		t.Fatalf("Lookup %v: %v", term, err)
+	}
+	var keys []string
+	for _, h := range hits {
+		keys = append(keys, h.Key+"="+h.ID)
+	}
+	return keys
+}
+
+func testIndex(t *testing.T, s Storage) {
+	ctx := context.Background()
+	put := func(key, id string, terms ...IndexTerm) {
+		t.Helper()
+		if err := PutIndexed(ctx, s, key, []byte(`{"id":"`+id+`"}`), id, terms); err != nil {
+			t.Fatalf("PutIndexed %s: %v", key, err)
+		}
+	}
+
+	put("acme_app_1.pbom.json", "id-1",
+		IndexTerm{IndexDigest, "sha256:AAAA"},
+		IndexTerm{IndexSHA, "0123456789abcdef"},
+		IndexTerm{IndexTag, "ghcr.io/acme/app:v1"},
+		IndexTerm{IndexTag, ""}, // ignored
+	)
+	put("acme_app_2.pbom.json", "id-2",
+		IndexTerm{IndexDigest, "sha256:aaaa"},
+		IndexTerm{IndexSHA, "0123fff"},
+	)
+
+	tests := []struct {
+		term IndexTerm
+		want []string
+	}{
+		{IndexTerm{IndexDigest, "sha256:aaaa"}, []string{"acme_app_1.pbom.json=id-1", "acme_app_2.pbom.json=id-2"}},
+		{IndexTerm{IndexDigest, "sha256:aa"}, nil}, // digests match exactly
+		{IndexTerm{IndexSHA, "0123"}, []string{"acme_app_1.pbom.json=id-1", "acme_app_2.pbom.json=id-2"}},
+		{IndexTerm{IndexSHA, "0123456"}, []string{"acme_app_1.pbom.json=id-1"}},
+		{IndexTerm{IndexTag, "ghcr.io/acme/app:v1"}, []string{"acme_app_1.pbom.json=id-1"}},
+		{IndexTerm{IndexTag, "ghcr.io/acme/app"}, nil},
+	}
+	for _, tt := range tests {
+		if got := hitKeys(t, s, tt.term); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Lookup %v = %v, want %v", tt.term, got, tt.want)
+		}
+	}
+
+	if got, err := s.Get(ctx, "acme_app_1.pbom.json"); err != nil || string(got) != `{"id":"id-1"}` {
+		t.Errorf("document = %s, %v", got, err)
+	}
+
+	// Re-storing drops terms the document no longer carries.
+	put("acme_app_1.pbom.json", "id-1", IndexTerm{IndexDigest, "sha256:bbbb"})
+	if got := hitKeys(t, s, IndexTerm{IndexDigest, "sha256:aaaa"}); !reflect.DeepEqual(got, []string{"acme_app_2.pbom.json=id-2"}) {
+		t.Errorf("after re-store, old digest = %v", got)
+	}
+	if got := hitKeys(t, s, IndexTerm{IndexTag, "ghcr.io/acme/app:v1"}); got != nil {
+		t.Errorf("after re-store, old tag = %v", got)
+	}
+	if got := hitKeys(t, s, IndexTerm{IndexDigest, "sha256:bbbb"}); !reflect.DeepEqual(got, []string{"acme_app_1.pbom.json=id-1"}) {
+		t.Errorf("after re-store, new digest = %v", got)
+	}
+
+	// Index entries are kept apart from documents.
+	keys, _ := s.List(ctx, "")
+	var docs []string
+	for _, k := range keys {
+		if !strings.HasPrefix(k, IndexPrefix) {
+			docs = append(docs, k)
+		}
+	}
+	if want := []string{"acme_app_1.pbom.json", "acme_app_2.pbom.json"}; !reflect.DeepEqual(docs, want) {
+		t.Errorf("documents = %v, want %v", docs, want)
+	}
+
+	if err := PutIndexed(ctx, s, IndexPrefix+"x.json", []byte("{}"), "x", nil); err == nil {
+		t.Error("expected error for key inside the index namespace")
+	}
+}
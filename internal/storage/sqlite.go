@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+// SQLite stores documents in a single table of a SQLite database.
+type SQLite struct {
+	db   *sql.DB
+	path string
+}
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS documents (
+	key        TEXT PRIMARY KEY,
+	data       BLOB NOT NULL,
+	updated_at TEXT NOT NULL
+)`
+
+const sqliteUpsert = `
+INSERT INTO documents (key, data, updated_at) VALUES (?, ?, ?)
+ON CONFLICT(key) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`
+
+// OpenSQLite opens (creating if needed) the database file at path.
+func OpenSQLite(path string) (*SQLite, error) {
+	if path == "" {
+		return nil, errors.New("sqlite database path is empty")
+	}
+
+	// WAL lets readers (e.g. the query API) run alongside the writer; the
+	// busy timeout covers the short write lock.
+	dsn := "file:" + path + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing %s: %w", path, err)
+	}
+	return &SQLite{db: db, path: path}, nil
+}
+
+func (s *SQLite) String() string { return "sqlite://" + s.path }
+
+// Close closes the database.
+func (s *SQLite) Close() error { return s.db.Close() }
+
+func (s *SQLite) Put(ctx context.Context, key string, data []byte) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, sqliteUpsert, key, data, time.Now().UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("storing %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *SQLite) Get(ctx context.Context, key string) ([]byte, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM documents WHERE key = ?`, key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", key, err)
+	}
+	return data, nil
+}
+
+func (s *SQLite) List(ctx context.Context, prefix string) ([]string, error) {
+	// Keys compare bytewise, so every key with the prefix sorts at or
+	// after it; stop at the first one that no longer matches.
+	rows, err := s.db.QueryContext(ctx, `SELECT key FROM documents WHERE key >= ? ORDER BY key`, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("listing documents: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("listing documents: %w", err)
+		}
+		if !strings.HasPrefix(key, prefix) {
+			break
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing documents: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *SQLite) Delete(ctx context.Context, key string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM documents WHERE key = ?`, key); err != nil {
+		return fmt.Errorf("deleting %s: %w", key, err)
+	}
+	return nil
+}
+
+// Batch applies ops in one transaction.
+func (s *SQLite) Batch(ctx context.Context, ops []Op) error {
+	for _, op := range ops {
+		if err := checkKey(op.Key); err != nil {
+			return err
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	for _, op := range ops {
+		if op.Data == nil {
+			_, err = tx.ExecContext(ctx, `DELETE FROM documents WHERE key = ?`, op.Key)
+		} else {
+			_, err = tx.ExecContext(ctx, sqliteUpsert, op.Key, op.Data, now)
+		}
+		if err != nil {
+			return fmt.Errorf("writing %s: %w", op.Key, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// Package storage persists PBOM documents for the webhook listener. A
+// Storage is a flat key/value store of JSON documents; backends exist for
+// a local directory, S3-compatible object stores and SQLite.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrNotFound is returned by Get for keys that are not stored.
+var ErrNotFound = errors.New("not found")
+
+// Storage stores documents by key. Keys are slash-separated relative
+// paths such as "acme_app_42.pbom.json". Implementations must be safe for
+// concurrent use.
+type Storage interface {
+	// Put stores data under key, replacing any existing document.
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns the document stored under key, or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// List returns the keys starting with prefix, in lexical order.
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Open returns the backend described by a storage URL:
+//
+//	./pbom-data, file:///var/lib/pbom      local directory
+//	s3://bucket/prefix?endpoint=...&region=... S3-compatible object store
+//	sqlite:///var/lib/pbom/pbom.db          SQLite database file
+//
+// S3 credentials are read from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY
+// and AWS_SESSION_TOKEN.
+func Open(rawURL string) (Storage, error) {
+	scheme, rest, ok := strings.Cut(rawURL, "://")
+	if !ok {
+		return NewFS(rawURL)
+	}
+
+	switch scheme {
+	case "file":
+		return NewFS(rest)
+	case "sqlite":
+		return OpenSQLite(rest)
+	case "s3":
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("parsing storage URL: %w", err)
+		}
+		return newS3FromURL(u)
+	default:
+		return nil, fmt.Errorf("unsupported storage scheme %q (expected file, s3 or sqlite)", scheme)
+	}
+}
+
+// checkKey rejects keys that could escape the store's namespace.
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid storage key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid storage key %q", key)
+		}
+	}
+	return nil
+}
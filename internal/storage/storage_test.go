@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// testBackend runs the behavior every Storage must share.
+func testBackend(t *testing.T, s Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, err := s.Get(ctx, "missing.json"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get missing: err = %v, want ErrNotFound", err)
+	}
+
+	docs := map[string]string{
+		"acme_app_1.pbom.json":      `{"id":"1"}`,
+		"acme_app_2.pbom.json":      `{"id":"2"}`,
+		"acme_other_1.pbom.json":    `{"id":"3"}`,
+		"index/sha/abc.json":        `["1"]`,
+		"zeta space+plus.pbom.json": `{"id":"4"}`,
+	}
+	for k, v := range docs {
+		if err := s.Put(ctx, k, []byte(v)); err != nil {
+			t.Fatalf("Put %s: %v", k, err)
+		}
+	}
+
+	for k, v := range docs {
+		got, err := s.Get(ctx, k)
+		if err != nil {
+			t.Fatalf("Get %s: %v", k, err)
+		}
+		if string(got) != v {
+			t.Errorf("Get %s = %s, want %s", k, got, v)
+		}
+	}
+
+	if err := s.Put(ctx, "acme_app_1.pbom.json", []byte(`{"id":"1b"}`)); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+	if got, _ := s.Get(ctx, "acme_app_1.pbom.json"); string(got) != `{"id":"1b"}` {
+		t.Errorf("after overwrite Get = %s", got)
+	}
+
+	listTests := map[string][]string{
+		"acme_app_":   {"acme_app_1.pbom.json", "acme_app_2.pbom.json"},
+		"index/":      {"index/sha/abc.json"},
+		"index/s":     {"index/sha/abc.json"},
+		"index/sha/a": {"index/sha/abc.json"},
+		"index/t":     nil,
+		"index/tag/":  nil,
+		"../":         nil,
+		"nothing":     nil,
+		"": {"acme_app_1.pbom.json", "acme_app_2.pbom.json", "acme_other_1.pbom.json",
+			"index/sha/abc.json", "zeta space+plus.pbom.json"},
+	}
+	for prefix, want := range listTests {
+		got, err := s.List(ctx, prefix)
+		if err != nil {
+			t.Fatalf("List %q: %v", prefix, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("List %q = %v, want %v", prefix, got, want)
+		}
+	}
+
+	if err := s.Delete(ctx, "acme_app_2.pbom.json"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get(ctx, "acme_app_2.pbom.json"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, "acme_app_2.pbom.json"); err != nil {
+		t.Errorf("Delete missing: %v", err)
+	}
+
+	for _, bad := range []string{"", "../escape.json", "/abs.json", "a//b.json"} {
+		if err := s.Put(ctx, bad, []byte("{}")); err == nil {
+			t.Errorf("Put %q: expected invalid key error", bad)
+		}
+	}
+
+	// Concurrent writers must not corrupt each other.
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := s.Put(ctx, fmt.Sprintf("concurrent/%02d.json", i), []byte("{}")); err != nil {
+				t.Errorf("concurrent Put: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if keys, _ := s.List(ctx, "concurrent/"); len(keys) != 16 {
+		t.Errorf("got %d concurrent keys, want 16", len(keys))
+	}
+}
+
+func TestFS(t *testing.T) {
+	s, err := NewFS(filepath.Join(t.TempDir(), "pbom-data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBackend(t, s)
+}
+
+func TestSQLite(t *testing.T) {
+	s, err := OpenSQLite(filepath.Join(t.TempDir(), "pbom.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	testBackend(t, s)
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AWS_ACCESS_KEY_ID", "minio")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "minio123")
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{dir, "file://" + dir},
+		{"file://" + dir, "file://" + dir},
+		{"sqlite://" + filepath.Join(dir, "x.db"), "sqlite://" + filepath.Join(dir, "x.db")},
+		{"s3://pboms/prod?endpoint=http://localhost:9000", "s3://pboms/prod/"},
+	}
+	for _, tt := range tests {
+		s, err := Open(tt.url)
+		if err != nil {
+			t.Errorf("Open(%q): %v", tt.url, err)
+			continue
+		}
+		if got := fmt.Sprint(s); got != tt.want {
+			t.Errorf("Open(%q) = %s, want %s", tt.url, got, tt.want)
+		}
+		if c, ok := s.(interface{ Close() error }); ok {
+			c.Close()
+		}
+	}
+
+	if _, err := Open("ftp://nope"); err == nil {
+		t.Error("expected error for unsupported scheme")
+	}
+}
@@ -0,0 +1,396 @@
+package webhook
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/dsse"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// PBOMSummary is the list representation of a stored PBOM.
+type PBOMSummary struct {
+	ID          string        `json:"id"`
+	Key         string        `json:"key"`
+	Timestamp   time.Time     `json:"timestamp"`
+	Repository  string        `json:"repository"`
+	CommitSHA   string        `json:"commit_sha"`
+	Branch      string        `json:"branch,omitempty"`
+	Workflow    string        `json:"workflow,omitempty"`
+	WorkflowRun string        `json:"workflow_run_id,omitempty"`
+	RunAttempt  int           `json:"run_attempt,omitempty"`
+	Conclusion  string        `json:"conclusion,omitempty"`
+	Artifacts   []ArtifactRef `json:"artifacts,omitempty"`
+}
+
+// ArtifactRef identifies an artifact within a PBOMSummary.
+type ArtifactRef struct {
+	Name   string `json:"name"`
+	Digest string `json:"digest"`
+}
+
+// PBOMList is a page of query results.
+type PBOMList struct {
+	PBOMs         []PBOMSummary `json:"pboms"`
+	NextPageToken string        `json:"next_page_token,omitempty"`
+}
+
+// storedPBOM is a decoded PBOM together with its storage key.
+type storedPBOM struct {
+	key  string
+	pbom *schema.PBOM
+}
+
+func summarize(sp storedPBOM) PBOMSummary {
+	p := sp.pbom
+	s := PBOMSummary{
+		ID:          p.ID,
+		Key:         sp.key,
+		Timestamp:   p.Timestamp,
+		Repository:  p.Source.Repository,
+		CommitSHA:   p.Source.CommitSHA,
+		Branch:      p.Source.Branch,
+		Workflow:    p.Build.WorkflowName,
+		WorkflowRun: p.Build.WorkflowRunID,
+		RunAttempt:  p.Build.RunAttempt,
+		Conclusion:  p.Build.Status,
+	}
+	for _, a := range p.Artifacts {
+		s.Artifacts = append(s.Artifacts, ArtifactRef{Name: a.Name, Digest: a.Digest})
+	}
+	return s
+}
+
+// pbomQuery holds the filters of a list request. Empty fields match
+// everything.
+type pbomQuery struct {
+	id         string
+	repo       string
+	sha        string
+	branch     string
+	workflow   string
+	conclusion string
+	digest     string
+	since      time.Time
+	until      time.Time
+}
+
+func parseQuery(v url.Values) (pbomQuery, error) {
+	q := pbomQuery{
+		repo:       v.Get("repo"),
+		sha:        strings.ToLower(v.Get("sha")),
+		branch:     v.Get("branch"),
+		workflow:   v.Get("workflow"),
+		conclusion: v.Get("conclusion"),
+	}
+	var err error
+	if q.since, err = parseTime(v.Get("since")); err != nil {
+		return q, fmt.Errorf("invalid since: %w", err)
+	}
+	if q.until, err = parseTime(v.Get("until")); err != nil {
+		return q, fmt.Errorf("invalid until: %w", err)
+	}
+	return q, nil
+}
+
+// parseTime accepts RFC 3339 timestamps and plain dates (midnight UTC).
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not an RFC 3339 time or YYYY-MM-DD date", s)
+	}
+	return t, nil
+}
+
+// normalizeDigest lowercases a digest and defaults the algorithm to sha256.
+func normalizeDigest(d string) string {
+	d = strings.ToLower(d)
+	if d != "" && !strings.Contains(d, ":") {
+		d = "sha256:" + d
+	}
+	return d
+}
+
+func (q pbomQuery) matches(p *schema.PBOM) bool {
+	if q.id != "" && p.ID != q.id {
+		return false
+	}
+	if q.repo != "" && !strings.EqualFold(p.Source.Repository, q.repo) {
+		return false
+	}
+	// Abbreviated SHAs match by prefix, as in git.
+	if q.sha != "" && !strings.HasPrefix(strings.ToLower(p.Source.CommitSHA), q.sha) {
+		return false
+	}
+	if q.branch != "" && p.Source.Branch != q.branch {
+		return false
+	}
+	if q.workflow != "" && p.Build.WorkflowName != q.workflow && p.Build.WorkflowFile != q.workflow {
+		return false
+	}
+	if q.conclusion != "" && !strings.EqualFold(p.Build.Status, q.conclusion) {
+		return false
+	}
+	if !q.since.IsZero() && p.Timestamp.Before(q.since) {
+		return false
+	}
+	if !q.until.IsZero() && !p.Timestamp.Before(q.until) {
+		return false
+	}
+	if q.digest != "" && !hasArtifact(p, q.digest) {
+		return false
+	}
+	return true
+}
+
+func hasArtifact(p *schema.PBOM, digest string) bool {
+	for _, a := range p.Artifacts {
+		if strings.EqualFold(a.Digest, digest) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadPBOMs reads the stored PBOMs that may match q, newest first. Queries
+// by digest, SHA, repository or branch are answered from the index; only
+// the rest scan the store. Documents that cannot be decoded are logged and
+// skipped.
+func (s *Server) loadPBOMs(ctx context.Context, q pbomQuery) ([]storedPBOM, error) {
+	keys, err := s.candidateKeys(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	var pboms []storedPBOM
+	for _, key := range keys {
+		p, err := loadPBOM(ctx, s.cfg.Storage, key)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			s.logger.Warn("skipping unreadable PBOM", "key", key, "error", err)
+			continue
+		}
+		pboms = append(pboms, storedPBOM{key: key, pbom: p})
+	}
+
+	sort.SliceStable(pboms, func(i, j int) bool {
+		ti, tj := pboms[i].pbom.Timestamp, pboms[j].pbom.Timestamp
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return pboms[i].key < pboms[j].key
+	})
+	return pboms, nil
+}
+
+func (s *Server) candidateKeys(ctx context.Context, q pbomQuery) ([]string, error) {
+	var term storage.IndexTerm
+	switch {
+	case q.id != "":
+		term = storage.IndexTerm{Kind: storage.IndexID, Value: q.id}
+	case q.digest != "":
+		term = storage.IndexTerm{Kind: storage.IndexDigest, Value: q.digest}
+	case q.sha != "":
+		term = storage.IndexTerm{Kind: storage.IndexSHA, Value: q.sha}
+	case q.repo != "":
+		term = storage.IndexTerm{Kind: storage.IndexRepo, Value: q.repo}
+	case q.branch != "":
+		term = storage.IndexTerm{Kind: storage.IndexBranch, Value: q.branch}
+	default:
+		all, err := s.cfg.Storage.List(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+		var keys []string
+		for _, key := range all {
+			if isPBOMKey(key) {
+				keys = append(keys, key)
+			}
+		}
+		return keys, nil
+	}
+
+	hits, err := storage.Lookup(ctx, s.cfg.Storage, term)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var keys []string
+	for _, h := range hits {
+		if !seen[h.Key] {
+			seen[h.Key] = true
+			keys = append(keys, h.Key)
+		}
+	}
+	return keys, nil
+}
+
+// decodeStored unwraps a signed envelope, if any, and decodes the PBOM.
+func decodeStored(data []byte) (*schema.PBOM, error) {
+	payload, _, err := dsse.Unwrap(data, schema.MediaType)
+	if err != nil {
+		return nil, err
+	}
+	return schema.Decode(payload)
+}
+
+// hasAPIToken reports whether r carries the configured bearer token. It
+// is always false when no token is configured.
+func (s *Server) hasAPIToken(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && s.cfg.APIToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.APIToken)) == 1
+}
+
+// requireToken rejects requests without the configured bearer token.
+func (s *Server) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.hasAPIToken(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="pbom"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleListPBOMs serves GET /api/v1/pboms.
+func (s *Server) handleListPBOMs(w http.ResponseWriter, r *http.Request) {
+	q, err := parseQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.servePBOMList(w, r, q)
+}
+
+// handleArtifactPBOMs serves GET /api/v1/artifacts/{digest}/pboms, the
+// builds that produced an artifact.
+func (s *Server) handleArtifactPBOMs(w http.ResponseWriter, r *http.Request) {
+	q, err := parseQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	q.digest = normalizeDigest(r.PathValue("digest"))
+	s.servePBOMList(w, r, q)
+}
+
+func (s *Server) servePBOMList(w http.ResponseWriter, r *http.Request, q pbomQuery) {
+	limit, offset, err := parsePage(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pboms, err := s.loadPBOMs(r.Context(), q)
+	if err != nil {
+		s.logger.Error("failed to load PBOMs", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to read storage")
+		return
+	}
+
+	list := PBOMList{PBOMs: []PBOMSummary{}}
+	matched := 0
+	for _, sp := range pboms {
+		if !q.matches(sp.pbom) {
+			continue
+		}
+		matched++
+		if matched <= offset {
+			continue
+		}
+		if len(list.PBOMs) == limit {
+			list.NextPageToken = encodePageToken(offset + limit)
+			break
+		}
+		list.PBOMs = append(list.PBOMs, summarize(sp))
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handleGetPBOM serves GET /api/v1/pboms/{id}.
+func (s *Server) handleGetPBOM(w http.ResponseWriter, r *http.Request) {
+	q := pbomQuery{id: r.PathValue("id")}
+	pboms, err := s.loadPBOMs(r.Context(), q)
+	if err != nil {
+		s.logger.Error("failed to load PBOMs", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to read storage")
+		return
+	}
+	for _, sp := range pboms {
+		if q.matches(sp.pbom) {
+			writeJSON(w, http.StatusOK, sp.pbom)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "PBOM not found")
+}
+
+// parsePage reads the limit and page_token parameters.
+func parsePage(v url.Values) (limit, offset int, err error) {
+	limit = defaultPageSize
+	if s := v.Get("limit"); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+	}
+	if tok := v.Get("page_token"); tok != "" {
+		if offset, err = decodePageToken(tok); err != nil {
+			return 0, 0, err
+		}
+	}
+	return limit, offset, nil
+}
+
+// Page tokens are opaque to clients; today they encode a result offset.
+func encodePageToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("o:" + strconv.Itoa(offset)))
+}
+
+func decodePageToken(tok string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(tok)
+	if err == nil {
+		if s, ok := strings.CutPrefix(string(raw), "o:"); ok {
+			if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+				return n, nil
+			}
+		}
+	}
+	return 0, errors.New("invalid page_token")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,268 @@
+package webhook
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+)
+
+const testAPIToken = "s3cret-token"
+
+// newAPITestServer stores three PBOMs (one signed) and returns a server
+// with the query API enabled.
+func newAPITestServer(t *testing.T) *Server {
+	t.Helper()
+	st, err := storage.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, signer, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	docs := []struct {
+		pbom   *schema.PBOM
+		runID  int64
+		signed bool
+	}{
+		{
+			pbom: &schema.PBOM{
+				PBOMVersion: schema.Version, ID: "pbom-1", Timestamp: base,
+				Source:    schema.Source{Repository: "acme/app", CommitSHA: "aaaaaaa111", Branch: "main"},
+				Build:     schema.Build{WorkflowRunID: "1", WorkflowName: "CI", WorkflowFile: ".github/workflows/ci.yml", Actor: "dev", Status: "success"},
+				Artifacts: []schema.Artifact{{Name: "ghcr.io/acme/app", Type: "container-image", Digest: "sha256:abc123"}},
+			},
+			runID: 1,
+		},
+		{
+			pbom: &schema.PBOM{
+				PBOMVersion: schema.Version, ID: "pbom-2", Timestamp: base.Add(24 * time.Hour),
+				Source: schema.Source{Repository: "acme/app", CommitSHA: "bbbbbbb222", Branch: "feature"},
+				Build:  schema.Build{WorkflowRunID: "2", WorkflowName: "CI", Actor: "dev", Status: "failure"},
+			},
+			runID:  2,
+			signed: true,
+		},
+		{
+			pbom: &schema.PBOM{
+				PBOMVersion: schema.Version, ID: "pbom-3", Timestamp: base.Add(48 * time.Hour),
+				Source:    schema.Source{Repository: "acme/api", CommitSHA: "ccccccc333", Branch: "main"},
+				Build:     schema.Build{WorkflowRunID: "3", WorkflowName: "Release", Actor: "dev", Status: "success"},
+				Artifacts: []schema.Artifact{{Name: "ghcr.io/acme/api", Type: "container-image", Digest: "sha256:abc123"}},
+			},
+			runID: 3,
+		},
+	}
+	for _, d := range docs {
+		var key crypto.Signer
+		if d.signed {
+			key = signer
+		}
+		owner, repo, _ := strings.Cut(d.pbom.Source.Repository, "/")
+		if _, err := Store(context.Background(), st, d.pbom, key, owner, repo, d.runID, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Unrelated keys and unreadable documents are ignored.
+	st.Put(context.Background(), "notes.txt", []byte("hello"))
+	st.Put(context.Background(), "broken_x_9.pbom.json", []byte("{"))
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewServer(Config{Storage: st, APIToken: testAPIToken}, logger)
+}
+
+func apiGet(t *testing.T, s *Server, path, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func listIDs(t *testing.T, rec *httptest.ResponseRecorder) ([]string, string) {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	var list PBOMList
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, p := range list.PBOMs {
+		ids = append(ids, p.ID)
+	}
+	return ids, list.NextPageToken
+}
+
+func TestAPIListFilters(t *testing.T) {
+	s := newAPITestServer(t)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"pbom-3", "pbom-2", "pbom-1"}},
+		{"repo=acme/app", []string{"pbom-2", "pbom-1"}},
+		{"repo=ACME/API", []string{"pbom-3"}},
+		{"sha=bbbbbbb", []string{"pbom-2"}},
+		{"branch=main", []string{"pbom-3", "pbom-1"}},
+		{"workflow=CI", []string{"pbom-2", "pbom-1"}},
+		{"workflow=.github/workflows/ci.yml", []string{"pbom-1"}},
+		{"conclusion=success", []string{"pbom-3", "pbom-1"}},
+		{"since=2026-03-02", []string{"pbom-3", "pbom-2"}},
+		{"until=2026-03-02T12:00:00Z", []string{"pbom-1"}},
+		{"repo=acme/app&conclusion=success&branch=main", []string{"pbom-1"}},
+		{"repo=nope", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, _ := listIDs(t, apiGet(t, s, "/api/v1/pboms?"+tt.query, testAPIToken))
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	if rec := apiGet(t, s, "/api/v1/pboms?since=yesterday", testAPIToken); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad since: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestAPIPagination(t *testing.T) {
+	s := newAPITestServer(t)
+
+	var all []string
+	path := "/api/v1/pboms?limit=2"
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination did not terminate")
+		}
+		ids, next := listIDs(t, apiGet(t, s, path, testAPIToken))
+		all = append(all, ids...)
+		if next == "" {
+			break
+		}
+		path = "/api/v1/pboms?limit=2&page_token=" + next
+	}
+	if len(all) != 3 || all[0] != "pbom-3" || all[2] != "pbom-1" {
+		t.Errorf("paged results = %v", all)
+	}
+
+	for _, q := range []string{"limit=0", "limit=501", "page_token=garbage"} {
+		if rec := apiGet(t, s, "/api/v1/pboms?"+q, testAPIToken); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", q, rec.Code)
+		}
+	}
+}
+
+func TestAPIGetPBOM(t *testing.T) {
+	s := newAPITestServer(t)
+
+	// pbom-2 is stored signed; the API returns the PBOM itself.
+	rec := apiGet(t, s, "/api/v1/pboms/pbom-2", testAPIToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	var p schema.PBOM
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != "pbom-2" || p.Source.CommitSHA != "bbbbbbb222" {
+		t.Errorf("got PBOM %s at %s", p.ID, p.Source.CommitSHA)
+	}
+
+	if rec := apiGet(t, s, "/api/v1/pboms/missing", testAPIToken); rec.Code != http.StatusNotFound {
+		t.Errorf("missing: status = %d, want 404", rec.Code)
+	}
+}
+
+// noScan is a Storage that fails listings of the whole store.
+type noScan struct{ storage.Storage }
+
+func (s noScan) List(ctx context.Context, prefix string) ([]string, error) {
+	if prefix == "" {
+		return nil, errors.New("scanned the whole store")
+	}
+	return s.Storage.List(ctx, prefix)
+}
+
+func TestAPIUsesIndex(t *testing.T) {
+	s := newAPITestServer(t)
+	s.cfg.Storage = noScan{s.cfg.Storage}
+
+	if rec := apiGet(t, s, "/api/v1/pboms/pbom-3", testAPIToken); rec.Code != http.StatusOK {
+		t.Errorf("get by ID: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if rec := apiGet(t, s, "/api/v1/pboms/missing", testAPIToken); rec.Code != http.StatusNotFound {
+		t.Errorf("missing: status = %d, want 404", rec.Code)
+	}
+	for query, want := range map[string]string{
+		"repo=ACME/app":                  "[pbom-2 pbom-1]",
+		"branch=main&conclusion=success": "[pbom-3 pbom-1]",
+	} {
+		got, _ := listIDs(t, apiGet(t, s, "/api/v1/pboms?"+query, testAPIToken))
+		if fmt.Sprint(got) != want {
+			t.Errorf("%s: got %v, want %s", query, got, want)
+		}
+	}
+}
+
+func TestAPIArtifactPBOMs(t *testing.T) {
+	s := newAPITestServer(t)
+
+	for _, digest := range []string{"sha256:abc123", "SHA256:ABC123", "abc123"} {
+		got, _ := listIDs(t, apiGet(t, s, "/api/v1/artifacts/"+digest+"/pboms", testAPIToken))
+		if len(got) != 2 || got[0] != "pbom-3" || got[1] != "pbom-1" {
+			t.Errorf("%s: got %v, want [pbom-3 pbom-1]", digest, got)
+		}
+	}
+	got, _ := listIDs(t, apiGet(t, s, "/api/v1/artifacts/sha256:abc123/pboms?repo=acme/app", testAPIToken))
+	if len(got) != 1 || got[0] != "pbom-1" {
+		t.Errorf("with repo filter: got %v", got)
+	}
+}
+
+func TestAPIAuth(t *testing.T) {
+	s := newAPITestServer(t)
+
+	for _, token := range []string{"", "wrong"} {
+		rec := apiGet(t, s, "/api/v1/pboms", token)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("token %q: status = %d, want 401", token, rec.Code)
+		}
+		if rec.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("token %q: missing WWW-Authenticate", token)
+		}
+	}
+
+	// Without a configured token the API is not served at all.
+	disabled := NewServer(Config{Storage: s.cfg.Storage}, s.logger)
+	if rec := apiGet(t, disabled, "/api/v1/pboms", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("disabled API: status = %d, want 404", rec.Code)
+	}
+}
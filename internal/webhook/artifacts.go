@@ -2,7 +2,6 @@ package webhook
 
 import (
 	"archive/zip"
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -36,7 +35,7 @@ func ExtractDockerArtifacts(ctx context.Context, client *gh.Client, owner, repo
 			continue
 		}
 
-		meta, err := downloadAndParseDockerMetadata(ctx, client, art.ArchiveDownloadURL)
+		meta, err := downloadAndParseDockerMetadata(ctx, client, art)
 		if err != nil {
 			logger.Warn("failed to parse docker metadata artifact", "name", art.Name, "error", err)
 			continue
@@ -62,27 +61,27 @@ func ExtractDockerArtifacts(ctx context.Context, client *gh.Client, owner, repo
 	return result
 }
 
-func downloadAndParseDockerMetadata(ctx context.Context, client *gh.Client, downloadURL string) (*DockerMetadata, error) {
-	zipData, err := client.DownloadArtifact(ctx, downloadURL)
+func downloadAndParseDockerMetadata(ctx context.Context, client *gh.Client, art gh.Artifact) (*DockerMetadata, error) {
+	zipFile, err := client.DownloadArtifact(ctx, art)
 	if err != nil {
 		return nil, fmt.Errorf("downloading artifact: %w", err)
 	}
+	defer zipFile.Close()
 
-	reader, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+	reader, err := zip.NewReader(zipFile, zipFile.Size)
 	if err != nil {
 		return nil, fmt.Errorf("opening zip: %w", err)
 	}
 
 	for _, f := range reader.File {
 		if strings.HasSuffix(f.Name, ".json") {
-			rc, err := f.Open()
+			data, err := readZipEntry(f)
 			if err != nil {
-				return nil, fmt.Errorf("opening %s: %w", f.Name, err)
+				return nil, err
 			}
-			defer rc.Close()
 
 			var meta DockerMetadata
-			if err := json.NewDecoder(rc).Decode(&meta); err != nil {
+			if err := json.Unmarshal(data, &meta); err != nil {
 				return nil, fmt.Errorf("parsing %s: %w", f.Name, err)
 			}
 			return &meta, nil
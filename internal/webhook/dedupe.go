@@ -0,0 +1,158 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
+)
+
+const (
+	dedupeDeliveryPrefix = "dedupe/delivery/"
+	dedupeRunPrefix      = "dedupe/run/"
+)
+
+// Reasons a delivery is a duplicate.
+const (
+	DuplicateDelivery = "delivery" // same X-GitHub-Delivery ID
+	DuplicateRun      = "run"      // same repository, run ID and attempt
+)
+
+// Deduper records accepted webhook deliveries so that GitHub redeliveries
+// and manual "Redeliver" clicks are acknowledged without re-enrichment.
+// Records older than the retention period are pruned.
+type Deduper struct {
+	st        storage.Storage
+	retention time.Duration
+	now       func() time.Time
+
+	mu sync.Mutex // serializes check-and-record
+}
+
+// dedupeRecord is the stored form of an accepted delivery.
+type dedupeRecord struct {
+	AcceptedAt time.Time `json:"accepted_at"`
+	Delivery   string    `json:"delivery,omitempty"`
+	Run        string    `json:"run"`
+}
+
+// NewDeduper returns a Deduper persisting its records in st. A retention
+// of zero keeps records for 30 days.
+func NewDeduper(st storage.Storage, retention time.Duration) *Deduper {
+	if retention <= 0 {
+		retention = 30 * 24 * time.Hour
+	}
+	return &Deduper{st: st, retention: retention, now: time.Now}
+}
+
+// RunKey identifies one attempt of a workflow run.
+func RunKey(event WebhookEvent) string {
+	return fmt.Sprintf("%s/%d/%d", event.Repository.FullName, event.WorkflowRun.ID, event.WorkflowRun.RunAttempt)
+}
+
+func dedupeKey(prefix, id string) string {
+	return prefix + url.PathEscape(id) + ".json"
+}
+
+// Claim records a delivery unless it duplicates one already accepted, in
+// which case it returns DuplicateDelivery or DuplicateRun. With force the
+// checks are skipped and the records overwritten. deliveryID may be empty.
+func (d *Deduper) Claim(ctx context.Context, deliveryID, runKey string, force bool) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !force {
+		if deliveryID != "" {
+			if seen, err := d.seen(ctx, dedupeKey(dedupeDeliveryPrefix, deliveryID)); err != nil || seen {
+				return DuplicateDelivery, err
+			}
+		}
+		if seen, err := d.seen(ctx, dedupeKey(dedupeRunPrefix, runKey)); err != nil || seen {
+			return DuplicateRun, err
+		}
+	}
+
+	rec, err := json.Marshal(dedupeRecord{AcceptedAt: d.now().UTC(), Delivery: deliveryID, Run: runKey})
+	if err != nil {
+		return "", err
+	}
+	ops := []storage.Op{{Key: dedupeKey(dedupeRunPrefix, runKey), Data: rec}}
+	if deliveryID != "" {
+		ops = append(ops, storage.Op{Key: dedupeKey(dedupeDeliveryPrefix, deliveryID), Data: rec})
+	}
+	if err := storage.Apply(ctx, d.st, ops); err != nil {
+		return "", fmt.Errorf("recording delivery: %w", err)
+	}
+	return "", nil
+}
+
+// seen reports whether key holds an unexpired record.
+func (d *Deduper) seen(ctx context.Context, key string) (bool, error) {
+	data, err := d.st.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var rec dedupeRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return false, nil // unreadable record; let the delivery through
+	}
+	return d.now().Sub(rec.AcceptedAt) < d.retention, nil
+}
+
+// Release forgets a claimed delivery, e.g. when it could not be queued,
+// so that a redelivery is processed.
+func (d *Deduper) Release(ctx context.Context, deliveryID, runKey string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ops := []storage.Op{{Key: dedupeKey(dedupeRunPrefix, runKey)}}
+	if deliveryID != "" {
+		ops = append(ops, storage.Op{Key: dedupeKey(dedupeDeliveryPrefix, deliveryID)})
+	}
+	return storage.Apply(ctx, d.st, ops)
+}
+
+// Prune deletes records older than the retention period and returns how
+// many were removed.
+func (d *Deduper) Prune(ctx context.Context) (int, error) {
+	n := 0
+	for _, prefix := range []string{dedupeDeliveryPrefix, dedupeRunPrefix} {
+		keys, err := d.st.List(ctx, prefix)
+		if err != nil {
+			return n, err
+		}
+		for _, key := range keys {
+			if !strings.HasSuffix(key, ".json") {
+				continue
+			}
+			removed, err := d.pruneKey(ctx, key)
+			if err != nil {
+				return n, err
+			}
+			if removed {
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
+func (d *Deduper) pruneKey(ctx context.Context, key string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	seen, err := d.seen(ctx, key)
+	if err != nil || seen {
+		return false, err
+	}
+	return true, d.st.Delete(ctx, key)
+}
@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
+)
+
+func TestDeduper(t *testing.T) {
+	ctx := context.Background()
+	st, err := storage.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	d := NewDeduper(st, 24*time.Hour)
+	d.now = func() time.Time { return now }
+
+	claim := func(delivery, run string, force bool, want string) {
+		t.Helper()
+		got, err := d.Claim(ctx, delivery, run, force)
+		if err != nil {
+			t.Fatalf("Claim(%q, %q): %v", delivery, run, err)
+		}
+		if got != want {
+			t.Errorf("Claim(%q, %q, force=%v) = %q, want %q", delivery, run, force, got, want)
+		}
+	}
+
+	claim("d-1", "acme/app/1/1", false, "")
+	claim("d-1", "acme/app/1/1", false, DuplicateDelivery)
+	claim("d-2", "acme/app/1/1", false, DuplicateRun)
+	claim("", "acme/app/1/1", false, DuplicateRun)
+	claim("d-3", "acme/app/1/2", false, "")
+	claim("d-1", "acme/app/1/1", true, "")
+
+	// A released claim can be made again.
+	if err := d.Release(ctx, "d-4", "acme/app/2/1"); err != nil {
+		t.Fatal(err)
+	}
+	claim("d-4", "acme/app/2/1", false, "")
+	if err := d.Release(ctx, "d-4", "acme/app/2/1"); err != nil {
+		t.Fatal(err)
+	}
+	claim("d-4", "acme/app/2/1", false, "")
+
+	// Records expire after the retention period.
+	now = now.Add(25 * time.Hour)
+	claim("d-5", "acme/app/3/1", false, "")
+	n, err := d.Prune(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// d-1, d-3, d-4 and their runs; d-5 is fresh.
+	if n != 6 {
+		t.Errorf("Prune removed %d records, want 6", n)
+	}
+	claim("d-1", "acme/app/1/1", false, "")
+	claim("d-5", "acme/app/3/1", false, DuplicateDelivery)
+}
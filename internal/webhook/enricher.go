@@ -2,77 +2,127 @@ package webhook
 
 import (
 	"context"
+	"crypto"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
 	"strings"
 	"time"
 
 	gh "github.com/BuildGuard-Test-Lab/pbom/internal/github"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
 	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+	"github.com/google/uuid"
 )
 
 // Enricher performs PBOM enrichment from GitHub API data.
 type Enricher struct {
-	ghClient   *gh.Client
-	storageDir string
-	logger     *slog.Logger
+	ghClient *gh.Client
+	store    storage.Storage
+	signer   crypto.Signer // nil stores unsigned PBOMs
+	logger   *slog.Logger
+	metrics  *serverMetrics // nil records nothing
+
+	noWait bool    // look for the collector run once instead of retrying
+	app    *gh.App // if set, each event uses a client for its installation
 }
 
-// NewEnricher creates an Enricher.
-func NewEnricher(ghClient *gh.Client, storageDir string, logger *slog.Logger) *Enricher {
+// NewEnricher creates an Enricher. If signer is non-nil, stored PBOMs are
+// wrapped in signed DSSE envelopes.
+func NewEnricher(ghClient *gh.Client, store storage.Storage, signer crypto.Signer, logger *slog.Logger) *Enricher {
 	return &Enricher{
-		ghClient:   ghClient,
-		storageDir: storageDir,
-		logger:     logger,
+		ghClient: ghClient,
+		store:    store,
+		signer:   signer,
+		logger:   logger,
 	}
 }
 
-// Enrich is the main enrichment pipeline for a completed workflow run.
-func (e *Enricher) Enrich(parentCtx context.Context, event WebhookEvent) {
-	// Use a fresh context with timeout (the HTTP request context may already be done)
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+// Enrich is the main enrichment pipeline for a completed workflow run. It
+// returns an error when the run should be retried: the GitHub API could
+// not list the run's jobs, or the PBOM could not be stored.
+func (e *Enricher) Enrich(parentCtx context.Context, event WebhookEvent) (err error) {
+	defer e.metrics.step("total", time.Now())
+	defer func() { e.metrics.enrichment(err) }()
+
+	ctx, cancel := context.WithTimeout(parentCtx, 2*time.Minute)
 	defer cancel()
 
 	owner := event.Repository.Owner.Login
 	repo := event.Repository.Name
 	runID := event.WorkflowRun.ID
+	attempt := event.WorkflowRun.RunAttempt
 	headSHA := event.WorkflowRun.HeadSHA
 
 	log := e.logger.With(
 		"repo", event.Repository.FullName,
 		"run_id", runID,
+		"run_attempt", attempt,
 		"sha", headSHA[:min(8, len(headSHA))],
 	)
+	if event.Enterprise != nil {
+		log = log.With("enterprise", event.Enterprise.Slug)
+	}
 
-	// Step 1: Find the companion PBOM Collector run (with retry for race condition)
-	pbom, err := e.findSkeletonWithRetry(ctx, owner, repo, headSHA, log)
+	client, err := e.clientFor(ctx, event)
 	if err != nil {
+		return err
+	}
+
+	// Step 1: Find the companion PBOM Collector run (with retry for race condition)
+	start := time.Now()
+	pbom, err := e.findSkeletonWithRetry(ctx, client, owner, repo, headSHA, log)
+	e.metrics.step("skeleton", start)
+	e.metrics.skeleton(err)
+	if errors.Is(err, gh.ErrRateLimited) {
+		// Retry the whole event later rather than store a PBOM missing
+		// the skeleton only because the API was busy.
+		return err
+	}
+	switch {
+	case errors.Is(err, gh.ErrDigestMismatch):
+		// The archive is not what the collector uploaded; build the PBOM
+		// from the API alone rather than trust its contents.
+		log.Error("skeleton PBOM failed its integrity check, creating from scratch", "error", err)
+		pbom = e.buildFallbackPBOM(event)
+	case err != nil:
 		log.Warn("could not find skeleton PBOM, creating from scratch", "error", err)
 		pbom = e.buildFallbackPBOM(event)
+	default:
+		// Every attempt of a commit reads the same skeleton, so give the
+		// enriched PBOM its own ID; the skeleton's stays in build.skeleton.
+		pbom.ID = uuid.NewString()
+		log.Info("using skeleton PBOM", "artifact_id", pbom.Build.Skeleton.ArtifactID,
+			"digest", pbom.Build.Skeleton.Digest, "verified", pbom.Build.Skeleton.Verified)
 	}
 
 	// Step 2: Get jobs from the developer's CI run
-	jobs, err := e.ghClient.GetJobs(ctx, owner, repo, runID)
+	start = time.Now()
+	jobs, err := client.GetJobs(ctx, owner, repo, runID)
+	e.metrics.step("jobs", start)
 	if err != nil {
-		log.Error("failed to get jobs", "error", err)
-	} else {
-		// Enrich runner
-		if runner := ExtractRunner(jobs); runner != nil {
-			pbom.Build.Runner = runner
-			log.Info("enriched runner", "os", runner.OS, "arch", runner.Arch, "self_hosted", runner.SelfHosted)
-		}
+		return fmt.Errorf("getting jobs: %w", err)
+	}
 
-		// Enrich timestamps
-		started, completed := ExtractTimestamps(jobs)
-		if started != nil {
-			pbom.Build.StartedAt = started
-		}
-		if completed != nil {
-			pbom.Build.CompletedAt = completed
-		}
+	// Enrich runner
+	if runner := ExtractRunner(jobs); runner != nil {
+		pbom.Build.Runner = runner
+		log.Info("enriched runner", "os", runner.OS, "arch", runner.Arch, "self_hosted", runner.SelfHosted)
+	}
+
+	// Enrich timestamps
+	started, completed := ExtractTimestamps(jobs)
+	if started != nil {
+		pbom.Build.StartedAt = started
+	}
+	if completed != nil {
+		pbom.Build.CompletedAt = completed
 	}
 
 	// Step 3: Update build status and metadata from the developer CI (not the collector)
+	pbom.Build.WorkflowRunID = strconv.FormatInt(runID, 10)
+	pbom.Build.RunAttempt = attempt
 	pbom.Build.Status = event.WorkflowRun.Conclusion
 	pbom.Build.WorkflowName = event.WorkflowRun.Name
 	pbom.Build.WorkflowFile = event.WorkflowRun.Path
@@ -80,7 +130,8 @@ func (e *Enricher) Enrich(parentCtx context.Context, event WebhookEvent) {
 	// Step 4: Extract secrets from workflow YAML
 	workflowPath := event.WorkflowRun.Path
 	if workflowPath != "" {
-		yamlContent, err := e.ghClient.GetWorkflowContent(ctx, owner, repo, workflowPath, headSHA)
+		start = time.Now()
+		yamlContent, err := client.GetWorkflowContent(ctx, owner, repo, workflowPath, headSHA)
 		if err != nil {
 			log.Warn("failed to fetch workflow YAML", "path", workflowPath, "error", err)
 		} else {
@@ -90,33 +141,73 @@ func (e *Enricher) Enrich(parentCtx context.Context, event WebhookEvent) {
 				log.Info("enriched secrets", "count", len(secrets), "secrets", strings.Join(secrets, ","))
 			}
 		}
+		e.metrics.step("secrets", start)
 	}
 
 	// Step 5: Extract Docker artifacts from the developer's CI run
-	dockerArtifacts := ExtractDockerArtifacts(ctx, e.ghClient, owner, repo, runID, log)
+	start = time.Now()
+	dockerArtifacts := ExtractDockerArtifacts(ctx, client, owner, repo, runID, log)
+	e.metrics.step("artifacts", start)
 	if len(dockerArtifacts) > 0 {
 		pbom.Artifacts = append(pbom.Artifacts, dockerArtifacts...)
 		log.Info("enriched artifacts", "count", len(dockerArtifacts))
 	}
 
 	// Step 6: Store the enriched PBOM
-	path, err := Store(e.storageDir, pbom, owner, repo, runID)
+	start = time.Now()
+	key, err := Store(ctx, e.store, pbom, e.signer, owner, repo, runID, attempt)
+	e.metrics.step("store", start)
 	if err != nil {
-		log.Error("failed to store enriched PBOM", "error", err)
-		return
+		return fmt.Errorf("storing enriched PBOM: %w", err)
 	}
 
 	log.Info("enriched PBOM stored",
-		"path", path,
+		"key", key,
 		"artifacts", len(pbom.Artifacts),
 		"secrets", len(pbom.Build.SecretsAccessed),
+		"signed", e.signer != nil,
 	)
+	return nil
+}
+
+// UseApp makes Enrich authenticate as the GitHub App installation that
+// delivered each event, instead of with the Enricher's client. Events
+// without an installation ID, such as replayed org webhooks, use the
+// installation covering their repository.
+func (e *Enricher) UseApp(app *gh.App) {
+	e.app = app
+}
+
+// clientFor returns the GitHub client to enrich an event with.
+func (e *Enricher) clientFor(ctx context.Context, event WebhookEvent) (*gh.Client, error) {
+	if e.app == nil {
+		return e.ghClient, nil
+	}
+	id := event.Installation.ID
+	if id == 0 {
+		var err error
+		id, err = e.app.RepoInstallation(ctx, event.Repository.Owner.Login, event.Repository.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return e.app.Client(id), nil
+}
+
+// WaitForCollector sets whether Enrich waits, for up to 100 seconds, for
+// a PBOM Collector run that has not completed yet. It is on by default;
+// replays of runs that finished long ago turn it off.
+func (e *Enricher) WaitForCollector(wait bool) {
+	e.noWait = !wait
 }
 
 // findSkeletonWithRetry attempts to find and download the skeleton PBOM,
 // retrying if the collector run hasn't completed yet.
-func (e *Enricher) findSkeletonWithRetry(ctx context.Context, owner, repo, headSHA string, log *slog.Logger) (*schema.PBOM, error) {
+func (e *Enricher) findSkeletonWithRetry(ctx context.Context, client *gh.Client, owner, repo, headSHA string, log *slog.Logger) (*schema.PBOM, error) {
 	delays := []time.Duration{0, 10 * time.Second, 30 * time.Second, 60 * time.Second}
+	if e.noWait {
+		delays = delays[:1]
+	}
 
 	for attempt, delay := range delays {
 		if delay > 0 {
@@ -128,15 +219,15 @@ func (e *Enricher) findSkeletonWithRetry(ctx context.Context, owner, repo, headS
 			}
 		}
 
-		collectorRun, err := FindCollectorRun(ctx, e.ghClient, owner, repo, headSHA)
+		collectorRun, err := FindCollectorRun(ctx, client, owner, repo, headSHA)
 		if err != nil {
-			if attempt < len(delays)-1 {
+			if attempt < len(delays)-1 && !errors.Is(err, gh.ErrRateLimited) {
 				continue
 			}
-			return nil, fmt.Errorf("after %d attempts: %w", len(delays), err)
+			return nil, fmt.Errorf("after %d attempts: %w", attempt+1, err)
 		}
 
-		pbom, err := DownloadSkeletonPBOM(ctx, e.ghClient, owner, repo, collectorRun.ID)
+		pbom, err := DownloadSkeletonPBOM(ctx, client, owner, repo, collectorRun.ID)
 		if err != nil {
 			return nil, fmt.Errorf("downloading skeleton: %w", err)
 		}
@@ -152,7 +243,7 @@ func (e *Enricher) buildFallbackPBOM(event WebhookEvent) *schema.PBOM {
 	now := time.Now().UTC()
 	return &schema.PBOM{
 		PBOMVersion: schema.Version,
-		ID:          fmt.Sprintf("fallback-%d", event.WorkflowRun.ID),
+		ID:          uuid.New().String(),
 		Timestamp:   now,
 		Source: schema.Source{
 			Repository: event.Repository.FullName,
@@ -162,6 +253,7 @@ func (e *Enricher) buildFallbackPBOM(event WebhookEvent) *schema.PBOM {
 		},
 		Build: schema.Build{
 			WorkflowRunID: fmt.Sprintf("%d", event.WorkflowRun.ID),
+			RunAttempt:    event.WorkflowRun.RunAttempt,
 			WorkflowName:  event.WorkflowRun.Name,
 			WorkflowFile:  event.WorkflowRun.Path,
 			Trigger:       event.WorkflowRun.Event,
@@ -1,22 +1,36 @@
 package webhook
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	gh "github.com/BuildGuard-Test-Lab/pbom/internal/github"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/queue"
 )
 
 // WebhookEvent represents the top-level workflow_run webhook payload.
 type WebhookEvent struct {
-	Action      string      `json:"action"`
-	WorkflowRun RunPayload  `json:"workflow_run"`
-	Repository  RepoPayload `json:"repository"`
+	Action       string              `json:"action"`
+	WorkflowRun  RunPayload          `json:"workflow_run"`
+	Repository   RepoPayload         `json:"repository"`
+	Installation InstallationPayload `json:"installation"`
+	// Enterprise is set for deliveries from GitHub Enterprise Server and
+	// from repositories of enterprise accounts.
+	Enterprise *EnterprisePayload `json:"enterprise,omitempty"`
 }
 
 // RunPayload is the workflow_run object within the webhook event.
 type RunPayload struct {
 	ID         int64  `json:"id"`
+	RunAttempt int    `json:"run_attempt"`
 	Name       string `json:"name"`
 	HeadSHA    string `json:"head_sha"`
 	HeadBranch string `json:"head_branch"`
@@ -38,6 +52,56 @@ type RepoPayload struct {
 	} `json:"owner"`
 }
 
+// EventFromRun builds the workflow_run.completed event that GitHub would
+// have delivered for a run listed through the API, so that past runs can
+// be enriched like live ones.
+func EventFromRun(run gh.WorkflowRun, repo gh.Repo) WebhookEvent {
+	event := WebhookEvent{Action: "completed"}
+	event.WorkflowRun = RunPayload{
+		ID:         run.ID,
+		RunAttempt: run.RunAttempt,
+		Name:       run.Name,
+		HeadSHA:    run.HeadSHA,
+		HeadBranch: run.HeadBranch,
+		Path:       run.Path,
+		Event:      run.Event,
+		Status:     run.Status,
+		Conclusion: run.Conclusion,
+	}
+	event.WorkflowRun.Actor.Login = run.Actor.Login
+	event.Repository.Name = repo.Name
+	event.Repository.FullName = repo.FullName
+	event.Repository.Owner.Login = repo.Owner.Login
+	return event
+}
+
+// IgnoreReason reports why a workflow_run event is not enriched, or ""
+// if it should be.
+func IgnoreReason(event WebhookEvent) string {
+	// Only process completed runs
+	if event.Action != "completed" {
+		return "action " + event.Action
+	}
+	// Skip PBOM Collector runs to prevent infinite enrichment loops
+	if event.WorkflowRun.Name == "PBOM Collector" {
+		return "PBOM Collector run"
+	}
+	return ""
+}
+
+// InstallationPayload identifies the GitHub App installation that a
+// webhook was delivered for. It is absent for org and repository webhooks.
+type InstallationPayload struct {
+	ID int64 `json:"id"`
+}
+
+// EnterprisePayload identifies the enterprise account a webhook was
+// delivered for.
+type EnterprisePayload struct {
+	ID   int64  `json:"id"`
+	Slug string `json:"slug"`
+}
+
 // handleWebhook processes incoming GitHub webhook POST requests.
 func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -49,6 +113,7 @@ func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(io.LimitReader(r.Body, 10<<20))
 	if err != nil {
 		s.logger.Error("failed to read request body", "error", err)
+		s.metrics.delivery(unverifiedEvent, outcomeBadRequest)
 		http.Error(w, "failed to read body", http.StatusBadRequest)
 		return
 	}
@@ -57,6 +122,7 @@ func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	sig := r.Header.Get("X-Hub-Signature-256")
 	if err := VerifySignature(body, sig, s.cfg.WebhookSecret); err != nil {
 		s.logger.Warn("signature verification failed", "error", err)
+		s.metrics.delivery(unverifiedEvent, outcomeInvalidSignature)
 		http.Error(w, "invalid signature", http.StatusUnauthorized)
 		return
 	}
@@ -65,31 +131,39 @@ func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	eventType := r.Header.Get("X-GitHub-Event")
 	if eventType != "workflow_run" {
 		s.logger.Debug("ignoring non-workflow_run event", "type", eventType)
+		s.metrics.delivery(eventType, outcomeIgnored)
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
+	// A GitHub Enterprise Server names itself in each delivery. Its runs
+	// can only be enriched through its own API.
+	if host := r.Header.Get("X-GitHub-Enterprise-Host"); host != "" && !s.fromConfiguredGitHub(host) {
+		s.logger.Warn("ignoring delivery from another GitHub instance",
+			"enterprise_host", host, "github_url", s.ghClient.BaseURL())
+		s.metrics.delivery(eventType, outcomeIgnored)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	s.archive(r.Context(), r.Header.Get("X-GitHub-Delivery"), body)
+
 	// Parse event
 	var event WebhookEvent
 	if err := json.Unmarshal(body, &event); err != nil {
 		s.logger.Error("failed to parse webhook payload", "error", err)
+		s.metrics.delivery(eventType, outcomeBadRequest)
 		http.Error(w, "invalid payload", http.StatusBadRequest)
 		return
 	}
 
-	// Only process completed runs
-	if event.Action != "completed" {
-		s.logger.Debug("ignoring non-completed action", "action", event.Action)
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-
-	// Skip PBOM Collector runs to prevent infinite enrichment loops
-	if event.WorkflowRun.Name == "PBOM Collector" {
-		s.logger.Debug("skipping PBOM Collector run",
+	if reason := IgnoreReason(event); reason != "" {
+		s.logger.Debug("ignoring workflow_run event",
+			"reason", reason,
 			"repo", event.Repository.FullName,
 			"run_id", event.WorkflowRun.ID,
 		)
+		s.metrics.delivery(eventType, outcomeIgnored)
 		w.WriteHeader(http.StatusOK)
 		return
 	}
@@ -98,12 +172,87 @@ func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		"repo", event.Repository.FullName,
 		"workflow", event.WorkflowRun.Name,
 		"run_id", event.WorkflowRun.ID,
+		"run_attempt", event.WorkflowRun.RunAttempt,
 		"conclusion", event.WorkflowRun.Conclusion,
 		slog.String("sha", event.WorkflowRun.HeadSHA[:8]),
 	)
 
-	// Dispatch enrichment asynchronously — respond 202 immediately
-	go s.enricher.Enrich(r.Context(), event)
+	// Acknowledge redeliveries of runs already accepted. ?force=1 re-enriches
+	// anyway; the query string is not covered by the signature, so it also
+	// requires the API token.
+	deliveryID := r.Header.Get("X-GitHub-Delivery")
+	runKey := RunKey(event)
+	force := r.URL.Query().Get("force") == "1"
+	if force && !s.hasAPIToken(r) {
+		s.logger.Warn("rejected forced delivery without API token", "delivery", deliveryID)
+		s.metrics.delivery(eventType, outcomeForbidden)
+		http.Error(w, "force requires the API bearer token", http.StatusForbidden)
+		return
+	}
+	if s.cfg.Dedupe != nil {
+		dup, err := s.cfg.Dedupe.Claim(r.Context(), deliveryID, runKey, force)
+		if err != nil {
+			s.logger.Error("failed to check for duplicate delivery", "error", err)
+			s.metrics.delivery(eventType, outcomeError)
+			http.Error(w, "failed to record delivery", http.StatusServiceUnavailable)
+			return
+		}
+		if dup != "" {
+			s.logger.Info("duplicate delivery acknowledged",
+				"delivery", deliveryID,
+				"run", runKey,
+				"duplicate_of", dup,
+			)
+			s.metrics.delivery(eventType, outcomeDuplicate)
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, "duplicate %s\n", dup)
+			return
+		}
+	}
+
+	// Persist the event for the enrichment workers; respond 202 once it is
+	// durable. A failure makes GitHub record the delivery as failed so it
+	// can be redelivered.
+	job, err := s.cfg.Queue.Enqueue(r.Context(), body)
+	if err != nil {
+		s.logger.Error("failed to enqueue event", "error", err)
+		if s.cfg.Dedupe != nil {
+			if err := s.cfg.Dedupe.Release(context.WithoutCancel(r.Context()), deliveryID, runKey); err != nil {
+				s.logger.Error("failed to release delivery record", "error", err)
+			}
+		}
+		s.metrics.delivery(eventType, outcomeError)
+		http.Error(w, "failed to queue event", http.StatusServiceUnavailable)
+		return
+	}
+	s.logger.Debug("event queued", "job_id", job.ID, "delivery", deliveryID, "forced", force)
+	s.metrics.delivery(eventType, outcomeAccepted)
 
 	w.WriteHeader(http.StatusAccepted)
 }
+
+// fromConfiguredGitHub reports whether a delivery's
+// X-GitHub-Enterprise-Host names the instance whose API the server uses.
+func (s *Server) fromConfiguredGitHub(host string) bool {
+	u, err := url.Parse(s.ghClient.BaseURL())
+	return err == nil && strings.EqualFold(u.Hostname(), host)
+}
+
+// processJob enriches a queued workflow_run event.
+func (s *Server) processJob(ctx context.Context, job *queue.Job) error {
+	var event WebhookEvent
+	if err := json.Unmarshal(job.Payload, &event); err != nil {
+		return queue.Permanent(fmt.Errorf("parsing queued event: %w", err))
+	}
+	if err := s.enricher.Enrich(ctx, event); err != nil {
+		if errors.Is(err, gh.ErrNotFound) {
+			// The run was deleted or the token cannot see the repository;
+			// retrying will not change that.
+			return queue.Permanent(err)
+		}
+		return err
+	}
+	s.eventsProcessed.Add(1)
+	s.lastEventAt.Store(time.Now().UTC())
+	return nil
+}
@@ -0,0 +1,169 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/queue"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
+)
+
+const testWebhookSecret = "test-secret-key"
+
+func newQueueTestServer(t *testing.T) *Server {
+	t.Helper()
+	st, err := storage.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewServer(Config{
+		WebhookSecret: testWebhookSecret,
+		APIToken:      testAPIToken,
+		Storage:       st,
+		Queue:         queue.New(st, queue.Config{}, logger),
+		Dedupe:        NewDeduper(st, 0),
+	}, logger)
+}
+
+func postEvent(s *Server, eventType string, body []byte) *httptest.ResponseRecorder {
+	return postDelivery(s, "/webhook", eventType, "", body, "")
+}
+
+func postDelivery(s *Server, path, eventType, deliveryID string, body []byte, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("X-GitHub-Event", eventType)
+	if deliveryID != "" {
+		req.Header.Set("X-GitHub-Delivery", deliveryID)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("X-Hub-Signature-256", computeSignature(body, testWebhookSecret))
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandleWebhookQueuesEvent(t *testing.T) {
+	s := newQueueTestServer(t)
+	ctx := context.Background()
+
+	completed := []byte(`{"action":"completed","workflow_run":{"id":42,"name":"CI","head_sha":"0123456789abcdef"},"repository":{"name":"app","full_name":"acme/app","owner":{"login":"acme"}}}`)
+	tests := []struct {
+		name      string
+		eventType string
+		body      []byte
+		wantCode  int
+		wantDepth int
+	}{
+		{"completed run", "workflow_run", completed, http.StatusAccepted, 1},
+		{"in-progress run", "workflow_run", []byte(`{"action":"in_progress","workflow_run":{"id":43}}`), http.StatusOK, 1},
+		{"collector run", "workflow_run", []byte(`{"action":"completed","workflow_run":{"id":44,"name":"PBOM Collector"}}`), http.StatusOK, 1},
+		{"other event", "push", []byte(`{}`), http.StatusOK, 1},
+	}
+	for _, tt := range tests {
+		rec := postEvent(s, tt.eventType, tt.body)
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.wantCode)
+		}
+		if depth, _ := s.cfg.Queue.Depth(ctx); depth != tt.wantDepth {
+			t.Errorf("%s: queue depth = %d, want %d", tt.name, depth, tt.wantDepth)
+		}
+	}
+
+	pending, err := s.cfg.Queue.Pending(ctx)
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("pending = %v, %v", pending, err)
+	}
+	if !bytes.Equal(pending[0].Payload, completed) {
+		t.Errorf("queued payload = %s", pending[0].Payload)
+	}
+}
+
+func TestProcessJobRejectsMalformedPayload(t *testing.T) {
+	s := newQueueTestServer(t)
+	err := s.processJob(context.Background(), &queue.Job{ID: "x", Payload: []byte(`not json`)})
+	// Retrying cannot fix a malformed payload.
+	if !queue.IsPermanent(err) {
+		t.Errorf("processJob error = %v, want a permanent error", err)
+	}
+}
+
+func TestHandleWebhookDeduplicates(t *testing.T) {
+	s := newQueueTestServer(t)
+	ctx := context.Background()
+
+	event := func(attempt int) []byte {
+		return []byte(fmt.Sprintf(`{"action":"completed","workflow_run":{"id":42,"run_attempt":%d,"name":"CI","head_sha":"0123456789abcdef"},"repository":{"name":"app","full_name":"acme/app","owner":{"login":"acme"}}}`, attempt))
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		delivery  string
+		body      []byte
+		token     string
+		wantCode  int
+		wantDepth int
+	}{
+		{"first delivery", "/webhook", "d-1", event(1), "", http.StatusAccepted, 1},
+		{"redelivery", "/webhook", "d-1", event(1), "", http.StatusOK, 1},
+		{"new delivery of same run attempt", "/webhook", "d-2", event(1), "", http.StatusOK, 1},
+		{"re-run attempt", "/webhook", "d-3", event(2), "", http.StatusAccepted, 2},
+		{"force without token", "/webhook?force=1", "d-1", event(1), "", http.StatusForbidden, 2},
+		{"force with wrong token", "/webhook?force=1", "d-1", event(1), "nope", http.StatusForbidden, 2},
+		{"force", "/webhook?force=1", "d-1", event(1), testAPIToken, http.StatusAccepted, 3},
+	}
+	for _, tt := range tests {
+		rec := postDelivery(s, tt.path, "workflow_run", tt.delivery, tt.body, tt.token)
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: status = %d, want %d (%s)", tt.name, rec.Code, tt.wantCode, rec.Body)
+		}
+		if depth, _ := s.cfg.Queue.Depth(ctx); depth != tt.wantDepth {
+			t.Errorf("%s: queue depth = %d, want %d", tt.name, depth, tt.wantDepth)
+		}
+	}
+}
+
+func TestHandleWebhookChecksEnterpriseHost(t *testing.T) {
+	st, err := storage.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewServer(Config{
+		WebhookSecret: testWebhookSecret,
+		GitHubURL:     "https://ghe.example.com/api/v3",
+		Storage:       st,
+		Queue:         queue.New(st, queue.Config{}, logger),
+	}, logger)
+	ctx := context.Background()
+
+	body := []byte(`{"action":"completed","workflow_run":{"id":42,"name":"CI","head_sha":"0123456789abcdef"},"repository":{"name":"app","full_name":"acme/app","owner":{"login":"acme"}},"enterprise":{"id":1,"slug":"acme-corp"}}`)
+	deliver := func(host string) int {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+		req.Header.Set("X-GitHub-Event", "workflow_run")
+		req.Header.Set("X-GitHub-Enterprise-Host", host)
+		req.Header.Set("X-Hub-Signature-256", computeSignature(body, testWebhookSecret))
+		rec := httptest.NewRecorder()
+		s.mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := deliver("other-ghe.example.com"); code != http.StatusOK {
+		t.Errorf("delivery from another instance: status %d, want 200", code)
+	}
+	if depth, _ := s.cfg.Queue.Depth(ctx); depth != 0 {
+		t.Fatalf("delivery from another instance was queued")
+	}
+	if code := deliver("GHE.example.com"); code != http.StatusAccepted {
+		t.Errorf("delivery from the configured instance: status %d, want 202", code)
+	}
+}
@@ -0,0 +1,172 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+)
+
+// IndexTerms returns the terms a PBOM is indexed under: its ID, repository,
+// branch and commit SHA, and the digest and tags of every artifact. Bare
+// tags such as "v1.2" are also indexed as full image references when the
+// artifact URI names the image.
+func IndexTerms(p *schema.PBOM) []storage.IndexTerm {
+	terms := []storage.IndexTerm{
+		{Kind: storage.IndexID, Value: p.ID},
+		{Kind: storage.IndexRepo, Value: p.Source.Repository},
+		{Kind: storage.IndexBranch, Value: p.Source.Branch},
+		{Kind: storage.IndexSHA, Value: p.Source.CommitSHA},
+	}
+	for _, a := range p.Artifacts {
+		terms = append(terms, storage.IndexTerm{Kind: storage.IndexDigest, Value: a.Digest})
+
+		image, _, _ := strings.Cut(a.URI, "@")
+		for _, tag := range a.Tags {
+			terms = append(terms, storage.IndexTerm{Kind: storage.IndexTag, Value: tag})
+			if image != "" && !strings.ContainsAny(tag, "/:") {
+				terms = append(terms, storage.IndexTerm{Kind: storage.IndexTag, Value: image + ":" + tag})
+			}
+		}
+	}
+	return terms
+}
+
+// isPBOMKey reports whether a storage key holds a PBOM document rather
+// than an index entry or other data.
+func isPBOMKey(key string) bool {
+	return strings.HasSuffix(key, ".pbom.json") && !strings.HasPrefix(key, storage.IndexPrefix)
+}
+
+// Reindex rebuilds the index entries of every stored PBOM, e.g. for
+// documents stored before the index existed. It returns the number of
+// PBOMs indexed.
+func Reindex(ctx context.Context, st storage.Storage) (int, error) {
+	keys, err := st.List(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, key := range keys {
+		if !isPBOMKey(key) {
+			continue
+		}
+		data, err := st.Get(ctx, key)
+		if err != nil {
+			return n, err
+		}
+		p, err := decodeStored(data)
+		if err != nil {
+			return n, fmt.Errorf("%s: %w", key, err)
+		}
+		if err := storage.PutIndexed(ctx, st, key, data, p.ID, IndexTerms(p)); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+var (
+	digestRe = regexp.MustCompile(`^[a-z0-9]+:[0-9a-f]{32,}$`)
+	hexRe    = regexp.MustCompile(`^[0-9a-f]{7,64}$`)
+)
+
+// lookupTerms turns a lookup query into index terms:
+//
+//	sha256:abc...            artifact digest
+//	ghcr.io/acme/app@sha256: artifact digest
+//	0123abc (7-64 hex chars) commit SHA prefix, or a bare sha256 digest
+//	ghcr.io/acme/app:v1      tag (":latest" is implied when absent)
+func lookupTerms(query string) []storage.IndexTerm {
+	q := strings.TrimSpace(query)
+	if _, digest, ok := strings.Cut(q, "@"); ok {
+		return []storage.IndexTerm{{Kind: storage.IndexDigest, Value: digest}}
+	}
+	lower := strings.ToLower(q)
+	if digestRe.MatchString(lower) {
+		return []storage.IndexTerm{{Kind: storage.IndexDigest, Value: lower}}
+	}
+	if hexRe.MatchString(lower) {
+		terms := []storage.IndexTerm{{Kind: storage.IndexSHA, Value: lower}}
+		if len(lower) == 64 {
+			terms = append(terms, storage.IndexTerm{Kind: storage.IndexDigest, Value: "sha256:" + lower})
+		}
+		return terms
+	}
+	terms := []storage.IndexTerm{{Kind: storage.IndexTag, Value: q}}
+	if i := strings.LastIndex(q, "/"); !strings.Contains(q[i+1:], ":") {
+		terms = append(terms, storage.IndexTerm{Kind: storage.IndexTag, Value: q + ":latest"})
+	}
+	return terms
+}
+
+// LookupResult is a stored PBOM matched by Lookup.
+type LookupResult struct {
+	Key   string
+	PBOM  *schema.PBOM
+	Match storage.IndexTerm
+}
+
+// Lookup finds stored PBOMs by artifact digest, commit SHA or image
+// reference using the index, newest first.
+func Lookup(ctx context.Context, st storage.Storage, query string) ([]LookupResult, error) {
+	seen := map[string]bool{}
+	var results []LookupResult
+	for _, term := range lookupTerms(query) {
+		hits, err := storage.Lookup(ctx, st, term)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range hits {
+			if seen[h.Key] {
+				continue
+			}
+			p, err := loadPBOM(ctx, st, h.Key)
+			if errors.Is(err, storage.ErrNotFound) {
+				continue // entry written ahead of an unfinished Store
+			}
+			if err != nil {
+				return nil, err
+			}
+			if !carries(p, h.Term) {
+				continue // stale entry of a re-stored document
+			}
+			seen[h.Key] = true
+			results = append(results, LookupResult{Key: h.Key, PBOM: p, Match: h.Term})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].PBOM.Timestamp.After(results[j].PBOM.Timestamp)
+	})
+	return results, nil
+}
+
+// carries reports whether p is still indexed under term.
+func carries(p *schema.PBOM, term storage.IndexTerm) bool {
+	for _, t := range IndexTerms(p) {
+		if t.Kind == term.Kind && strings.EqualFold(t.Value, term.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadPBOM reads and decodes the PBOM stored under key.
+func loadPBOM(ctx context.Context, st storage.Storage, key string) (*schema.PBOM, error) {
+	data, err := st.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decodeStored(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	return p, nil
+}
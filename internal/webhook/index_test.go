@@ -0,0 +1,137 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+)
+
+const testDigest = "sha256:4f2a8c1e9b7d6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4c3b2a1f"
+
+func TestLookupTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{testDigest, []string{"digest " + testDigest}},
+		{strings.ToUpper(testDigest), []string{"digest " + testDigest}},
+		{"ghcr.io/acme/app@" + testDigest, []string{"digest " + testDigest}},
+		{"3E1B9C0", []string{"sha 3e1b9c0"}},
+		{testDigest[len("sha256:"):], []string{"sha " + testDigest[len("sha256:"):], "digest " + testDigest}},
+		{"ghcr.io/acme/app:v1", []string{"tag ghcr.io/acme/app:v1"}},
+		{"localhost:5000/app", []string{"tag localhost:5000/app", "tag localhost:5000/app:latest"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, term := range lookupTerms(tt.query) {
+			got = append(got, term.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lookupTerms(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestStoreIndexesAndLookup(t *testing.T) {
+	ctx := context.Background()
+	st, err := storage.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := &schema.PBOM{
+		PBOMVersion: schema.Version, ID: "old", Timestamp: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Source: schema.Source{Repository: "acme/app", CommitSHA: "3e1b9c0aaaa"},
+		Artifacts: []schema.Artifact{{
+			Name: "app", Type: "container-image", Digest: testDigest,
+			URI: "ghcr.io/acme/app@" + testDigest, Tags: []string{"v1", "ghcr.io/acme/app:latest"},
+		}},
+	}
+	rebuilt := &schema.PBOM{
+		PBOMVersion: schema.Version, ID: "rebuilt", Timestamp: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+		Source:    schema.Source{Repository: "acme/app", CommitSHA: "3e1b9c0aaaa"},
+		Artifacts: []schema.Artifact{{Name: "app", Type: "container-image", Digest: testDigest}},
+	}
+	for i, p := range []*schema.PBOM{old, rebuilt} {
+		if _, err := Store(ctx, st, p, nil, "acme", "app", int64(i+1), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := func(query string) []string {
+		t.Helper()
+		results, err := Lookup(ctx, st, query)
+		if err != nil {
+			t.Fatalf("Lookup(%q): %v", query, err)
+		}
+		var ids []string
+		for _, r := range results {
+			ids = append(ids, r.PBOM.ID)
+		}
+		return ids
+	}
+
+	tests := map[string][]string{
+		testDigest:                       {"rebuilt", "old"},
+		"ghcr.io/acme/app@" + testDigest: {"rebuilt", "old"},
+		"3e1b9c0":                        {"rebuilt", "old"},
+		"ghcr.io/acme/app:v1":            {"old"},
+		"ghcr.io/acme/app":               {"old"},
+		"v1":                             {"old"},
+		"ghcr.io/acme/other:v1":          nil,
+	}
+	for query, want := range tests {
+		if got := ids(query); !reflect.DeepEqual(got, want) {
+			t.Errorf("Lookup(%q) = %v, want %v", query, got, want)
+		}
+	}
+
+	// Re-storing run 1 without its tags drops them from the index.
+	old.Artifacts[0].Tags = nil
+	if _, err := Store(ctx, st, old, nil, "acme", "app", 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids("ghcr.io/acme/app:v1"); got != nil {
+		t.Errorf("after re-store, tag lookup = %v, want none", got)
+	}
+}
+
+func TestReindex(t *testing.T) {
+	ctx := context.Background()
+	st, err := storage.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A PBOM written before the index existed is invisible to Lookup.
+	p := &schema.PBOM{
+		PBOMVersion: schema.Version, ID: "legacy",
+		Source:    schema.Source{Repository: "acme/app", CommitSHA: "abcdef0123"},
+		Artifacts: []schema.Artifact{{Name: "app", Type: "container-image", Digest: testDigest}},
+	}
+	data, _ := json.Marshal(p)
+	if err := st.Put(ctx, StorageKey("acme", "app", 7, 1), data); err != nil {
+		t.Fatal(err)
+	}
+	if results, _ := Lookup(ctx, st, testDigest); len(results) != 0 {
+		t.Fatalf("unindexed PBOM found: %v", results)
+	}
+
+	n, err := Reindex(ctx, st)
+	if err != nil {
+		t.Fatalf("Reindex: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Reindex = %d, want 1", n)
+	}
+	results, err := Lookup(ctx, st, testDigest)
+	if err != nil || len(results) != 1 || results[0].PBOM.ID != "legacy" {
+		t.Errorf("after Reindex: %v, %v", results, err)
+	}
+}
@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	gh "github.com/BuildGuard-Test-Lab/pbom/internal/github"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/metrics"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/queue"
+)
+
+// Delivery outcomes, the "outcome" label of pbom_webhook_deliveries_total.
+const (
+	outcomeAccepted         = "accepted"          // queued for enrichment
+	outcomeIgnored          = "ignored"           // not a completed run, or a collector run
+	outcomeDuplicate        = "duplicate"         // redelivery of an accepted run
+	outcomeInvalidSignature = "invalid_signature" // HMAC check failed
+	outcomeBadRequest       = "bad_request"       // unreadable body or payload
+	outcomeForbidden        = "forbidden"         // force without the API token
+	outcomeError            = "error"             // could not be recorded or queued
+)
+
+// unverifiedEvent labels deliveries rejected before their signature was
+// checked; the X-GitHub-Event header is not trusted as a label until then.
+const unverifiedEvent = "unverified"
+
+// enrichBuckets cover enrichment steps, which range from a single API call
+// to the skeleton lookup waiting minutes for the collector run.
+var enrichBuckets = []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// serverMetrics are the metrics served on /metrics. A nil *serverMetrics
+// records nothing, so an Enricher works without a server.
+type serverMetrics struct {
+	registry          *metrics.Registry
+	deliveries        *metrics.Counter
+	signatureFailures *metrics.Counter
+	enrichments       *metrics.Counter
+	enrichStep        *metrics.Histogram
+	skeletons         *metrics.Counter
+	githubRequests    *metrics.Counter
+	githubDuration    *metrics.Histogram
+}
+
+func newServerMetrics(q *queue.Queue) *serverMetrics {
+	r := metrics.NewRegistry()
+	m := &serverMetrics{
+		registry: r,
+		deliveries: r.NewCounter("pbom_webhook_deliveries_total",
+			"Webhook deliveries received, by event type and outcome.", "event", "outcome"),
+		signatureFailures: r.NewCounter("pbom_webhook_signature_failures_total",
+			"Webhook deliveries rejected for an invalid signature."),
+		enrichments: r.NewCounter("pbom_enrichments_total",
+			"Enrichment attempts of queued workflow runs, by result.", "result"),
+		enrichStep: r.NewHistogram("pbom_enrichment_step_duration_seconds",
+			"Duration of each enrichment step in seconds.", enrichBuckets, "step"),
+		skeletons: r.NewCounter("pbom_skeleton_lookups_total",
+			"Enrichments by whether the collector's skeleton PBOM was found, rejected for a digest mismatch, or a fallback was built.", "result"),
+		githubRequests: r.NewCounter("pbom_github_api_requests_total",
+			"GitHub API requests by endpoint and HTTP status (0 if no response).", "endpoint", "status"),
+		githubDuration: r.NewHistogram("pbom_github_api_request_duration_seconds",
+			"GitHub API request duration in seconds, by endpoint.", nil, "endpoint"),
+	}
+
+	r.NewGaugeFunc("pbom_queue_depth", "Jobs waiting for enrichment, including ones backing off.",
+		func(ctx context.Context) (float64, error) {
+			if q == nil {
+				return 0, errors.New("no queue")
+			}
+			n, err := q.Depth(ctx)
+			return float64(n), err
+		})
+	r.NewGaugeFunc("pbom_queue_dead_jobs", "Jobs in the dead-letter list.",
+		func(ctx context.Context) (float64, error) {
+			if q == nil {
+				return 0, errors.New("no queue")
+			}
+			jobs, err := q.Dead(ctx)
+			return float64(len(jobs)), err
+		})
+	return m
+}
+
+func (m *serverMetrics) delivery(event, outcome string) {
+	if m == nil {
+		return
+	}
+	m.deliveries.Inc(event, outcome)
+	if outcome == outcomeInvalidSignature {
+		m.signatureFailures.Inc()
+	}
+}
+
+// step records the duration of an enrichment step begun at start.
+func (m *serverMetrics) step(name string, start time.Time) {
+	if m == nil {
+		return
+	}
+	m.enrichStep.Since(start, name)
+}
+
+func (m *serverMetrics) enrichment(err error) {
+	if m == nil {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	m.enrichments.Inc(result)
+}
+
+// skeleton records the outcome of a skeleton lookup: found, rejected
+// for failing its digest check, or a fallback built without one.
+func (m *serverMetrics) skeleton(err error) {
+	if m == nil {
+		return
+	}
+	result := "found"
+	switch {
+	case errors.Is(err, gh.ErrDigestMismatch):
+		result = "rejected"
+	case err != nil:
+		result = "fallback"
+	}
+	m.skeletons.Inc(result)
+}
+
+// observeGitHub is the GitHub client's Observer.
+func (m *serverMetrics) observeGitHub(endpoint string, status int, elapsed time.Duration) {
+	m.githubRequests.Inc(endpoint, strconv.Itoa(status))
+	m.githubDuration.Observe(elapsed.Seconds(), endpoint)
+}
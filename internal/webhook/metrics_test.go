@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gh "github.com/BuildGuard-Test-Lab/pbom/internal/github"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	s := newQueueTestServer(t)
+
+	completed := []byte(`{"action":"completed","workflow_run":{"id":42,"name":"CI","head_sha":"0123456789abcdef"},"repository":{"name":"app","full_name":"acme/app","owner":{"login":"acme"}}}`)
+	postDelivery(s, "/webhook", "workflow_run", "d-1", completed, "")
+	postDelivery(s, "/webhook", "workflow_run", "d-1", completed, "")
+	postEvent(s, "push", []byte(`{}`))
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{}`))
+	req.Header.Set("X-GitHub-Event", "workflow_run")
+	req.Header.Set("X-Hub-Signature-256", "sha256=00")
+	s.mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	// GitHub API calls are labelled by route, not by the concrete path.
+	api := httptest.NewServer(http.NotFoundHandler())
+	defer api.Close()
+	client := gh.NewClientWithBase("", api.URL)
+	client.SetObserver(s.metrics.observeGitHub)
+	client.GetJobs(context.Background(), "acme", "app", 42)
+
+	s.metrics.skeleton(nil)
+	s.metrics.skeleton(errors.New("no collector run"))
+	s.metrics.skeleton(errors.New("no collector run"))
+	s.metrics.skeleton(fmt.Errorf("downloading PBOM artifact: %w", gh.ErrDigestMismatch))
+
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`pbom_webhook_deliveries_total{event="workflow_run",outcome="accepted"} 1`,
+		`pbom_webhook_deliveries_total{event="workflow_run",outcome="duplicate"} 1`,
+		`pbom_webhook_deliveries_total{event="push",outcome="ignored"} 1`,
+		`pbom_webhook_deliveries_total{event="unverified",outcome="invalid_signature"} 1`,
+		`pbom_webhook_signature_failures_total 1`,
+		`pbom_github_api_requests_total{endpoint="/repos/{owner}/{repo}/actions/runs/{run_id}/jobs",status="404"} 1`,
+		`pbom_github_api_request_duration_seconds_count{endpoint="/repos/{owner}/{repo}/actions/runs/{run_id}/jobs"} 1`,
+		`pbom_skeleton_lookups_total{result="fallback"} 2`,
+		`pbom_skeleton_lookups_total{result="found"} 1`,
+		`pbom_skeleton_lookups_total{result="rejected"} 1`,
+		`pbom_queue_depth 1`,
+		`pbom_queue_dead_jobs 0`,
+		`# TYPE pbom_enrichment_step_duration_seconds histogram`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+	if t.Failed() {
+		t.Log(body)
+	}
+}
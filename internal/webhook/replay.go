@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrIgnored is returned by Replay for events the listener would not
+// enrich, such as in-progress runs.
+var ErrIgnored = errors.New("event is not enriched")
+
+// Replay runs the enrichment pipeline on a recorded workflow_run payload
+// the way a queue worker would, but without signature verification,
+// deduplication or the queue. The parsed event is returned even when
+// enrichment fails.
+func (e *Enricher) Replay(ctx context.Context, payload []byte) (*WebhookEvent, error) {
+	var event WebhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("parsing payload: %w", err)
+	}
+	if event.WorkflowRun.ID == 0 || event.Repository.FullName == "" {
+		return nil, errors.New("not a workflow_run payload")
+	}
+	if reason := IgnoreReason(event); reason != "" {
+		return &event, fmt.Errorf("%w: %s", ErrIgnored, reason)
+	}
+	return &event, e.Enrich(ctx, event)
+}
+
+// ArchiveKey returns the key under which a verified payload is archived:
+// {date}/{delivery ID}.json. Deliveries without an ID get a random one.
+func ArchiveKey(receivedAt time.Time, deliveryID string) string {
+	if deliveryID == "" {
+		deliveryID = uuid.NewString()
+	}
+	return receivedAt.UTC().Format("2006-01-02") + "/" + url.PathEscape(deliveryID) + ".json"
+}
+
+// archive saves the raw body of a verified delivery. Failures are logged
+// but do not affect the delivery.
+func (s *Server) archive(ctx context.Context, deliveryID string, body []byte) {
+	if s.cfg.Archive == nil {
+		return
+	}
+	key := ArchiveKey(time.Now(), deliveryID)
+	if err := s.cfg.Archive.Put(ctx, key, body); err != nil {
+		s.logger.Error("failed to archive payload", "key", key, "error", err)
+	}
+}
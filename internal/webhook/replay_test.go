@@ -0,0 +1,165 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gh "github.com/BuildGuard-Test-Lab/pbom/internal/github"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
+)
+
+// fakeGitHub answers the enricher's API calls for a run with no collector
+// run, jobs or artifacts.
+func fakeGitHub(t *testing.T) *gh.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/acme/app/actions/runs":
+			w.Write([]byte(`{"total_count":0,"workflow_runs":[]}`))
+		case strings.HasSuffix(r.URL.Path, "/jobs"):
+			w.Write([]byte(`{"total_count":0,"jobs":[]}`))
+		case strings.HasSuffix(r.URL.Path, "/artifacts"):
+			w.Write([]byte(`{"total_count":0,"artifacts":[]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return gh.NewClientWithBase("", srv.URL)
+}
+
+func TestReplay(t *testing.T) {
+	st, err := storage.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnricher(fakeGitHub(t), st, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	e.WaitForCollector(false)
+	ctx := context.Background()
+
+	start := time.Now()
+	event, err := e.Replay(ctx, []byte(`{"action":"completed","workflow_run":{"id":42,"run_attempt":2,"name":"CI","head_sha":"0123456789abcdef","conclusion":"failure"},"repository":{"name":"app","full_name":"acme/app","owner":{"login":"acme"}}}`))
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("Replay waited for the collector run")
+	}
+	p, err := loadPBOM(ctx, st, StorageKey("acme", "app", event.WorkflowRun.ID, event.WorkflowRun.RunAttempt))
+	if err != nil {
+		t.Fatalf("replayed PBOM not stored: %v", err)
+	}
+	if p.Build.WorkflowRunID != "42" || p.Build.RunAttempt != 2 || p.Build.Status != "failure" {
+		t.Errorf("stored build = %+v", p.Build)
+	}
+	// A re-run built without a skeleton gets its own ID too.
+	if _, err := e.Replay(ctx, []byte(`{"action":"completed","workflow_run":{"id":42,"run_attempt":3,"head_sha":"0123456789abcdef"},"repository":{"name":"app","full_name":"acme/app","owner":{"login":"acme"}}}`)); err != nil {
+		t.Fatalf("Replay attempt 3: %v", err)
+	}
+	if rerun, err := loadPBOM(ctx, st, StorageKey("acme", "app", 42, 3)); err != nil || rerun.ID == p.ID {
+		t.Errorf("attempt 3 ID = %v (err %v), want other than attempt 2's %s", rerun, err, p.ID)
+	}
+
+	if _, err := e.Replay(ctx, []byte(`{"action":"in_progress","workflow_run":{"id":43},"repository":{"full_name":"acme/app"}}`)); !errors.Is(err, ErrIgnored) {
+		t.Errorf("in-progress run: err = %v, want ErrIgnored", err)
+	}
+	for _, payload := range []string{`not json`, `{"zen":"Keep it logically awesome."}`} {
+		if _, err := e.Replay(ctx, []byte(payload)); err == nil || errors.Is(err, ErrIgnored) {
+			t.Errorf("Replay(%s) = %v, want a parse error", payload, err)
+		}
+	}
+}
+
+func TestReplayAsAppInstallation(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	var unauthorized []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/app/installations/99/access_tokens":
+			fmt.Fprintf(w, `{"token":"ghs_inst99","expires_at":%q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+			return
+		case r.Header.Get("Authorization") != "Bearer ghs_inst99":
+			unauthorized = append(unauthorized, r.URL.Path)
+			http.Error(w, "bad credentials", http.StatusUnauthorized)
+		case r.URL.Path == "/repos/acme/app/actions/runs":
+			w.Write([]byte(`{"total_count":0,"workflow_runs":[]}`))
+		case strings.HasSuffix(r.URL.Path, "/jobs"):
+			w.Write([]byte(`{"total_count":0,"jobs":[]}`))
+		case strings.HasSuffix(r.URL.Path, "/artifacts"):
+			w.Write([]byte(`{"total_count":0,"artifacts":[]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	app, err := gh.NewAppWithBase(1, keyPEM, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := storage.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The Enricher's own client has no token; every call must go through
+	// the installation named in the payload.
+	e := NewEnricher(gh.NewClientWithBase("", srv.URL), st, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	e.WaitForCollector(false)
+	e.UseApp(app)
+
+	_, err = e.Replay(context.Background(), []byte(`{"action":"completed","workflow_run":{"id":42,"name":"CI","head_sha":"0123456789abcdef","conclusion":"success"},"repository":{"name":"app","full_name":"acme/app","owner":{"login":"acme"}},"installation":{"id":99}}`))
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if len(unauthorized) > 0 {
+		t.Errorf("requests without the installation token: %v", unauthorized)
+	}
+}
+
+func TestHandleWebhookArchivesVerifiedPayloads(t *testing.T) {
+	s := newQueueTestServer(t)
+	archive, err := storage.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.cfg.Archive = archive
+	ctx := context.Background()
+
+	body := []byte(`{"action":"in_progress","workflow_run":{"id":42},"repository":{"full_name":"acme/app"}}`)
+	postDelivery(s, "/webhook", "workflow_run", "d-1", body, "")
+
+	forged := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"action":"completed"}`))
+	forged.Header.Set("X-GitHub-Event", "workflow_run")
+	forged.Header.Set("X-GitHub-Delivery", "d-2")
+	s.mux.ServeHTTP(httptest.NewRecorder(), forged)
+
+	keys, err := archive.List(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ArchiveKey(time.Now(), "d-1")
+	if len(keys) != 1 || keys[0] != want {
+		t.Fatalf("archived keys = %v, want [%s]", keys, want)
+	}
+	got, _ := archive.Get(ctx, want)
+	if string(got) != string(body) {
+		t.Errorf("archived payload = %s", got)
+	}
+}
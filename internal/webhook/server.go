@@ -2,6 +2,7 @@ package webhook
 
 import (
 	"context"
+	"crypto"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -10,6 +11,8 @@ import (
 	"time"
 
 	gh "github.com/BuildGuard-Test-Lab/pbom/internal/github"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/queue"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
 )
 
 // Config holds webhook server configuration.
@@ -17,7 +20,37 @@ type Config struct {
 	Addr          string
 	WebhookSecret string
 	GitHubToken   string
-	StorageDir    string
+	// GitHubURL is the REST API of a GitHub Enterprise Server, e.g.
+	// https://ghe.example.com/api/v3. Empty uses github.com.
+	GitHubURL string
+	// GitHubTransport, if set, carries GitHub API requests, e.g. to trust
+	// a private CA.
+	GitHubTransport http.RoundTripper
+	// GitHubApp, if set, authenticates enrichment as the app installation
+	// each event was delivered for, instead of with GitHubToken. It must
+	// be created for GitHubURL and use GitHubTransport.
+	GitHubApp *gh.App
+	// GitHubCache holds GitHub API responses for conditional requests.
+	// Nil uses an in-memory cache.
+	GitHubCache *gh.Cache
+	// MaxArtifactSize caps artifact downloads, in bytes. Zero uses
+	// gh.DefaultMaxDownloadSize.
+	MaxArtifactSize int64
+	// Storage receives enriched PBOMs.
+	Storage storage.Storage
+	// Queue holds accepted events until they are enriched. Start runs it.
+	Queue *queue.Queue
+	// Dedupe, if set, acknowledges redelivered events without queueing
+	// them again.
+	Dedupe *Deduper
+	// SigningKey, if set, signs every stored PBOM with a DSSE envelope.
+	SigningKey crypto.Signer
+	// Archive, if set, receives the raw body of every verified
+	// workflow_run delivery, for 'pbom webhook replay'.
+	Archive storage.Storage
+	// APIToken enables the /api/v1 query endpoints, which require it as a
+	// bearer token. Empty leaves the API disabled.
+	APIToken string
 }
 
 // Server is the webhook HTTP server.
@@ -27,6 +60,7 @@ type Server struct {
 	enricher *Enricher
 	logger   *slog.Logger
 	mux      *http.ServeMux
+	metrics  *serverMetrics
 
 	eventsProcessed atomic.Int64
 	lastEventAt     atomic.Value // time.Time
@@ -34,8 +68,33 @@ type Server struct {
 
 // NewServer creates a configured webhook server.
 func NewServer(cfg Config, logger *slog.Logger) *Server {
+	m := newServerMetrics(cfg.Queue)
 	ghClient := gh.NewClient(cfg.GitHubToken)
-	enricher := NewEnricher(ghClient, cfg.StorageDir, logger)
+	if cfg.GitHubURL != "" {
+		ghClient = gh.NewClientWithBase(cfg.GitHubToken, cfg.GitHubURL)
+	}
+	if cfg.GitHubTransport != nil {
+		ghClient.SetTransport(cfg.GitHubTransport)
+	}
+	ghClient.SetObserver(m.observeGitHub)
+	cache := cfg.GitHubCache
+	if cache == nil {
+		cache = gh.NewCache()
+	}
+	ghClient.SetCache(cache)
+	if cfg.MaxArtifactSize > 0 {
+		ghClient.SetMaxDownloadSize(cfg.MaxArtifactSize)
+	}
+	enricher := NewEnricher(ghClient, cfg.Storage, cfg.SigningKey, logger)
+	enricher.metrics = m
+	if cfg.GitHubApp != nil {
+		cfg.GitHubApp.SetObserver(m.observeGitHub)
+		cfg.GitHubApp.SetCache(cache)
+		if cfg.MaxArtifactSize > 0 {
+			cfg.GitHubApp.SetMaxDownloadSize(cfg.MaxArtifactSize)
+		}
+		enricher.UseApp(cfg.GitHubApp)
+	}
 
 	s := &Server{
 		cfg:      cfg,
@@ -43,11 +102,19 @@ func NewServer(cfg Config, logger *slog.Logger) *Server {
 		enricher: enricher,
 		logger:   logger,
 		mux:      http.NewServeMux(),
+		metrics:  m,
 	}
 
 	s.mux.HandleFunc("/webhook", s.handleWebhook)
 	s.mux.HandleFunc("/health", s.handleHealth)
 	s.mux.HandleFunc("/status", s.handleStatus)
+	s.mux.Handle("GET /metrics", m.registry.Handler())
+
+	if cfg.APIToken != "" {
+		s.mux.HandleFunc("GET /api/v1/pboms", s.requireToken(s.handleListPBOMs))
+		s.mux.HandleFunc("GET /api/v1/pboms/{id}", s.requireToken(s.handleGetPBOM))
+		s.mux.HandleFunc("GET /api/v1/artifacts/{digest}/pboms", s.requireToken(s.handleArtifactPBOMs))
+	}
 
 	return s
 }
@@ -62,23 +129,65 @@ func (s *Server) Start(ctx context.Context) error {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	queueCtx, stopQueue := context.WithCancel(context.Background())
+	defer stopQueue()
+	queueDone := make(chan error, 1)
+	go func() { queueDone <- s.cfg.Queue.Run(queueCtx, s.processJob) }()
+	if s.cfg.Dedupe != nil {
+		go s.pruneDeliveries(queueCtx)
+	}
+
 	errCh := make(chan error, 1)
 	go func() {
 		s.logger.Info("webhook listener starting",
 			"addr", s.cfg.Addr,
-			"storage_dir", s.cfg.StorageDir,
+			"storage", fmt.Sprint(s.cfg.Storage),
+			"queue", fmt.Sprint(s.cfg.Queue),
+			"archive", fmt.Sprint(s.cfg.Archive),
+			"api", s.cfg.APIToken != "",
 		)
 		errCh <- srv.ListenAndServe()
 	}()
 
 	select {
 	case err := <-errCh:
+		stopQueue()
+		<-queueDone
 		return fmt.Errorf("server error: %w", err)
+	case err := <-queueDone:
+		srv.Close()
+		return fmt.Errorf("queue error: %w", err)
 	case <-ctx.Done():
 		s.logger.Info("shutting down webhook listener")
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
-		return srv.Shutdown(shutdownCtx)
+		err := srv.Shutdown(shutdownCtx)
+
+		// Stop workers only after the listener, so every accepted event is
+		// queued; jobs interrupted here resume on the next start.
+		stopQueue()
+		<-queueDone
+		return err
+	}
+}
+
+// pruneDeliveries expires old delivery records at startup and then daily.
+func (s *Server) pruneDeliveries(ctx context.Context) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+	for {
+		if n, err := s.cfg.Dedupe.Prune(ctx); err != nil {
+			if ctx.Err() == nil {
+				s.logger.Error("failed to prune delivery records", "error", err)
+			}
+		} else if n > 0 {
+			s.logger.Info("pruned delivery records", "count", n)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
 	}
 }
 
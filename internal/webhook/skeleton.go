@@ -2,12 +2,13 @@ package webhook
 
 import (
 	"archive/zip"
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
+	"io"
+	"strconv"
 	"strings"
 
+	"github.com/BuildGuard-Test-Lab/pbom/internal/dsse"
 	gh "github.com/BuildGuard-Test-Lab/pbom/internal/github"
 	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
 )
@@ -30,7 +31,8 @@ func FindCollectorRun(ctx context.Context, client *gh.Client, owner, repo, headS
 }
 
 // DownloadSkeletonPBOM downloads the PBOM artifact from a collector run,
-// extracts the pbom.json from the ZIP, and unmarshals it.
+// extracts the pbom.json from the ZIP, and unmarshals it. The returned
+// PBOM's Build.Skeleton records the artifact it was read from.
 func DownloadSkeletonPBOM(ctx context.Context, client *gh.Client, owner, repo string, collectorRunID int64) (*schema.PBOM, error) {
 	artifacts, err := client.GetArtifacts(ctx, owner, repo, collectorRunID)
 	if err != nil {
@@ -50,36 +52,84 @@ func DownloadSkeletonPBOM(ctx context.Context, client *gh.Client, owner, repo st
 		return nil, fmt.Errorf("no artifact named %q found in collector run %d", prefix, collectorRunID)
 	}
 
-	// Download and extract
-	zipData, err := client.DownloadArtifact(ctx, pbomArtifact.ArchiveDownloadURL)
+	// Download and extract. DownloadArtifact rejects an archive that does
+	// not match the digest GitHub reported.
+	zipFile, err := client.DownloadArtifact(ctx, *pbomArtifact)
 	if err != nil {
 		return nil, fmt.Errorf("downloading PBOM artifact: %w", err)
 	}
+	defer zipFile.Close()
 
-	return extractPBOMFromZip(zipData)
+	pbom, err := extractPBOMFromZip(zipFile, zipFile.Size)
+	if err != nil {
+		return nil, err
+	}
+	pbom.Build.Skeleton = &schema.SkeletonSource{
+		RunID:      strconv.FormatInt(collectorRunID, 10),
+		ArtifactID: pbomArtifact.ID,
+		Digest:     zipFile.Digest,
+		Verified:   pbomArtifact.Digest != "",
+		PBOMID:     pbom.ID,
+	}
+	return pbom, nil
 }
 
-func extractPBOMFromZip(zipData []byte) (*schema.PBOM, error) {
-	reader, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+func extractPBOMFromZip(zipData io.ReaderAt, size int64) (*schema.PBOM, error) {
+	reader, err := zip.NewReader(zipData, size)
 	if err != nil {
 		return nil, fmt.Errorf("opening artifact zip: %w", err)
 	}
 
 	for _, f := range reader.File {
 		if strings.HasSuffix(f.Name, ".json") {
-			rc, err := f.Open()
+			data, err := readZipEntry(f)
 			if err != nil {
-				return nil, fmt.Errorf("opening %s: %w", f.Name, err)
+				return nil, err
 			}
-			defer rc.Close()
 
-			var pbom schema.PBOM
-			if err := json.NewDecoder(rc).Decode(&pbom); err != nil {
+			// The collector may sign its skeleton; the enriched PBOM is
+			// re-signed (or not) when stored, so only the payload matters.
+			data, _, err = dsse.Unwrap(data, schema.MediaType)
+			if err != nil {
+				return nil, fmt.Errorf("parsing PBOM envelope: %w", err)
+			}
+
+			// Skeletons from older collectors are upgraded, since the
+			// enriched PBOM is stored at the current version.
+			pbom, err := schema.Decode(data)
+			if err != nil {
 				return nil, fmt.Errorf("parsing PBOM JSON: %w", err)
 			}
-			return &pbom, nil
+			return pbom, nil
 		}
 	}
 
 	return nil, fmt.Errorf("no JSON file found in PBOM artifact zip")
 }
+
+// maxZipEntrySize is the largest file read from an artifact archive. The
+// download limit bounds the archive, not what it expands to.
+const maxZipEntrySize = 8 << 20
+
+// readZipEntry reads a file from an artifact archive, refusing files that
+// decompress to more than maxZipEntrySize.
+func readZipEntry(f *zip.File) ([]byte, error) {
+	if f.UncompressedSize64 > maxZipEntrySize {
+		return nil, fmt.Errorf("%s is %d bytes uncompressed, limit %d: %w", f.Name, f.UncompressedSize64, maxZipEntrySize, gh.ErrTooLarge)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	// The header's size is not trusted to bound the data.
+	data, err := io.ReadAll(io.LimitReader(rc, maxZipEntrySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", f.Name, err)
+	}
+	if len(data) > maxZipEntrySize {
+		return nil, fmt.Errorf("%s is over %d bytes uncompressed: %w", f.Name, maxZipEntrySize, gh.ErrTooLarge)
+	}
+	return data, nil
+}
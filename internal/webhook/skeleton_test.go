@@ -0,0 +1,180 @@
+package webhook
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gh "github.com/BuildGuard-Test-Lab/pbom/internal/github"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+)
+
+func TestDownloadSkeletonPBOM(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("pbom.json")
+	// Written by a collector on an older release.
+	w.Write([]byte(`{"pbom_version":"1.1.0","id":"skeleton-id","build":{"workflow_run_id":"7","status":"success"}}`))
+	zw.Close()
+	archive := buf.Bytes()
+	sum := sha256.Sum256(archive)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name         string
+		reported     string // digest the Artifacts API reports
+		wantErr      error
+		wantVerified bool
+	}{
+		{"digest matches", digest, nil, true},
+		{"no digest reported", "", nil, false},
+		{"digest mismatch", "sha256:" + strings.Repeat("0", 64), gh.ErrDigestMismatch, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var srv *httptest.Server
+			srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/repos/acme/app/actions/runs/99/artifacts":
+					fmt.Fprintf(w, `{"total_count":1,"artifacts":[{"id":555,"name":"pbom-99","size_in_bytes":%d,"digest":%q,"archive_download_url":"%s/repos/acme/app/actions/artifacts/555/zip"}]}`,
+						len(archive), tt.reported, srv.URL)
+				case "/repos/acme/app/actions/artifacts/555/zip":
+					w.Write(archive)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer srv.Close()
+
+			p, err := DownloadSkeletonPBOM(context.Background(), gh.NewClientWithBase("", srv.URL), "acme", "app", 99)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.ID != "skeleton-id" || p.PBOMVersion != schema.Version {
+				t.Errorf("skeleton id %q version %q, want skeleton-id at %s", p.ID, p.PBOMVersion, schema.Version)
+			}
+			want := schema.SkeletonSource{RunID: "99", ArtifactID: 555, Digest: digest, Verified: tt.wantVerified, PBOMID: "skeleton-id"}
+			if p.Build.Skeleton == nil || *p.Build.Skeleton != want {
+				t.Errorf("Build.Skeleton = %+v, want %+v", p.Build.Skeleton, want)
+			}
+		})
+	}
+}
+
+func TestEnrichGivesEachAttemptItsOwnID(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("pbom.json")
+	w.Write([]byte(`{"pbom_version":"1.2.0","id":"skeleton-id","build":{"workflow_run_id":"42","status":"success"}}`))
+	zw.Close()
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/app/actions/runs":
+			w.Write([]byte(`{"total_count":1,"workflow_runs":[{"id":99,"name":"PBOM Collector","conclusion":"success"}]}`))
+		case "/repos/acme/app/actions/runs/99/artifacts":
+			fmt.Fprintf(w, `{"total_count":1,"artifacts":[{"id":555,"name":"pbom-99","archive_download_url":"%s/repos/acme/app/actions/artifacts/555/zip"}]}`, srv.URL)
+		case "/repos/acme/app/actions/artifacts/555/zip":
+			w.Write(buf.Bytes())
+		case "/repos/acme/app/actions/runs/42/jobs":
+			w.Write([]byte(`{"total_count":0,"jobs":[]}`))
+		case "/repos/acme/app/actions/runs/42/artifacts":
+			w.Write([]byte(`{"total_count":0,"artifacts":[]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	st, err := storage.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEnricher(gh.NewClientWithBase("", srv.URL), st, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	e.WaitForCollector(false)
+	ctx := context.Background()
+
+	ids := map[string]bool{}
+	for attempt := 1; attempt <= 2; attempt++ {
+		var event WebhookEvent
+		event.WorkflowRun.ID = 42
+		event.WorkflowRun.RunAttempt = attempt
+		event.WorkflowRun.HeadSHA = "0123456789abcdef"
+		event.Repository.Name = "app"
+		event.Repository.FullName = "acme/app"
+		event.Repository.Owner.Login = "acme"
+		if err := e.Enrich(ctx, event); err != nil {
+			t.Fatalf("Enrich attempt %d: %v", attempt, err)
+		}
+		p, err := loadPBOM(ctx, st, StorageKey("acme", "app", 42, attempt))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Build.Skeleton == nil || p.Build.Skeleton.PBOMID != "skeleton-id" {
+			t.Errorf("attempt %d: Build.Skeleton = %+v, want skeleton-id recorded", attempt, p.Build.Skeleton)
+		}
+		ids[p.ID] = true
+	}
+	if len(ids) != 2 || ids["skeleton-id"] {
+		t.Errorf("attempt IDs = %v, want two fresh IDs", ids)
+	}
+}
+
+func TestExtractPBOMFromZipLimitsEntries(t *testing.T) {
+	big := bytes.Repeat([]byte(" "), maxZipEntrySize+1)
+	var compressed bytes.Buffer
+	fw, _ := flate.NewWriter(&compressed, flate.BestCompression)
+	fw.Write(big)
+	fw.Close()
+
+	tests := []struct {
+		name    string
+		write   func(*zip.Writer)
+		wantErr error
+	}{
+		{"declared size", func(zw *zip.Writer) {
+			w, _ := zw.Create("pbom.json")
+			w.Write(big)
+		}, gh.ErrTooLarge},
+		// The header claims a small file, but the data inflates past it;
+		// reading stops at the claimed size.
+		{"understated size", func(zw *zip.Writer) {
+			w, _ := zw.CreateRaw(&zip.FileHeader{
+				Name: "pbom.json", Method: zip.Deflate,
+				CompressedSize64: uint64(compressed.Len()), UncompressedSize64: 100,
+			})
+			w.Write(compressed.Bytes())
+		}, zip.ErrFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			zw := zip.NewWriter(&buf)
+			tt.write(zw)
+			zw.Close()
+			_, err := extractPBOMFromZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,32 +1,151 @@
 package webhook
 
 import (
+	"context"
+	"crypto"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/BuildGuard-Test-Lab/pbom/internal/dsse"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
 	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
 )
 
-// Store writes an enriched PBOM to the storage directory as JSON.
-// File naming: {owner}_{repo}_{runID}.pbom.json
-func Store(dir string, pbom *schema.PBOM, owner, repo string, runID int64) (string, error) {
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return "", fmt.Errorf("creating storage dir: %w", err)
-	}
+const pbomSuffix = ".pbom.json"
+
+// StorageKey returns the storage key for the enriched PBOM of one attempt
+// of a run: {owner}_{repo}_{runID}_{attempt}.pbom.json. Attempts below 1
+// are stored as attempt 1.
+func StorageKey(owner, repo string, runID int64, attempt int) string {
+	return fmt.Sprintf("%s%d%s", runKeyPrefix(owner, repo, runID), max(attempt, 1), pbomSuffix)
+}
+
+// runKeyPrefix is the key prefix shared by every attempt of a run.
+func runKeyPrefix(owner, repo string, runID int64) string {
+	return fmt.Sprintf("%s_%s_%d_", owner, repo, runID)
+}
+
+// legacyStorageKey is the key used before run attempts were tracked. Each
+// re-run overwrote it, so it holds whichever attempt was enriched last.
+func legacyStorageKey(owner, repo string, runID int64) string {
+	return fmt.Sprintf("%s_%s_%d%s", owner, repo, runID, pbomSuffix)
+}
 
-	filename := fmt.Sprintf("%s_%s_%d.pbom.json", owner, repo, runID)
-	path := filepath.Join(dir, filename)
+// loadRunPBOM reads the PBOM stored under key for a run of owner/repo.
+// Legacy and attempt keys can collide: acme_app_42_1.pbom.json is both
+// the legacy key of run 1 of repo "app_42" and the key of attempt 1 of run
+// 42 of repo "app". A document of another repository is therefore treated
+// as not found. Only the repository is compared; skeleton-based PBOMs
+// stored before run attempts were tracked carry the collector's run ID.
+func loadRunPBOM(ctx context.Context, st storage.Storage, key, owner, repo string) (*schema.PBOM, error) {
+	p, err := loadPBOM(ctx, st, key)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(p.Source.Repository, owner+"/"+repo) {
+		return nil, fmt.Errorf("%s belongs to %s: %w", key, p.Source.Repository, storage.ErrNotFound)
+	}
+	return p, nil
+}
 
+// Store writes an enriched PBOM to st as JSON, indexed under IndexTerms,
+// and returns its key. Each run attempt gets its own key, so re-runs add
+// to the run's history instead of replacing it. If signer is non-nil the
+// PBOM is wrapped in a signed DSSE envelope.
+func Store(ctx context.Context, st storage.Storage, pbom *schema.PBOM, signer crypto.Signer, owner, repo string, runID int64, attempt int) (string, error) {
 	data, err := json.MarshalIndent(pbom, "", "  ")
 	if err != nil {
 		return "", fmt.Errorf("marshaling PBOM: %w", err)
 	}
 
-	if err := os.WriteFile(path, data, 0o644); err != nil {
-		return "", fmt.Errorf("writing PBOM file: %w", err)
+	if signer != nil {
+		env, err := dsse.Sign(schema.MediaType, data, signer)
+		if err != nil {
+			return "", fmt.Errorf("signing PBOM: %w", err)
+		}
+		if data, err = json.MarshalIndent(env, "", "  "); err != nil {
+			return "", fmt.Errorf("marshaling envelope: %w", err)
+		}
+	}
+
+	key := StorageKey(owner, repo, runID, attempt)
+	if err := storage.PutIndexed(ctx, st, key, data, pbom.ID, IndexTerms(pbom)); err != nil {
+		return "", fmt.Errorf("writing PBOM: %w", err)
+	}
+
+	return key, nil
+}
+
+// Stored reports whether a PBOM for the given run attempt is in st. A
+// legacy PBOM of the run that does not record its attempt counts as
+// stored, since it was written for the attempt that was latest then.
+func Stored(ctx context.Context, st storage.Storage, owner, repo string, runID int64, attempt int) (bool, error) {
+	_, err := loadRunPBOM(ctx, st, StorageKey(owner, repo, runID, attempt), owner, repo)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return false, err
+	}
+	p, err := loadRunPBOM(ctx, st, legacyStorageKey(owner, repo, runID), owner, repo)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return p.Build.RunAttempt == 0 || p.Build.RunAttempt == max(attempt, 1), nil
+}
+
+// RunAttempt is one stored attempt of a workflow run.
+type RunAttempt struct {
+	Attempt int // 0 for a legacy PBOM whose attempt was not recorded
+	Key     string
+	PBOM    *schema.PBOM
+}
+
+// RunHistory returns every stored attempt of a run, oldest first. PBOMs
+// stored before attempts were tracked are included; they sort first unless
+// the document records its attempt.
+func RunHistory(ctx context.Context, st storage.Storage, owner, repo string, runID int64) ([]RunAttempt, error) {
+	prefix := runKeyPrefix(owner, repo, runID)
+	keys, err := st.List(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("listing attempts: %w", err)
+	}
+
+	var history []RunAttempt
+	for _, key := range keys {
+		// The prefix also matches other repos, e.g. repo "app_42" for
+		// repo "app" run 42; only "<attempt>.pbom.json" may follow it.
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(key, prefix), pbomSuffix))
+		if err != nil || !strings.HasSuffix(key, pbomSuffix) {
+			continue
+		}
+		p, err := loadRunPBOM(ctx, st, key, owner, repo)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, RunAttempt{Attempt: n, Key: key, PBOM: p})
+	}
+
+	legacy := legacyStorageKey(owner, repo, runID)
+	p, err := loadRunPBOM(ctx, st, legacy, owner, repo)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+	case err != nil:
+		return nil, err
+	default:
+		history = append(history, RunAttempt{Attempt: p.Build.RunAttempt, Key: legacy, PBOM: p})
 	}
 
-	return path, nil
+	sort.SliceStable(history, func(i, j int) bool { return history[i].Attempt < history[j].Attempt })
+	return history, nil
 }
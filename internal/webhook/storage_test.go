@@ -0,0 +1,189 @@
+package webhook
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/BuildGuard-Test-Lab/pbom/internal/dsse"
+	"github.com/BuildGuard-Test-Lab/pbom/internal/storage"
+	"github.com/BuildGuard-Test-Lab/pbom/pkg/schema"
+)
+
+func TestStoreSigned(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pbom := &schema.PBOM{PBOMVersion: schema.Version, ID: "test-id"}
+
+	st, err := storage.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	key, err := Store(ctx, st, pbom, priv, "acme", "app", 42, 1)
+	if err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if key != "acme_app_42_1.pbom.json" {
+		t.Errorf("key = %q, want acme_app_42_1.pbom.json", key)
+	}
+	data, err := st.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := dsse.Parse(data)
+	if err != nil {
+		t.Fatalf("stored PBOM is not an envelope: %v", err)
+	}
+	if _, err := env.Verify([]crypto.PublicKey{pub}); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+
+	// A signed skeleton is read back transparently.
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("pbom.json")
+	w.Write(data)
+	zw.Close()
+
+	got, err := extractPBOMFromZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("extractPBOMFromZip: %v", err)
+	}
+	if got.ID != "test-id" {
+		t.Errorf("ID = %q, want test-id", got.ID)
+	}
+}
+
+func TestRunHistory(t *testing.T) {
+	st, err := storage.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	attempt := func(id string, n int, status string) *schema.PBOM {
+		return &schema.PBOM{
+			PBOMVersion: schema.Version, ID: id,
+			Source: schema.Source{Repository: "acme/app"},
+			Build:  schema.Build{WorkflowRunID: "42", RunAttempt: n, Status: status},
+		}
+	}
+	for _, p := range []*schema.PBOM{attempt("second", 2, "success"), attempt("first", 1, "failure")} {
+		if _, err := Store(ctx, st, p, nil, "acme", "app", 42, p.Build.RunAttempt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Attempt 0 is an event without run_attempt; it is stored as attempt 1
+	// of its run.
+	if key, _ := Store(ctx, st, attempt("other-run", 0, "success"), nil, "acme", "app", 43, 0); key != "acme_app_43_1.pbom.json" {
+		t.Errorf("key for attempt 0 = %q", key)
+	}
+	// A PBOM stored before attempts were tracked, which kept the run ID
+	// of the collector run its skeleton came from, and one from repo
+	// "app_42" whose key shares the run's prefix.
+	old := attempt("legacy", 0, "failure")
+	old.Build.WorkflowRunID = "41"
+	legacy, _ := json.Marshal(old)
+	if err := st.Put(ctx, "acme_app_42.pbom.json", legacy); err != nil {
+		t.Fatal(err)
+	}
+	lookalike := attempt("lookalike", 1, "success")
+	lookalike.Source.Repository = "acme/app_42"
+	if _, err := Store(ctx, st, lookalike, nil, "acme", "app_42", 1, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := RunHistory(ctx, st, "acme", "app", 42)
+	if err != nil {
+		t.Fatalf("RunHistory: %v", err)
+	}
+	var got []string
+	for _, h := range history {
+		got = append(got, fmt.Sprintf("%d %s %s", h.Attempt, h.PBOM.ID, h.Key))
+	}
+	want := []string{
+		"0 legacy acme_app_42.pbom.json",
+		"1 first acme_app_42_1.pbom.json",
+		"2 second acme_app_42_2.pbom.json",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("history = %q, want %q", got, want)
+	}
+
+	if history, err := RunHistory(ctx, st, "acme", "app", 99); err != nil || len(history) != 0 {
+		t.Errorf("unknown run: %v, %v", history, err)
+	}
+}
+
+func TestLegacyKeyOfOtherRepo(t *testing.T) {
+	st, err := storage.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Attempt 1 of run 42 of repo "app" is stored under the legacy key of
+	// run 1 of repo "app_42".
+	p := &schema.PBOM{
+		PBOMVersion: schema.Version, ID: "app-run-42",
+		Source: schema.Source{Repository: "acme/app"},
+		Build:  schema.Build{WorkflowRunID: "42", RunAttempt: 1},
+	}
+	if key, err := Store(ctx, st, p, nil, "acme", "app", 42, 1); err != nil || key != legacyStorageKey("acme", "app_42", 1) {
+		t.Fatalf("Store = %q, %v", key, err)
+	}
+
+	if ok, err := Stored(ctx, st, "acme", "app_42", 1, 1); err != nil || ok {
+		t.Errorf("Stored(app_42 run 1) = %v, %v, want false", ok, err)
+	}
+	if history, err := RunHistory(ctx, st, "acme", "app_42", 1); err != nil || len(history) != 0 {
+		t.Errorf("RunHistory(app_42 run 1) = %v, %v, want none", history, err)
+	}
+	if ok, err := Stored(ctx, st, "acme", "app", 42, 1); err != nil || !ok {
+		t.Errorf("Stored(app run 42) = %v, %v, want true", ok, err)
+	}
+}
+
+func TestAttemptKeyOfOtherRepo(t *testing.T) {
+	st, err := storage.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// The legacy PBOM of run 1 of repo "app_42" is stored under the key of
+	// attempt 1 of run 42 of repo "app".
+	p := &schema.PBOM{
+		PBOMVersion: schema.Version, ID: "app_42-run-1",
+		Source: schema.Source{Repository: "acme/app_42"},
+		Build:  schema.Build{WorkflowRunID: "1"},
+	}
+	data, _ := json.Marshal(p)
+	if err := st.Put(ctx, StorageKey("acme", "app", 42, 1), data); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := Stored(ctx, st, "acme", "app", 42, 1); err != nil || ok {
+		t.Errorf("Stored(app run 42) = %v, %v, want false", ok, err)
+	}
+	if history, err := RunHistory(ctx, st, "acme", "app", 42); err != nil || len(history) != 0 {
+		t.Errorf("RunHistory(app run 42) = %v, %v, want none", history, err)
+	}
+	if ok, err := Stored(ctx, st, "acme", "app_42", 1, 1); err != nil || !ok {
+		t.Errorf("Stored(app_42 run 1) = %v, %v, want true", ok, err)
+	}
+	if history, err := RunHistory(ctx, st, "acme", "app_42", 1); err != nil || len(history) != 1 || history[0].PBOM.ID != p.ID {
+		t.Errorf("RunHistory(app_42 run 1) = %v, %v, want its legacy PBOM", history, err)
+	}
+}
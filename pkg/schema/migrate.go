@@ -0,0 +1,139 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupportedVersion is returned for documents whose pbom_version has no
+// migration path to the current Version.
+var ErrUnsupportedVersion = errors.New("unsupported pbom_version")
+
+// MigrationFunc upgrades a decoded document in place by one version step.
+// It must not touch pbom_version; the Migrator sets it after each step.
+type MigrationFunc func(doc map[string]any) error
+
+type migrationStep struct {
+	to string
+	fn MigrationFunc
+}
+
+// Migrator upgrades PBOM documents through a chain of registered steps
+// until they reach its current version.
+type Migrator struct {
+	current string
+	steps   map[string]migrationStep // keyed by source version
+}
+
+// NewMigrator creates a Migrator targeting the given version.
+func NewMigrator(current string) *Migrator {
+	return &Migrator{
+		current: current,
+		steps:   make(map[string]migrationStep),
+	}
+}
+
+// Register adds an upgrade step from one version to the next. Each source
+// version may have only one step.
+func (m *Migrator) Register(from, to string, fn MigrationFunc) {
+	if _, dup := m.steps[from]; dup {
+		panic(fmt.Sprintf("schema: duplicate migration from %s", from))
+	}
+	m.steps[from] = migrationStep{to: to, fn: fn}
+}
+
+// Supports reports whether documents of the given version can be read,
+// either directly or through migration.
+func (m *Migrator) Supports(version string) bool {
+	for i := 0; i <= len(m.steps); i++ {
+		if version == m.current {
+			return true
+		}
+		step, ok := m.steps[version]
+		if !ok {
+			return false
+		}
+		version = step.to
+	}
+	return false
+}
+
+// Migrate upgrades a raw JSON document to the current version. It returns
+// the upgraded document and the version it started at. Documents already
+// at the current version are returned unchanged.
+func (m *Migrator) Migrate(data []byte) ([]byte, string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, "", err
+	}
+
+	from, _ := doc["pbom_version"].(string)
+	if from == "" {
+		return nil, "", fmt.Errorf("%w: missing pbom_version", ErrUnsupportedVersion)
+	}
+	if from == m.current {
+		return data, from, nil
+	}
+
+	version := from
+	for i := 0; version != m.current; i++ {
+		step, ok := m.steps[version]
+		if !ok || i >= len(m.steps) {
+			return nil, from, fmt.Errorf("%w %q (current is %q)", ErrUnsupportedVersion, from, m.current)
+		}
+		if err := step.fn(doc); err != nil {
+			return nil, from, fmt.Errorf("migrating %s to %s: %w", version, step.to, err)
+		}
+		version = step.to
+		doc["pbom_version"] = version
+	}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, from, fmt.Errorf("marshaling migrated document: %w", err)
+	}
+	return out, from, nil
+}
+
+// defaultMigrator knows every released schema version. When Version is
+// bumped, register the step from the previous version here.
+var defaultMigrator = NewMigrator(Version)
+
+func init() {
+	// 1.1.0 added the optional build.run_attempt.
+	defaultMigrator.Register("1.0.0", "1.1.0", func(doc map[string]any) error { return nil })
+	// 1.2.0 added the optional build.skeleton.
+	defaultMigrator.Register("1.1.0", "1.2.0", func(doc map[string]any) error { return nil })
+	// 1.3.0 added the optional build.skeleton.pbom_id.
+	defaultMigrator.Register("1.2.0", "1.3.0", func(doc map[string]any) error { return nil })
+}
+
+// SupportsVersion reports whether documents of the given pbom_version can
+// be read by this package.
+func SupportsVersion(version string) bool {
+	return defaultMigrator.Supports(version)
+}
+
+// Migrate upgrades a raw PBOM document to the current Version. See
+// Migrator.Migrate.
+func Migrate(data []byte) ([]byte, string, error) {
+	return defaultMigrator.Migrate(data)
+}
+
+// Decode migrates a raw PBOM document to the current Version and
+// unmarshals it.
+func Decode(data []byte) (*PBOM, error) {
+	upgraded, _, err := Migrate(data)
+	if err != nil {
+		return nil, err
+	}
+	var pbom PBOM
+	if err := json.Unmarshal(upgraded, &pbom); err != nil {
+		return nil, fmt.Errorf("parsing PBOM: %w", err)
+	}
+	return &pbom, nil
+}
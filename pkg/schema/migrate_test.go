@@ -0,0 +1,127 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testMigrator builds a three-version chain: 0.8 renames "commit" to
+// "commit_sha", 0.9 adds an empty artifacts list.
+func testMigrator() *Migrator {
+	m := NewMigrator("1.0")
+	m.Register("0.8", "0.9", func(doc map[string]any) error {
+		src, ok := doc["source"].(map[string]any)
+		if !ok {
+			return errors.New("missing source")
+		}
+		src["commit_sha"] = src["commit"]
+		delete(src, "commit")
+		return nil
+	})
+	m.Register("0.9", "1.0", func(doc map[string]any) error {
+		if _, ok := doc["artifacts"]; !ok {
+			doc["artifacts"] = []any{}
+		}
+		return nil
+	})
+	return m
+}
+
+func TestMigrateChain(t *testing.T) {
+	m := testMigrator()
+	out, from, err := m.Migrate([]byte(`{"pbom_version":"0.8","source":{"commit":"abc"},"build":{"n":12345678901234567890}}`))
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if from != "0.8" {
+		t.Errorf("from = %q, want 0.8", from)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["pbom_version"] != "1.0" {
+		t.Errorf("pbom_version = %v, want 1.0", doc["pbom_version"])
+	}
+	if doc["source"].(map[string]any)["commit_sha"] != "abc" {
+		t.Errorf("source not migrated: %v", doc["source"])
+	}
+	if _, ok := doc["artifacts"]; !ok {
+		t.Error("second step did not run")
+	}
+	if !strings.Contains(string(out), "12345678901234567890") {
+		t.Errorf("large numbers must survive migration: %s", out)
+	}
+}
+
+func TestMigrateCurrentIsUnchanged(t *testing.T) {
+	in := []byte(`{"pbom_version":"1.0",  "id":"x"}`)
+	out, from, err := testMigrator().Migrate(in)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if from != "1.0" || string(out) != string(in) {
+		t.Errorf("current document modified: from=%q out=%s", from, out)
+	}
+}
+
+func TestMigrateErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		doc         string
+		unsupported bool
+	}{
+		{"unknown version", `{"pbom_version":"2.0"}`, true},
+		{"missing version", `{"id":"x"}`, true},
+		{"step fails", `{"pbom_version":"0.8"}`, false},
+		{"not an object", `[]`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := testMigrator().Migrate([]byte(tt.doc))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if errors.Is(err, ErrUnsupportedVersion) != tt.unsupported {
+				t.Errorf("errors.Is(ErrUnsupportedVersion) = %v, want %v (err: %v)", !tt.unsupported, tt.unsupported, err)
+			}
+		})
+	}
+}
+
+func TestSupports(t *testing.T) {
+	m := testMigrator()
+	for v, want := range map[string]bool{"0.8": true, "0.9": true, "1.0": true, "0.7": false, "": false} {
+		if got := m.Supports(v); got != want {
+			t.Errorf("Supports(%q) = %v, want %v", v, got, want)
+		}
+	}
+	if !SupportsVersion(Version) {
+		t.Errorf("SupportsVersion(%q) = false", Version)
+	}
+}
+
+func TestDecodeReleasedVersions(t *testing.T) {
+	for _, v := range []string{"1.0.0", "1.1.0", "1.2.0"} {
+		p, err := Decode([]byte(`{"pbom_version":"` + v + `","id":"a","build":{"workflow_run_id":"7"}}`))
+		if err != nil {
+			t.Fatalf("Decode %s: %v", v, err)
+		}
+		if p.PBOMVersion != Version || p.Build.WorkflowRunID != "7" || p.Build.RunAttempt != 0 || p.Build.Skeleton != nil {
+			t.Errorf("decoded %s = %+v", v, p)
+		}
+	}
+}
+
+func TestRegisterDuplicatePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on duplicate registration")
+		}
+	}()
+	m := testMigrator()
+	m.Register("0.8", "1.0", func(map[string]any) error { return nil })
+}
@@ -7,7 +7,10 @@ package schema
 
 import "time"
 
-const Version = "1.0.0"
+const Version = "1.3.0"
+
+// MediaType is the OCI artifact type of a PBOM document stored in a registry.
+const MediaType = "application/vnd.pbom.v1+json"
 
 // PBOM is the root document.
 type PBOM struct {
@@ -32,6 +35,7 @@ type Source struct {
 // Build represents Phase A: the GitHub Actions execution context.
 type Build struct {
 	WorkflowRunID   string            `json:"workflow_run_id"`
+	RunAttempt      int               `json:"run_attempt,omitempty"`
 	WorkflowName    string            `json:"workflow_name"`
 	WorkflowFile    string            `json:"workflow_file,omitempty"`
 	Trigger         string            `json:"trigger,omitempty"`
@@ -42,6 +46,18 @@ type Build struct {
 	StartedAt       *time.Time        `json:"started_at,omitempty"`
 	CompletedAt     *time.Time        `json:"completed_at,omitempty"`
 	Status          string            `json:"status"`
+	Skeleton        *SkeletonSource   `json:"skeleton,omitempty"`
+}
+
+// SkeletonSource records the collector artifact a PBOM was enriched from.
+type SkeletonSource struct {
+	RunID      string `json:"run_id"`            // collector workflow run
+	ArtifactID int64  `json:"artifact_id"`       // GitHub Actions artifact
+	Digest     string `json:"digest"`            // SHA-256 of the artifact archive
+	PBOMID     string `json:"pbom_id,omitempty"` // ID of the skeleton PBOM
+	// Verified is true when Digest matched the digest GitHub reported for
+	// the artifact; older artifacts have none to check against.
+	Verified bool `json:"verified"`
 }
 
 // Runner describes the GitHub Actions runner environment.
@@ -96,3 +112,14 @@ type CoDeployedService struct {
 	Digest  string `json:"digest,omitempty"`
 	Version string `json:"version,omitempty"`
 }
+
+// TriggerForEvent maps a GitHub Actions event name to a Build.Trigger
+// value. Events the schema does not enumerate become "other".
+func TriggerForEvent(event string) string {
+	switch event {
+	case "push", "pull_request", "workflow_dispatch", "schedule", "release", "":
+		return event
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,26 @@
+package schema
+
+import "testing"
+
+func TestTriggerForEvent(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"push", "push"},
+		{"pull_request", "pull_request"},
+		{"workflow_dispatch", "workflow_dispatch"},
+		{"schedule", "schedule"},
+		{"release", "release"},
+		{"", ""},
+		{"repository_dispatch", "other"},
+		{"workflow_call", "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := TriggerForEvent(tt.input); got != tt.want {
+				t.Errorf("TriggerForEvent(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
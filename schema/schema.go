@@ -0,0 +1,10 @@
+// Package schema embeds the published PBOM JSON Schema so that validation
+// always runs against exactly the file shipped in this directory.
+package schema
+
+import _ "embed"
+
+// JSON is the content of pbom.schema.json (draft 2020-12).
+//
+//go:embed pbom.schema.json
+var JSON []byte